@@ -10,6 +10,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
 
+	"github.com/ajenpan/surf/core/auth"
 	utilSignal "github.com/ajenpan/surf/core/utils/signal"
 
 	"github.com/ajenpan/surf/core/utils/rsagen"
@@ -33,7 +34,7 @@ func ReadRSAKey() (*rsa.PrivateKey, error) {
 
 	raw, err := os.ReadFile(privateFile)
 	if err != nil {
-		privateKey, publicKey, err := rsagen.GenerateRsaPem(2048)
+		privateKey, publicKey, err := rsagen.GenerateRsaPem(auth.DefaultKeyBits)
 		if err != nil {
 			return nil, err
 		}
@@ -2,14 +2,16 @@ package main
 
 import (
 	"bytes"
-	"crypto/rsa"
+	"context"
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
 	"github.com/ajenpan/surf/core/log"
 	"github.com/ajenpan/surf/core/network"
 	"github.com/ajenpan/surf/core/utils/rsagen"
@@ -55,6 +57,21 @@ func Run() error {
 	app := cli.NewApp()
 	app.Version = Version
 	app.Name = Name
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "public-key",
+			Value:       "public.pem",
+			Destination: &PublicKeyFile,
+		}, &cli.StringFlag{
+			Name:        "keys-url",
+			Usage:       "the jwks url of uauth, like http://uauth:9999/.well-known/jwks.json, public-key is used if empty",
+			Destination: &KeysURL,
+		}, &cli.StringSliceFlag{
+			Name:        "events",
+			Usage:       "the event stream of uauth, like http://uauth:9998/events, one for each instance, the conns of the revoked tokens are closed by it",
+			Destination: EventStreams,
+		},
+	}
 	app.Action = RealMain
 
 	err := app.Run(os.Args)
@@ -62,30 +79,48 @@ func Run() error {
 }
 
 var listenAt string = ":12345"
-
-func LoadAuthPublicKey() (*rsa.PublicKey, error) {
-	publicRaw, err := os.ReadFile("public.pem")
-	if err != nil {
-		return nil, err
+var PublicKeyFile string = ""
+var KeysURL string = ""
+var EventStreams = cli.NewStringSlice()
+
+// loadTokenVerifier verifies the tokens by the keys of uauth, which are refreshed as uauth rotates them,
+// or by the public key file.
+func loadTokenVerifier() (auth.Verifier, func(), error) {
+	if KeysURL == "" {
+		pk, err := rsagen.LoadRsaPublicKeyFromFile(PublicKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return auth.NewKeySet(pk), func() {}, nil
+	}
+	keys := auth.NewRemoteKeySet(auth.HTTPKeySource(KeysURL, nil), auth.RemoteKeySetOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := keys.Refresh(ctx); err != nil {
+		return nil, nil, err
 	}
-	pk, err := rsagen.ParseRsaPublicKeyFromPem(publicRaw)
-	return pk, err
+	keys.Start()
+	return keys, keys.Stop, nil
 }
 
 func RealMain(c *cli.Context) error {
-	pk, err := LoadAuthPublicKey()
+	keys, stop, err := loadTokenVerifier()
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer stop()
 
 	h := battleHandler.New()
 
+	revoked := auth.NewDenyList()
+	verifier := auth.WithRevocation(keys, revoked)
+
 	listener, err := network.NewTcpServer(network.TcpServerOptions{
 		ListenAddr:   listenAt,
 		OnConnPacket: h.OnMessage,
 		OnConnEnable: h.OnConn,
 		OnConnAuth: func(tokenRaw []byte) (auth.User, error) {
-			return auth.VerifyToken(pk, tokenRaw)
+			return verifier.Verify(tokenRaw)
 		},
 	})
 
@@ -93,6 +128,13 @@ func RealMain(c *cli.Context) error {
 		panic(err)
 	}
 
+	for _, url := range EventStreams.Value() {
+		sub := event.NewSubscriber(event.SubscriberOptions{URL: url, Topics: []string{auth.RevokedTopic}},
+			network.CloseRevoked(revoked, listener.Conns()))
+		sub.Start()
+		defer sub.Stop()
+	}
+
 	go listener.Start()
 	defer listener.Stop()
 
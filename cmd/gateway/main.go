@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
+	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/rsagen"
+	utilSignal "github.com/ajenpan/surf/core/utils/signal"
+	"github.com/ajenpan/surf/server/gateway"
+)
+
+var (
+	Name       string = "gateway"
+	Version    string = "unknow"
+	GitCommit  string = "unknow"
+	BuildAt    string = "unknow"
+	BuildBy    string = runtime.Version()
+	RunnningOS string = runtime.GOOS + "/" + runtime.GOARCH
+)
+
+var (
+	TcpListenAddr   string = ""
+	WsListenAddr    string = ""
+	AdminListenAddr string = ""
+	PublicKeyFile   string = ""
+	KeysURL         string = ""
+	Routes                 = cli.NewStringSlice()
+	EventStreams           = cli.NewStringSlice()
+)
+
+func longVersion() string {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintln(buf, "project:", Name)
+	fmt.Fprintln(buf, "version:", Version)
+	fmt.Fprintln(buf, "git commit:", GitCommit)
+	fmt.Fprintln(buf, "build at:", BuildAt)
+	fmt.Fprintln(buf, "build by:", BuildBy)
+	fmt.Fprintln(buf, "running OS/Arch:", RunnningOS)
+	return buf.String()
+}
+
+func main() {
+	cli.VersionPrinter = func(c *cli.Context) {
+		fmt.Println(longVersion())
+	}
+	app := cli.NewApp()
+	app.Version = Version
+	app.Name = Name
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "tcp-listen",
+			Value:       ":10010",
+			Destination: &TcpListenAddr,
+		}, &cli.StringFlag{
+			Name:        "ws-listen",
+			Value:       ":10011",
+			Destination: &WsListenAddr,
+		}, &cli.StringFlag{
+			Name:        "admin-listen",
+			Value:       ":10012",
+			Destination: &AdminListenAddr,
+		}, &cli.StringFlag{
+			Name:        "public-key",
+			Value:       "public.pem",
+			Destination: &PublicKeyFile,
+		}, &cli.StringFlag{
+			Name:        "keys-url",
+			Usage:       "the jwks url of uauth, like http://uauth:9999/.well-known/jwks.json, public-key is used if empty",
+			Destination: &KeysURL,
+		}, &cli.StringSliceFlag{
+			Name:        "route",
+			Usage:       "service=http://host:port, can be set multiple times",
+			Destination: Routes,
+		}, &cli.StringSliceFlag{
+			Name:        "events",
+			Usage:       "the event stream of uauth, like http://uauth:9998/events, one for each instance, the conns of the revoked tokens are closed by it",
+			Destination: EventStreams,
+		},
+	}
+	app.Action = RealMain
+	err := app.Run(os.Args)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func parseRoutes(values []string) (map[string]string, error) {
+	ret := make(map[string]string, len(values))
+	for _, v := range values {
+		name, addr, found := strings.Cut(v, "=")
+		if !found || name == "" || addr == "" {
+			return nil, fmt.Errorf("invalid route: %s", v)
+		}
+		ret[name] = addr
+	}
+	return ret, nil
+}
+
+func loadTokenVerifier() (auth.Verifier, func(), error) {
+	if KeysURL == "" {
+		pk, err := rsagen.LoadRsaPublicKeyFromFile(PublicKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return auth.NewKeySet(pk), func() {}, nil
+	}
+	keys := auth.NewRemoteKeySet(auth.HTTPKeySource(KeysURL, nil), auth.RemoteKeySetOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := keys.Refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+	keys.Start()
+	return keys, keys.Stop, nil
+}
+
+func RealMain(c *cli.Context) error {
+	verifier, stop, err := loadTokenVerifier()
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	routes, err := parseRoutes(Routes.Value())
+	if err != nil {
+		return err
+	}
+
+	gw := gateway.New(gateway.Options{
+		TokenVerifier: verifier,
+		Routes:        routes,
+	})
+
+	for _, url := range EventStreams.Value() {
+		sub := event.NewSubscriber(event.SubscriberOptions{URL: url, Topics: []string{auth.RevokedTopic}}, gw)
+		sub.Start()
+		defer sub.Stop()
+	}
+
+	tcpsvr, err := network.NewTcpServer(network.TcpServerOptions{
+		ListenAddr:   TcpListenAddr,
+		OnConnPacket: gw.OnConnPacket,
+		OnConnEnable: gw.OnConnEnable,
+		OnConnAuth:   gw.OnConnAuth,
+	})
+	if err != nil {
+		return err
+	}
+	tcpsvr.Start()
+	defer tcpsvr.Stop()
+
+	wssvr := network.NewWSServer(network.WSServerOptions{
+		ListenAddr:   WsListenAddr,
+		OnConnPacket: gw.OnConnPacket,
+		OnConnEnable: gw.OnConnEnable,
+		OnConnAuth:   gw.OnConnAuth,
+	})
+	wssvr.Start()
+	defer wssvr.Stop()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]network.Metrics{
+			"tcp": tcpsvr.Metrics(),
+			"ws":  wssvr.Metrics(),
+		})
+	})
+	adminsvr := &http.Server{Addr: AdminListenAddr, Handler: mux}
+	go adminsvr.ListenAndServe()
+	defer adminsvr.Close()
+
+	log.Infof("gateway start, tcp:%s, ws:%s, routes:%v", TcpListenAddr, WsListenAddr, routes)
+
+	s := utilSignal.WaitShutdown()
+	log.Infof("recv signal: %v", s.String())
+	return nil
+}
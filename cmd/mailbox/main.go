@@ -4,19 +4,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
-	"reflect"
 	"runtime/debug"
-	"strings"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"google.golang.org/protobuf/encoding/protojson"
-	"google.golang.org/protobuf/reflect/protoreflect"
 
+	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/event"
 	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	"github.com/ajenpan/surf/core/utils/marshal"
+	"github.com/ajenpan/surf/core/utils/openapi"
+	"github.com/ajenpan/surf/core/utils/rsagen"
 	proto "github.com/ajenpan/surf/msg/mailbox"
 	"github.com/ajenpan/surf/server/mailbox"
 )
@@ -24,8 +29,10 @@ import (
 var ConfigPath string = ""
 var ListenAddr string = ""
 var PrintConf bool = false
+var ServeDocs bool = false
 
 var GHandler *mailbox.Handler
+var TokenVerifier auth.Verifier
 
 var Version string = ""
 var GitCommit string = ""
@@ -55,6 +62,27 @@ func main() {
 				Name:        "print-config",
 				Destination: &PrintConf,
 				Hidden:      true,
+			}, &cli.BoolFlag{
+				Name:        "docs",
+				Usage:       "serve the api docs page at /docs",
+				Destination: &ServeDocs,
+			},
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "openapi",
+				Usage: "print the openapi document of the http api",
+				Action: func(c *cli.Context) error {
+					// the handler is not called, the document only needs the method types.
+					ct := calltable.NewCallTable[string]()
+					proto.RegisterMailBoxServer(ct, (*mailbox.Handler)(nil))
+					raw, err := json.MarshalIndent(openAPIDocument(ct), "", "  ")
+					if err != nil {
+						return err
+					}
+					fmt.Println(string(raw))
+					return nil
+				},
 			},
 		},
 		Version: Version,
@@ -68,11 +96,26 @@ func main() {
 			return err
 		}
 
+		verifier, stop, err := loadTokenVerifier(mailbox.DefaultConf)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		defer stop()
+		TokenVerifier = verifier
+
+		permissions, err := loadPermissions(mailbox.DefaultConf)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+
 		if GHandler = mailbox.NewHandler(mailbox.DefaultConf); GHandler == nil {
 			err := fmt.Errorf("create handler failed")
 			log.Panic(err)
 			return err
 		}
+		GHandler.Permissions = permissions
 
 		if err := httpsvr(); err != nil {
 			log.Error(err)
@@ -117,30 +160,88 @@ func HttpHeaderForward(f func(rw http.ResponseWriter, r *http.Request)) func(rw
 	}
 }
 
-var AllowList = map[string]bool{
-	"/MailBox/Announcement": true,
+// parserCaller authenticates the request by the uauth token if it carries one, the user is kept
+// in the context for the handlers. The permissions are checked by the dispatcher against the method options.
+func parserCaller(r *http.Request) (context.Context, calltable.Caller, error) {
+	user, err := auth.Authenticate(TokenVerifier, r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, errors.CodeUnauthenticated, "invalid token")
+	}
+	if user == nil {
+		return r.Context(), nil, nil
+	}
+	return auth.WithUser(r.Context(), user), user, nil
+}
+
+// loadTokenVerifier verifies the tokens by uauth remotely, by the keys of uauth, or by the public key file.
+// The remote one drops the revoked tokens from its cache by the event stream of uauth.
+func loadTokenVerifier(conf *mailbox.Config) (auth.Verifier, func(), error) {
+	if conf.AuthURL != "" {
+		client := auth.NewAuthClient(auth.AuthClientOptions{RemoteUrl: conf.AuthURL})
+		if conf.EventsURL == "" {
+			return client, func() {}, nil
+		}
+		sub := event.NewSubscriber(event.SubscriberOptions{URL: conf.EventsURL, Topics: []string{auth.RevokedTopic}}, client)
+		sub.Start()
+		return client, sub.Stop, nil
+	}
+	if conf.KeysURL == "" {
+		pk, err := rsagen.LoadRsaPublicKeyFromFile(conf.PublicKeyFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return auth.NewKeySet(pk), func() {}, nil
+	}
+	keys := auth.NewRemoteKeySet(auth.HTTPKeySource(conf.KeysURL, nil), auth.RemoteKeySetOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := keys.Refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+	keys.Start()
+	return keys, keys.Stop, nil
 }
 
-func parserAdminBearer(r *http.Request) (context.Context, error) {
-	bearerToekn := r.Header.Get("Authorization")
-	bearerToekn = strings.TrimPrefix(bearerToekn, "Bearer ")
-	if len(bearerToekn) < 1 {
-		return nil, fmt.Errorf("token is required")
+func loadPermissions(conf *mailbox.Config) (*calltable.Permissions, error) {
+	if conf.PermissionsFile == "" {
+		return calltable.DefaultPermissions, nil
 	}
-	claims, err := mailbox.VerifyAdminTokenWithClaims(bearerToekn)
-	if err != nil {
-		return nil, fmt.Errorf("token verify failed: %v", err)
+	return calltable.LoadPermissions(conf.PermissionsFile)
+}
+
+// openAPIDocument describes the api in the standard envelope of network: {"err","errors","data"}.
+func openAPIDocument(ct *calltable.CallTable[string]) *openapi.Document {
+	return openapi.Generate(ct, openapi.Options{
+		Title:         "MailBox",
+		Version:       Version,
+		PathPrefix:    "/MailBox/",
+		UseProtoNames: true,
+	})
+}
+
+// httpCodecs keeps the json of mailbox in proto names with the unpopulated fields.
+func httpCodecs() *marshal.Codecs {
+	codecs := marshal.DefaultCodecs()
+	jsonpb := &marshal.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: true},
 	}
-	newCtx := context.WithValue(r.Context(), mailbox.CtxClaims, claims)
-	newCtx = context.WithValue(newCtx, mailbox.CtxAdminUID, claims["uid"])
-	newCtx = context.WithValue(newCtx, mailbox.CtxCallerRole, "admin")
-	return newCtx, nil
+	codecs.Default = jsonpb
+	codecs.Register("application/json", jsonpb)
+	return codecs
 }
 
 func httpsvr() error {
-	ct := mailbox.ParseRpcMethod(proto.File_mailbox_proto.Services(), GHandler)
-	ct.Range(func(key string, method *mailbox.MessageMethod) bool {
-		key = "/" + key
+	ct := calltable.NewCallTable[string]()
+	proto.RegisterMailBoxServer(ct, GHandler)
+	openapi.Serve(http.DefaultServeMux, openAPIDocument(ct), ServeDocs)
+	dispatcher := calltable.NewDispatcher()
+	dispatcher.Permissions = GHandler.Permissions
+	codecs := httpCodecs()
+	ct.Range(func(key string, method *calltable.Method) bool {
+		if method.Style == calltable.StyleServerStream {
+			return true
+		}
+		key, verb := method.HttpRoute("/MailBox/" + key)
 		fmt.Println("handle path:", key)
 
 		handleCall := func(rw http.ResponseWriter, r *http.Request) {
@@ -151,65 +252,27 @@ func httpsvr() error {
 				}
 			}()
 
-			mapRaw := make(map[string]interface{})
-			mapRaw["code"] = 1
-
-			// 这里是否要限制一下 body 太大的情况, 如果客户端直接发了一个大于内存的数据, 会发生什么
-			defer r.Body.Close()
-			raw, err := ioutil.ReadAll(r.Body)
-			if err != nil {
-				rw.WriteHeader(http.StatusBadRequest)
-				rw.Write([]byte(err.Error()))
+			if len(verb) > 0 && r.Method != verb {
+				network.WriteHttpResponse(rw, codecs.Default, method.FuncName, nil, errors.MethodNotAllowed("method %s not allowed", r.Method))
 				return
 			}
 
-			req := reflect.New(method.Req).Interface().(protoreflect.ProtoMessage)
-			resp := reflect.New(method.Resp).Interface().(protoreflect.ProtoMessage)
-
-			if err = protojson.Unmarshal([]byte(raw), req); err != nil {
-				rw.WriteHeader(http.StatusBadRequest)
-				rw.Write([]byte(err.Error()))
+			defer r.Body.Close()
+			req, out, err := network.ReadHttpRequest(codecs, r, method, 0)
+			if err != nil {
+				network.WriteHttpResponse(rw, out, method.FuncName, nil, err)
 				return
 			}
 
-			callResult := method.Method.Func.Call([]reflect.Value{reflect.ValueOf(GHandler), reflect.ValueOf(r.Context()), reflect.ValueOf(req), reflect.ValueOf(resp)})
-
-			if !callResult[0].IsNil() {
-				err = callResult[0].Interface().(error)
+			ctx, caller, err := parserCaller(r)
+			var resp interface{}
+			if err == nil {
+				resp, err = dispatcher.Invoke(method, caller, ctx, req)
 			}
-
 			if err != nil {
 				log.Error(err)
-				mapRaw["code"] = -1
-				mapRaw["message"] = err.Error()
-			} else {
-				raw, err := protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: true}.Marshal(resp)
-				if err == nil {
-					mapRaw["data"] = json.RawMessage(raw)
-					mapRaw["code"] = 0
-					mapRaw["message"] = "ok"
-				} else {
-					log.Error(err)
-					mapRaw["code"] = -1
-					mapRaw["message"] = err.Error()
-				}
-			}
-
-			respRaw, err := json.Marshal(mapRaw)
-			if err != nil {
-				rw.WriteHeader(http.StatusBadRequest)
-				rw.Write([]byte(err.Error()))
-				return
-			}
-
-			rw.Header().Set("Content-Type", "application/json; charset=utf-8")
-
-			_, err = rw.Write(respRaw)
-
-			//the err after log
-			if err != nil {
-				log.Error("write resp err:", err)
 			}
+			network.WriteHttpResponse(rw, out, method.FuncName, resp, err)
 		}
 
 		h := MultiWarp(HttpHeaderForward)(handleCall)
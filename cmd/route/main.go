@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"runtime"
@@ -10,6 +11,7 @@ import (
 	"github.com/urfave/cli/v2"
 
 	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
 	"github.com/ajenpan/surf/core/log"
 	"github.com/ajenpan/surf/core/network"
 	"github.com/ajenpan/surf/core/utils/rsagen"
@@ -43,6 +45,21 @@ func main() {
 	app := cli.NewApp()
 	app.Version = Version
 	app.Name = Name
+	app.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "public-key",
+			Value:       "public.pem",
+			Destination: &PublicKeyFile,
+		}, &cli.StringFlag{
+			Name:        "keys-url",
+			Usage:       "the jwks url of uauth, like http://uauth:9999/.well-known/jwks.json, public-key is used if empty",
+			Destination: &KeysURL,
+		}, &cli.StringSliceFlag{
+			Name:        "events",
+			Usage:       "the event stream of uauth, like http://uauth:9998/events, one for each instance, the conns of the revoked tokens are closed by it",
+			Destination: EventStreams,
+		},
+	}
 	app.Action = RealMain
 	err := app.Run(os.Args)
 	if err != nil {
@@ -50,45 +67,39 @@ func main() {
 	}
 }
 
-const PrivateKeyFile = "private.pem"
-const PublicKeyFile = "public.pem"
+var PublicKeyFile string = ""
+var KeysURL string = ""
+var EventStreams = cli.NewStringSlice()
 
-func ReadRSAKey() ([]byte, []byte, error) {
-	privateRaw, err := os.ReadFile(PrivateKeyFile)
-	if err != nil {
-		privateKey, publicKey, err := rsagen.GenerateRsaPem(2048)
+// loadTokenVerifier verifies the tokens by the keys of uauth, which are refreshed as uauth rotates them,
+// or by the public key file.
+func loadTokenVerifier() (auth.Verifier, func(), error) {
+	if KeysURL == "" {
+		pk, err := rsagen.LoadRsaPublicKeyFromFile(PublicKeyFile)
 		if err != nil {
 			return nil, nil, err
 		}
-		privateRaw = []byte(privateKey)
-		os.WriteFile(PrivateKeyFile, []byte(privateKey), 0644)
-		os.WriteFile(PublicKeyFile, []byte(publicKey), 0644)
+		return auth.NewKeySet(pk), func() {}, nil
 	}
-	publicRaw, err := os.ReadFile(PublicKeyFile)
-	if err != nil {
+	keys := auth.NewRemoteKeySet(auth.HTTPKeySource(KeysURL, nil), auth.RemoteKeySetOptions{})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := keys.Refresh(ctx); err != nil {
 		return nil, nil, err
 	}
-	return privateRaw, publicRaw, nil
+	keys.Start()
+	return keys, keys.Stop, nil
 }
 
 func RealMain(c *cli.Context) error {
-	privateRaw, _, err := ReadRSAKey()
-	if err != nil {
-		panic(err)
-	}
-
-	ppk, err := rsagen.ParseRsaPrivateKeyFromPem(privateRaw)
+	keys, stop, err := loadTokenVerifier()
 	if err != nil {
 		return err
 	}
+	defer stop()
 
-	jwt, _ := auth.GenerateToken(ppk, &auth.UserInfo{
-		UId:   10001,
-		UName: "gdclient",
-		URole: 0,
-	}, 240*time.Hour)
-
-	fmt.Println(jwt)
+	revoked := auth.NewDenyList()
+	verifier := auth.WithRevocation(keys, revoked)
 
 	ws := network.NewWSServer(network.WSServerOptions{
 		ListenAddr: ":9999",
@@ -99,15 +110,22 @@ func RealMain(c *cli.Context) error {
 		OnConnEnable: func(c network.Conn, b bool) {
 			log.Printf("OnConnEnable %s %v", c.ConnID(), b)
 		},
-		// OnConnAuth: func(data []byte) (auth.User, error) {
-		// 	return auth.VerifyToken(&ppk.PublicKey, data)
-		// },
+		OnConnAuth: func(data []byte) (auth.User, error) {
+			return verifier.Verify(data)
+		},
 	})
 	if err != nil {
 		return err
 	}
 	ws.Start()
 
+	for _, url := range EventStreams.Value() {
+		sub := event.NewSubscriber(event.SubscriberOptions{URL: url, Topics: []string{auth.RevokedTopic}},
+			network.CloseRevoked(revoked, ws.Conns()))
+		sub.Start()
+		defer sub.Stop()
+	}
+
 	s := utilSignal.WaitShutdown()
 	log.Infof("recv signal: %v", s.String())
 	return nil
@@ -2,8 +2,11 @@ package main
 
 import (
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"github.com/urfave/cli/v2"
 	"gorm.io/driver/mysql"
 
@@ -12,10 +15,15 @@ import (
 	"gorm.io/gorm/schema"
 
 	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
 
 	auth "github.com/ajenpan/surf/server/uauth"
 	"github.com/ajenpan/surf/server/uauth/database/cache"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+	"github.com/ajenpan/surf/server/uauth/idp"
 
+	"github.com/ajenpan/surf/core/utils/calltable"
 	utilSignal "github.com/ajenpan/surf/core/utils/signal"
 
 	"github.com/ajenpan/surf/core/utils/rsagen"
@@ -31,27 +39,53 @@ var Name string = "auth"
 
 var ConfigPath string = ""
 var ListenAddr string = ""
+var EventsListenAddr string = ":9998"
 var PrintConf bool = false
 
+// PrivateKeyFile is the key of the old deployments, it is moved into KeysDir at the start,
+// it is only added to the ring on every start if KeysDir is empty.
 const PrivateKeyFile = "private.pem"
-const PublicKeyFile = "public.pem"
 
-func ReadRSAKey() ([]byte, []byte, error) {
-	privateRaw, err := os.ReadFile(PrivateKeyFile)
+var KeysDir string = "keys"
+var KeyRotateInterval time.Duration = 30 * 24 * time.Hour
+var AccessTokenTTL time.Duration = auth.DefaultAccessTokenTTL
+var RefreshTokenTTL time.Duration = auth.DefaultRefreshTokenTTL
+var PasswdIterations int = auth.DefaultPasswdIterations
+var RedisAddr string = ""
+var CaptchaAfterFailures int = auth.DefaultCaptchaAfterFailures
+var LockAfterFailures int = auth.DefaultLockAfterFailures
+var TotpIssuer string = auth.DefaultTotpIssuer
+var PermissionsFile string = ""
+var OAuthProvidersFile string = ""
+var LogResetCodes bool = false
+
+func loadKeyRing() (*coreauth.KeyRing, error) {
+	keys, err := coreauth.NewKeyRing(coreauth.KeyRingOptions{
+		Dir:            KeysDir,
+		RotateInterval: KeyRotateInterval,
+		// longer than the validity of the tokens.
+		Overlap: 48 * time.Hour,
+	})
 	if err != nil {
-		privateKey, publicKey, err := rsagen.GenerateRsaPem(1024)
+		return nil, err
+	}
+	if info, err := os.Stat(PrivateKeyFile); err == nil {
+		pk, err := rsagen.LoadRsaPrivateKeyFromFile(PrivateKeyFile)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+		if _, err := keys.Add(pk, info.ModTime()); err != nil {
+			return nil, err
+		}
+		// the key is saved in KeysDir now, it is retired and dropped there like the others.
+		if KeysDir != "" {
+			if err := os.Remove(PrivateKeyFile); err != nil {
+				return nil, err
+			}
+			log.Infof("%s is moved into %s", PrivateKeyFile, KeysDir)
 		}
-		privateRaw = []byte(privateKey)
-		os.WriteFile(PrivateKeyFile, []byte(privateKey), 0644)
-		os.WriteFile(PublicKeyFile, []byte(publicKey), 0644)
-	}
-	publicRaw, err := os.ReadFile(PublicKeyFile)
-	if err != nil {
-		return nil, nil, err
 	}
-	return privateRaw, publicRaw, nil
+	return keys, nil
 }
 
 func main() {
@@ -77,6 +111,64 @@ func main() {
 			Aliases:     []string{"l"},
 			Value:       ":30020",
 			Destination: &ListenAddr,
+		}, &cli.StringFlag{
+			Name:        "events-listen",
+			Usage:       "the internal address of the revocation stream at /events, it must be reachable by the servers only",
+			Value:       EventsListenAddr,
+			Destination: &EventsListenAddr,
+		}, &cli.StringFlag{
+			Name:        "keys-dir",
+			Value:       KeysDir,
+			Destination: &KeysDir,
+		}, &cli.DurationFlag{
+			Name:        "key-rotate",
+			Usage:       "how often the token key is rotated, 0 never",
+			Value:       KeyRotateInterval,
+			Destination: &KeyRotateInterval,
+		}, &cli.DurationFlag{
+			Name:        "access-ttl",
+			Value:       AccessTokenTTL,
+			Destination: &AccessTokenTTL,
+		}, &cli.DurationFlag{
+			Name:        "refresh-ttl",
+			Value:       RefreshTokenTTL,
+			Destination: &RefreshTokenTTL,
+		}, &cli.IntFlag{
+			Name:        "passwd-iterations",
+			Usage:       "the pbkdf2 cost of the passwords",
+			Value:       PasswdIterations,
+			Destination: &PasswdIterations,
+		}, &cli.IntFlag{
+			Name:        "captcha-after",
+			Usage:       "the failed logins of an account or an ip which require the captcha, -1 never",
+			Value:       CaptchaAfterFailures,
+			Destination: &CaptchaAfterFailures,
+		}, &cli.IntFlag{
+			Name:        "lock-after",
+			Usage:       "the failed logins of an account which lock it for a while, -1 never",
+			Value:       LockAfterFailures,
+			Destination: &LockAfterFailures,
+		}, &cli.StringFlag{
+			Name:        "totp-issuer",
+			Usage:       "the issuer of the totp accounts in the authenticator apps",
+			Value:       TotpIssuer,
+			Destination: &TotpIssuer,
+		}, &cli.StringFlag{
+			Name:        "permissions",
+			Usage:       "the json file which maps the roles to the permissions, empty grants all to the admins",
+			Destination: &PermissionsFile,
+		}, &cli.StringFlag{
+			Name:        "oauth-providers",
+			Usage:       "the json file of the identity providers, see idp.Config, empty disables the logins by them",
+			Destination: &OAuthProvidersFile,
+		}, &cli.BoolFlag{
+			Name:        "log-reset-codes",
+			Usage:       "log the reset codes of the passwords instead of sending them, only for development",
+			Destination: &LogResetCodes,
+		}, &cli.StringFlag{
+			Name:        "redis",
+			Usage:       "the redis address of the cache shared by the instances, empty keeps it in memory",
+			Destination: &RedisAddr,
 		}, &cli.BoolFlag{
 			Name:        "printconf",
 			Destination: &PrintConf,
@@ -120,21 +212,69 @@ func CreateMysqlClient(dsn string) *gorm.DB {
 // 	return dbc
 // }
 
-func RealMain(c *cli.Context) error {
-	privateRaw, publicRaw, err := ReadRSAKey()
-	if err != nil {
-		panic(err)
+func createCache() cache.AuthCache {
+	if RedisAddr == "" {
+		return cache.NewMemory()
 	}
-	pk, err := rsagen.ParseRsaPrivateKeyFromPem(privateRaw)
+	return cache.NewRedis(redis.NewClient(&redis.Options{Addr: RedisAddr}), cache.DefaultRedisPrefix)
+}
+
+// logResetCode is the sender of the reset codes for development, the deployments send them by email or sms.
+func logResetCode(user *models.Users, code string) error {
+	log.Warnf("the reset code of uid %d is %s", user.UID, code)
+	return nil
+}
+
+func RealMain(c *cli.Context) error {
+	keys, err := loadKeyRing()
 	if err != nil {
 		return err
 	}
+	keys.Start()
+	defer keys.Stop()
+
+	permissions := calltable.DefaultPermissions
+	if PermissionsFile != "" {
+		if permissions, err = calltable.LoadPermissions(PermissionsFile); err != nil {
+			return err
+		}
+	}
+
+	var providers *idp.Registry
+	if OAuthProvidersFile != "" {
+		confs, err := idp.LoadConfigs(OAuthProvidersFile)
+		if err != nil {
+			return err
+		}
+		providers = idp.NewOIDCRegistry(confs, nil)
+	}
+
+	// the revocations are streamed at /events of the internal listener to the servers which close the conns
+	// of the revoked tokens, they are retained for the validity of the access tokens, which is how long a revocation matters.
+	events := event.NewHub(event.HubOptions{Retain: AccessTokenTTL})
+
+	var sendResetCode func(user *models.Users, code string) error
+	if LogResetCodes {
+		sendResetCode = logResetCode
+	}
 
 	h := auth.NewAuth(auth.AuthOptions{
-		PK:        pk,
-		PublicKey: publicRaw,
-		DB:        CreateMysqlClient("sa1:sa1@tcp(test41:3306)/surf?charset=utf8mb4&parseTime=True&loc=Local"),
-		Cache:     cache.NewMemory(),
+		Keys:  keys,
+		DB:    CreateMysqlClient("sa1:sa1@tcp(test41:3306)/surf?charset=utf8mb4&parseTime=True&loc=Local"),
+		Cache: createCache(),
+
+		AccessTokenTTL:  AccessTokenTTL,
+		RefreshTokenTTL: RefreshTokenTTL,
+
+		PasswdIterations: PasswdIterations,
+		SendResetCode:    sendResetCode,
+
+		CaptchaAfterFailures: CaptchaAfterFailures,
+		LockAfterFailures:    LockAfterFailures,
+
+		TotpIssuer: TotpIssuer,
+		Providers:  providers,
+		Publisher:  events,
 	})
 	ct := h.CTByName()
 
@@ -142,6 +282,11 @@ func RealMain(c *cli.Context) error {
 		ServerId:       1,
 		HttpListenAddr: ":9999",
 		CTByName:       ct,
+		TokenVerifier:  coreauth.WithRevocation(keys, h.RevokeChecker()),
+		Permissions:    permissions,
+		HttpHandlers: map[string]http.Handler{
+			"/.well-known/jwks.json": keys,
+		},
 	})
 
 	err = surf.Start()
@@ -150,6 +295,16 @@ func RealMain(c *cli.Context) error {
 	}
 	defer surf.Close()
 
+	eventsMux := http.NewServeMux()
+	eventsMux.Handle("/events", events)
+	eventsSvr := &http.Server{Addr: EventsListenAddr, Handler: eventsMux}
+	go func() {
+		if err := eventsSvr.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Errorf("serve events failed: %v", err)
+		}
+	}()
+	defer eventsSvr.Close()
+
 	fmt.Println("start http server at:", ListenAddr)
 	signal := utilSignal.WaitShutdown()
 	log.Infof("recv signal: %v", signal.String())
@@ -4,6 +4,11 @@ type UserInfo struct {
 	UId   uint32 `json:"uid"`
 	UName string `json:"uname"`
 	URole uint32 `json:"urid"`
+
+	// the claims of the token which authenticated the user, they are empty before it is signed.
+	TokenID  string `json:"jti,omitempty"`
+	IssuedAt int64  `json:"iat,omitempty"`
+	ExpireAt int64  `json:"exp,omitempty"`
 }
 
 func (u *UserInfo) UserID() uint32 {
@@ -15,3 +20,6 @@ func (u *UserInfo) UserRole() uint32 {
 func (u *UserInfo) UserName() string {
 	return u.UName
 }
+func (u *UserInfo) TokenInfo() *UserInfo {
+	return u
+}
@@ -1,5 +1,299 @@
 package auth
 
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/event"
+	msgcore "github.com/ajenpan/surf/msg/core"
+	msguauth "github.com/ajenpan/surf/msg/uauth"
+)
+
+const protobufContentType = "application/protobuf"
+
+var (
+	ErrTokenInactive   = errors.New(errors.CodeUnauthenticated, "token is not active")
+	ErrAuthUnavailable = errors.New(errors.CodeUnavailable, "auth service unavailable")
+)
+
+type AuthClientOptions struct {
+	// RemoteUrl is the http server of uauth like "http://uauth:9999", which serves the methods at "/<name>".
+	RemoteUrl  string
+	HTTPClient *http.Client
+
+	// CacheTTL is how long an active token or a user info is cached, 1 minute if zero.
+	// A token is never cached after its expiry. A revoked token is still accepted for up to CacheTTL
+	// unless the revocation events of uauth are fed to OnEvent, see event.Subscriber.
+	CacheTTL time.Duration
+	// MaxCacheSize limits the cached tokens and the cached user infos each, 10000 if zero.
+	MaxCacheSize int
+
+	// FailureThreshold is how many calls failing in a row open the breaker, 5 if zero.
+	FailureThreshold int
+	// BreakerCooldown is how long the open breaker refuses the calls before a call tries uauth again,
+	// 10 seconds if zero.
+	BreakerCooldown time.Duration
+}
+
+// AuthClient verifies the tokens by the Introspect of uauth, for the services which don't hold the keys.
+// The active tokens are cached, so a revoked token may be accepted until its cache expires,
+// unless the revocation events are fed to OnEvent.
+// The calls are refused by a breaker while uauth keeps failing, the cached tokens are still accepted then.
 type AuthClient struct {
-	RemoteUrl string
+	opts    AuthClientOptions
+	client  *http.Client
+	now     func() time.Time
+	tokens  *ttlCache[string, *UserInfo]
+	users   *ttlCache[int64, *msguauth.UserInfo]
+	breaker *breaker
+}
+
+func NewAuthClient(opts AuthClientOptions) *AuthClient {
+	if opts.CacheTTL == 0 {
+		opts.CacheTTL = time.Minute
+	}
+	if opts.MaxCacheSize == 0 {
+		opts.MaxCacheSize = 10000
+	}
+	if opts.FailureThreshold == 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.BreakerCooldown == 0 {
+		opts.BreakerCooldown = 10 * time.Second
+	}
+	opts.RemoteUrl = strings.TrimSuffix(opts.RemoteUrl, "/")
+	client := opts.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &AuthClient{
+		opts:    opts,
+		client:  client,
+		now:     time.Now,
+		tokens:  newTTLCache[string, *UserInfo](opts.MaxCacheSize),
+		users:   newTTLCache[int64, *msguauth.UserInfo](opts.MaxCacheSize),
+		breaker: &breaker{threshold: opts.FailureThreshold, cooldown: opts.BreakerCooldown},
+	}
+}
+
+// Verify introspects the token, it is ErrTokenInactive if uauth doesn't accept the token,
+// and ErrAuthUnavailable if the breaker is open.
+func (c *AuthClient) Verify(token []byte) (*UserInfo, error) {
+	now := c.now()
+	if u, ok := c.tokens.get(string(token), now); ok {
+		ret := *u
+		return &ret, nil
+	}
+
+	out := &msguauth.IntrospectResponse{}
+	if err := c.call(context.Background(), "Introspect", &msguauth.IntrospectRequest{Token: string(token)}, out); err != nil {
+		return nil, err
+	}
+	if !out.Active || (out.ExpireAt != 0 && out.ExpireAt <= now.Unix()) {
+		return nil, ErrTokenInactive
+	}
+	u := &UserInfo{
+		UId:      uint32(out.Uid),
+		UName:    out.Uname,
+		URole:    out.Role,
+		TokenID:  out.TokenId,
+		IssuedAt: out.IssuedAt,
+		ExpireAt: out.ExpireAt,
+	}
+	expireAt := now.Add(c.opts.CacheTTL)
+	if u.ExpireAt != 0 && u.ExpireAt < expireAt.Unix() {
+		expireAt = time.Unix(u.ExpireAt, 0)
+	}
+	c.tokens.set(string(token), u, expireAt, now)
+	ret := *u
+	return &ret, nil
+}
+
+// UserInfo fetches the user info by the UserInfo of uauth.
+func (c *AuthClient) UserInfo(ctx context.Context, uid int64) (*msguauth.UserInfo, error) {
+	now := c.now()
+	if info, ok := c.users.get(uid, now); ok {
+		return proto.Clone(info).(*msguauth.UserInfo), nil
+	}
+	out := &msguauth.UserInfoResponse{}
+	if err := c.call(ctx, "UserInfo", &msguauth.UserInfoRequest{Uid: uid}, out); err != nil {
+		return nil, err
+	}
+	if out.Info == nil {
+		return nil, errors.NotFound("user %d not found", uid)
+	}
+	c.users.set(uid, out.Info, now.Add(c.opts.CacheTTL), now)
+	return proto.Clone(out.Info).(*msguauth.UserInfo), nil
+}
+
+// OnEvent drops the cached tokens revoked by the events, so they are introspected again.
+func (c *AuthClient) OnEvent(e *event.Event) {
+	r, ok := ParseRevocationEvent(e)
+	if !ok {
+		return
+	}
+	c.tokens.removeIf(func(u *UserInfo) bool {
+		if r.TokenId != "" {
+			return u.TokenID == r.TokenId
+		}
+		return u.UId == r.Uid && u.IssuedAt <= r.ValidAfter
+	})
+}
+
+// call posts the request in protobuf like client.HTTP, which can't be imported here.
+// The failures of uauth itself, not the errors of the requests, are counted by the breaker.
+func (c *AuthClient) call(ctx context.Context, method string, in proto.Message, out proto.Message) error {
+	if !c.breaker.allow(c.now()) {
+		return ErrAuthUnavailable
+	}
+	err := c.post(ctx, method, in, out)
+	c.breaker.done(err == nil || errors.HTTPStatus(err) < http.StatusInternalServerError, c.now())
+	return err
+}
+
+func (c *AuthClient) post(ctx context.Context, method string, in proto.Message, out proto.Message) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.RemoteUrl+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", protobufContentType)
+	r.Header.Set("Accept", protobufContentType)
+
+	resp, err := c.client.Do(r)
+	if err != nil {
+		return errors.Wrap(err, errors.CodeUnavailable, "auth call failed")
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, errors.CodeUnavailable, "auth read failed")
+	}
+
+	wrap := &msgcore.ResponseMsgWrap{}
+	if err := proto.Unmarshal(raw, wrap); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return errors.New(int32(resp.StatusCode), string(raw))
+		}
+		return errors.Wrap(err, errors.CodeUnavailable, "auth response unreadable")
+	}
+	e := wrap.Err
+	if e == nil && len(wrap.Errors) > 0 {
+		e = wrap.Errors[0]
+	}
+	if e != nil {
+		return &errors.Error{Code: e.Code, Detail: e.Detail, Message: e.Message, Field: e.Field, Metadata: e.Metadata}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(int32(resp.StatusCode), http.StatusText(resp.StatusCode))
+	}
+	return proto.Unmarshal(wrap.Body, out)
+}
+
+// breaker opens after the threshold of failures in a row, and refuses the calls for the cooldown.
+// Then one call at a time tries, the breaker is closed by a success, or opened again by a failure.
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) done(ok bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+type ttlEntry[V any] struct {
+	value    V
+	expireAt time.Time
+}
+
+// ttlCache is a map of the entries which expire, the expired ones are pruned when it is full,
+// and an arbitrary one is dropped if it is still full.
+type ttlCache[K comparable, V any] struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[K]ttlEntry[V]
+}
+
+func newTTLCache[K comparable, V any](max int) *ttlCache[K, V] {
+	return &ttlCache[K, V]{max: max, entries: make(map[K]ttlEntry[V])}
+}
+
+func (c *ttlCache[K, V]) get(key K, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, has := c.entries[key]
+	if !has || !now.Before(e.expireAt) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+func (c *ttlCache[K, V]) set(key K, value V, expireAt time.Time, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, has := c.entries[key]; !has && len(c.entries) >= c.max {
+		for k, e := range c.entries {
+			if !now.Before(e.expireAt) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.max {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = ttlEntry[V]{value: value, expireAt: expireAt}
+}
+
+func (c *ttlCache[K, V]) removeIf(match func(V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if match(e.value) {
+			delete(c.entries, k)
+		}
+	}
 }
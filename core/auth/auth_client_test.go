@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	msgcore "github.com/ajenpan/surf/msg/core"
+	msguauth "github.com/ajenpan/surf/msg/uauth"
+)
+
+func TestAuthClient(t *testing.T) {
+	var calls int32
+	var down atomic.Bool
+	exp := time.Now().Add(time.Hour).Unix()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		raw, _ := io.ReadAll(r.Body)
+		var out proto.Message
+		switch r.URL.Path {
+		case "/Introspect":
+			in := &msguauth.IntrospectRequest{}
+			proto.Unmarshal(raw, in)
+			resp := &msguauth.IntrospectResponse{}
+			if in.Token == "good" {
+				resp = &msguauth.IntrospectResponse{Active: true, Uid: 10001, Uname: "surf_user", TokenId: "jti-1", IssuedAt: 1, ExpireAt: exp}
+			}
+			out = resp
+		case "/UserInfo":
+			out = &msguauth.UserInfoResponse{Info: &msguauth.UserInfo{Uid: 10001, Nickname: "surf"}}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := proto.Marshal(out)
+		raw, _ = proto.Marshal(&msgcore.ResponseMsgWrap{Body: body})
+		w.Write(raw)
+	}))
+	defer svr.Close()
+
+	c := NewAuthClient(AuthClientOptions{RemoteUrl: svr.URL + "/", FailureThreshold: 2, BreakerCooldown: time.Minute})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	u, err := c.Verify([]byte("good"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.UId != 10001 || u.UName != "surf_user" || u.TokenID != "jti-1" {
+		t.Fatal("wrong user:", u)
+	}
+	if _, err := c.Verify([]byte("bad")); !errors.Is(err, ErrTokenInactive) {
+		t.Fatal("the bad token is accepted:", err)
+	}
+	if _, err := c.Verify([]byte("good")); err != nil || atomic.LoadInt32(&calls) != 2 {
+		t.Fatal("the active token is not cached:", err, calls)
+	}
+	if info, err := c.UserInfo(context.Background(), 10001); err != nil || info.Nickname != "surf" {
+		t.Fatal("wrong user info:", info, err)
+	}
+
+	down.Store(true)
+	for i := 0; i < 2; i++ {
+		if _, err := c.Verify([]byte("bad")); err == nil || errors.Is(err, ErrAuthUnavailable) {
+			t.Fatal("the failure is not returned:", err)
+		}
+	}
+	n := atomic.LoadInt32(&calls)
+	if _, err := c.Verify([]byte("bad")); !errors.Is(err, ErrAuthUnavailable) || atomic.LoadInt32(&calls) != n {
+		t.Fatal("the breaker is not open:", err)
+	}
+	if _, err := c.Verify([]byte("good")); err != nil {
+		t.Fatal("the cached token is refused by the open breaker:", err)
+	}
+
+	down.Store(false)
+	now = now.Add(2 * time.Minute)
+	if _, err := c.Verify([]byte("good")); err != nil {
+		t.Fatal("the breaker is not closed after the cooldown:", err)
+	}
+
+	e, err := RevocationEvent(&Revocation{TokenId: "jti-1", ExpireAt: exp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.OnEvent(e)
+	n = atomic.LoadInt32(&calls)
+	c.Verify([]byte("good"))
+	if atomic.LoadInt32(&calls) != n+1 {
+		t.Fatal("the revoked token is still cached")
+	}
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var ErrTokenRequired = errors.New("token required")
+
+// BearerToken is the token of the Authorization header, the "Bearer " prefix is optional
+// since the old clients send the bare token. It is empty if the request has no token.
+func BearerToken(r *http.Request) string {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(header) >= 7 && strings.EqualFold(header[:7], "Bearer ") {
+		header = strings.TrimSpace(header[7:])
+	}
+	return header
+}
+
+// Authenticate verifies the bearer token of the request, the user is nil if the request has no token.
+func Authenticate(v Verifier, r *http.Request) (*UserInfo, error) {
+	token := BearerToken(r)
+	if token == "" {
+		return nil, nil
+	}
+	return v.Verify([]byte(token))
+}
+
+type ctxUserKey struct{}
+
+// WithUser keeps the authenticated user in the context, for the handlers which take a context.Context.
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, ctxUserKey{}, u)
+}
+
+// UserFromContext is the user of WithUser, false if the caller is anonymous.
+func UserFromContext(ctx context.Context) (User, bool) {
+	u, ok := ctx.Value(ctxUserKey{}).(User)
+	return u, ok && u != nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthenticate(t *testing.T) {
+	ring, err := NewKeyRing(KeyRingOptions{Bits: 1024, Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ring.Sign(&UserInfo{UId: 10001, UName: "surf_admin", URole: 100, TokenID: "jti-1"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, header := range []string{"Bearer " + token, "bearer " + token, token} {
+		r := httptest.NewRequest("POST", "/MailBox/SendMail", nil)
+		r.Header.Set("Authorization", header)
+		u, err := Authenticate(ring, r)
+		if err != nil || u == nil || u.UId != 10001 || u.URole != 100 || u.TokenID != "jti-1" {
+			t.Fatalf("authenticate %q: %v, %v", header[:8], u, err)
+		}
+
+		ctx := WithUser(context.Background(), u)
+		if caller, ok := UserFromContext(ctx); !ok || caller.UserID() != 10001 {
+			t.Fatal("the user is not in the context")
+		}
+	}
+
+	r := httptest.NewRequest("POST", "/MailBox/Announcement", nil)
+	if u, err := Authenticate(ring, r); u != nil || err != nil {
+		t.Fatal("the request without token is authenticated:", u, err)
+	}
+	r.Header.Set("Authorization", "Bearer "+token+"x")
+	if _, err := Authenticate(ring, r); err == nil {
+		t.Fatal("a wrong token is authenticated")
+	}
+	if _, ok := UserFromContext(context.Background()); ok {
+		t.Fatal("an anonymous context has a user")
+	}
+}
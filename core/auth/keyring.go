@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/utils/rsagen"
+)
+
+// DefaultKeyBits is the size of the keys generated by KeyRing.
+const DefaultKeyBits = 2048
+
+// SigningKey is a private key of KeyRing.
+type SigningKey struct {
+	ID       string
+	Key      *rsa.PrivateKey
+	CreateAt time.Time
+}
+
+type KeyRingOptions struct {
+	// Bits is the size of the generated keys, DefaultKeyBits if zero.
+	Bits int
+
+	// Dir keeps the keys as "<kid>.pem", the keys are only in memory if it is empty.
+	Dir string
+
+	// RotateInterval is how long a key signs the tokens before a new key is generated, 0 never rotates.
+	RotateInterval time.Duration
+
+	// Overlap is how long a retired key is still published to verify the tokens it signed,
+	// it must be longer than the validity of the tokens. 24 hours if zero.
+	Overlap time.Duration
+}
+
+// KeyRing signs the tokens with its newest key, and publishes the public keys of all its keys.
+// The keys are rotated every RotateInterval, and a retired key is dropped after the Overlap.
+type KeyRing struct {
+	opts KeyRingOptions
+
+	mu   sync.RWMutex
+	keys []*SigningKey // ordered by CreateAt, the last one is current
+	pubs *KeySet
+
+	stopOnce sync.Once
+	chStop   chan struct{}
+}
+
+// NewKeyRing loads the keys of the dir, a new key is generated if there is none.
+func NewKeyRing(opts KeyRingOptions) (*KeyRing, error) {
+	if opts.Bits == 0 {
+		opts.Bits = DefaultKeyBits
+	}
+	if opts.Overlap == 0 {
+		opts.Overlap = 24 * time.Hour
+	}
+	r := &KeyRing{
+		opts:   opts,
+		pubs:   NewKeySet(),
+		chStop: make(chan struct{}),
+	}
+	if opts.Dir != "" {
+		if err := r.loadDir(); err != nil {
+			return nil, err
+		}
+	}
+	if len(r.keys) == 0 {
+		if _, err := r.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *KeyRing) loadDir() error {
+	if err := os.MkdirAll(r.opts.Dir, 0700); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(r.opts.Dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		raw, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		pk, err := rsagen.ParseRsaPrivateKeyFromPem(raw)
+		if err != nil {
+			return fmt.Errorf("%s: %w", f, err)
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		r.add(&SigningKey{ID: KeyID(&pk.PublicKey), Key: pk, CreateAt: info.ModTime()})
+	}
+	r.prune(time.Now())
+	return nil
+}
+
+// Add puts an existing key into the ring, like the private.pem of the old deployments.
+// The key is current only if it is newer than the others.
+func (r *KeyRing) Add(pk *rsa.PrivateKey, createAt time.Time) (*SigningKey, error) {
+	key := &SigningKey{ID: KeyID(&pk.PublicKey), Key: pk, CreateAt: createAt}
+	if err := r.save(key); err != nil {
+		return nil, err
+	}
+	r.add(key)
+	return key, nil
+}
+
+func (r *KeyRing) add(key *SigningKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.ID == key.ID {
+			return
+		}
+	}
+	r.keys = append(r.keys, key)
+	sort.Slice(r.keys, func(i, j int) bool { return r.keys[i].CreateAt.Before(r.keys[j].CreateAt) })
+	r.pubs.Set(key.ID, &key.Key.PublicKey)
+}
+
+// Rotate generates a new key to sign the tokens, the previous ones are kept for the overlap.
+func (r *KeyRing) Rotate() (*SigningKey, error) {
+	pk, err := rsa.GenerateKey(rand.Reader, r.opts.Bits)
+	if err != nil {
+		return nil, err
+	}
+	key := &SigningKey{ID: KeyID(&pk.PublicKey), Key: pk, CreateAt: time.Now()}
+	if err := r.save(key); err != nil {
+		return nil, err
+	}
+	r.add(key)
+	r.prune(key.CreateAt)
+	log.Infof("token key rotated, kid: %s", key.ID)
+	return key, nil
+}
+
+func (r *KeyRing) save(key *SigningKey) error {
+	if r.opts.Dir == "" {
+		return nil
+	}
+	fname := r.keyFile(key.ID)
+	if err := os.WriteFile(fname, rsagen.ExportRsaPrivateKeyAsPem(key.Key), 0600); err != nil {
+		return err
+	}
+	return os.Chtimes(fname, key.CreateAt, key.CreateAt)
+}
+
+func (r *KeyRing) keyFile(kid string) string {
+	return filepath.Join(r.opts.Dir, kid+".pem")
+}
+
+// prune drops the keys retired for longer than the overlap,
+// a key is retired when the next key is created.
+func (r *KeyRing) prune(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.keys) > 1 && now.Sub(r.keys[1].CreateAt) > r.opts.Overlap {
+		old := r.keys[0]
+		r.keys = r.keys[1:]
+		r.pubs.Remove(old.ID)
+		if r.opts.Dir != "" {
+			if err := os.Remove(r.keyFile(old.ID)); err != nil && !os.IsNotExist(err) {
+				log.Warnf("remove retired key %s failed: %v", old.ID, err)
+			}
+		}
+		log.Infof("token key dropped, kid: %s", old.ID)
+	}
+}
+
+// Current is the key which signs the tokens.
+func (r *KeyRing) Current() *SigningKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.keys[len(r.keys)-1]
+}
+
+func (r *KeyRing) Sign(uinfo *UserInfo, validity time.Duration) (string, error) {
+	return GenerateToken(r.Current().Key, uinfo, validity)
+}
+
+func (r *KeyRing) Verify(token []byte) (*UserInfo, error) {
+	return r.pubs.Verify(token)
+}
+
+// PublicKeys is the key set published to the verifiers, it is updated by the rotations.
+func (r *KeyRing) PublicKeys() *KeySet {
+	return r.pubs
+}
+
+// ServeHTTP writes the JWKS document of the public keys.
+func (r *KeyRing) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	raw, err := r.pubs.MarshalJSON()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "max-age=300")
+	w.Write(raw)
+}
+
+// Start rotates the keys every RotateInterval in the background.
+func (r *KeyRing) Start() {
+	if r.opts.RotateInterval <= 0 {
+		return
+	}
+	go func() {
+		check := time.Minute
+		if r.opts.RotateInterval < check {
+			check = r.opts.RotateInterval
+		}
+		ticker := time.NewTicker(check)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.chStop:
+				return
+			case now := <-ticker.C:
+				if now.Sub(r.Current().CreateAt) >= r.opts.RotateInterval {
+					if _, err := r.Rotate(); err != nil {
+						log.Errorf("rotate token key failed: %v", err)
+					}
+				} else {
+					r.prune(now)
+				}
+			}
+		}
+	}()
+}
+
+func (r *KeyRing) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.chStop)
+	})
+}
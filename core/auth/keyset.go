@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown token key")
+
+// KeyID is the kid of the key, the base64url of the first 12 bytes of the sha256 of its PKIX form,
+// the same key always has the same kid.
+func KeyID(pk *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(pk)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// KeySet holds the public keys to verify the tokens, the key of a token is selected by its kid header.
+type KeySet struct {
+	mu   sync.RWMutex
+	keys map[string]*rsa.PublicKey
+}
+
+func NewKeySet(pks ...*rsa.PublicKey) *KeySet {
+	s := &KeySet{keys: make(map[string]*rsa.PublicKey)}
+	for _, pk := range pks {
+		s.Add(pk)
+	}
+	return s
+}
+
+// Add holds the key by its KeyID, and returns the kid.
+func (s *KeySet) Add(pk *rsa.PublicKey) string {
+	kid := KeyID(pk)
+	s.Set(kid, pk)
+	return kid
+}
+
+// Set holds the key by the kid given by its issuer.
+func (s *KeySet) Set(kid string, pk *rsa.PublicKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = pk
+}
+
+func (s *KeySet) Remove(kid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.keys, kid)
+}
+
+// Replace drops all the keys, and holds the ones of the other set.
+func (s *KeySet) Replace(other *KeySet) {
+	keys := make(map[string]*rsa.PublicKey)
+	other.mu.RLock()
+	for kid, pk := range other.keys {
+		keys[kid] = pk
+	}
+	other.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func (s *KeySet) Get(kid string) *rsa.PublicKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[kid]
+}
+
+// IDs returns the sorted kids of the keys.
+func (s *KeySet) IDs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ret := make([]string, 0, len(s.keys))
+	for kid := range s.keys {
+		ret = append(ret, kid)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (s *KeySet) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+// Verify verifies the token with the key of its kid, ErrUnknownKey is returned if the set has no such key.
+// A token signed before the kid header was added is verified by the only key of the set.
+func (s *KeySet) Verify(tokenRaw []byte) (*UserInfo, error) {
+	claims := make(jwt.MapClaims)
+	token, err := jwt.ParseWithClaims(string(tokenRaw), claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if kid == "" {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			if len(s.keys) == 1 {
+				for _, pk := range s.keys {
+					return pk, nil
+				}
+			}
+			return nil, ErrUnknownKey
+		}
+		if pk := s.Get(kid); pk != nil {
+			return pk, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	return userInfoOf(claims), nil
+}
+
+// JWK is a RSA public key in the form of RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// MarshalJSON encodes the keys as a JWKS document.
+func (s *KeySet) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	for kid, pk := range s.keys {
+		doc.Keys = append(doc.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(pk.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pk.E)).Bytes()),
+		})
+	}
+	sort.Slice(doc.Keys, func(i, j int) bool { return doc.Keys[i].Kid < doc.Keys[j].Kid })
+	return json.Marshal(doc)
+}
+
+// ParseJWKS decodes the RSA keys of a JWKS document, the keys of the other types are skipped.
+func ParseJWKS(raw []byte) (*KeySet, error) {
+	doc := &JWKS{}
+	if err := json.Unmarshal(raw, doc); err != nil {
+		return nil, err
+	}
+	s := NewKeySet()
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", k.Kid, err)
+		}
+		s.Set(k.Kid, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())})
+	}
+	return s, nil
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestKeyRingRotate(t *testing.T) {
+	dir := t.TempDir()
+	ring, err := NewKeyRing(KeyRingOptions{Bits: 1024, Dir: dir, Overlap: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	uinfo := &UserInfo{UId: 10001, UName: "surf_user"}
+
+	old, err := ring.Sign(uinfo, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := ring.Current()
+	if _, err := ring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if ring.Current().ID == oldKey.ID {
+		t.Fatal("the key is not rotated")
+	}
+
+	// the tokens of the retired key are verified in the overlap.
+	if u, err := ring.Verify([]byte(old)); err != nil || u.UId != uinfo.UId {
+		t.Fatalf("verify the token of the retired key: %v, %v", u, err)
+	}
+	if ring.PublicKeys().Len() != 2 {
+		t.Fatal("wrong number of public keys:", ring.PublicKeys().IDs())
+	}
+
+	// the keys are loaded from the dir.
+	loaded, err := NewKeyRing(KeyRingOptions{Bits: 1024, Dir: dir, Overlap: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Current().ID != ring.Current().ID || loaded.PublicKeys().Len() != 2 {
+		t.Fatal("wrong loaded keys:", loaded.PublicKeys().IDs())
+	}
+
+	// the retired key is dropped after the overlap.
+	ring.prune(time.Now().Add(2 * time.Hour))
+	if _, err := ring.Verify([]byte(old)); !errors.Is(err, ErrUnknownKey) {
+		t.Fatal("expected ErrUnknownKey, got", err)
+	}
+	if _, err := os.Stat(ring.keyFile(oldKey.ID)); !os.IsNotExist(err) {
+		t.Fatal("the file of the dropped key is not removed")
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	ring, err := NewKeyRing(KeyRingOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr := httptest.NewServer(ring)
+	defer svr.Close()
+
+	remote := NewRemoteKeySet(HTTPKeySource(svr.URL, nil), RemoteKeySetOptions{MinInterval: time.Millisecond})
+	token, _ := ring.Sign(&UserInfo{UId: 1}, time.Minute)
+	// the keys are fetched for the unknown kid.
+	if _, err := remote.Verify([]byte(token)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	token, _ = ring.Sign(&UserInfo{UId: 2}, time.Minute)
+	if u, err := remote.Verify([]byte(token)); err != nil || u.UId != 2 {
+		t.Fatalf("verify the token of the rotated key: %v, %v", u, err)
+	}
+	if remote.Keys().Len() != 2 {
+		t.Fatal("wrong number of fetched keys:", remote.Keys().IDs())
+	}
+
+	// a token signed by a foreign key is refused.
+	other, _ := NewKeyRing(KeyRingOptions{Bits: 1024})
+	token, _ = other.Sign(&UserInfo{UId: 3}, time.Minute)
+	if _, err := remote.Verify([]byte(token)); !errors.Is(err, ErrUnknownKey) {
+		t.Fatal("expected ErrUnknownKey, got", err)
+	}
+}
+
+func TestKeySetLegacyToken(t *testing.T) {
+	ring, err := NewKeyRing(KeyRingOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the tokens signed before the kid header.
+	claims := jwt.MapClaims{"uid": float64(1), "exp": time.Now().Add(time.Minute).Unix()}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(ring.Current().Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := ring.PublicKeys().MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseJWKS(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, err := keys.Verify([]byte(token)); err != nil || u.UId != 1 {
+		t.Fatalf("verify the token without kid: %v, %v", u, err)
+	}
+
+	if _, err := ring.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ring.Verify([]byte(token)); !errors.Is(err, ErrUnknownKey) {
+		t.Fatal("expected ErrUnknownKey for the token without kid, got", err)
+	}
+}
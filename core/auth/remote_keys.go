@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ajenpan/surf/core/log"
+)
+
+// KeySource fetches the JWKS document of the token issuer.
+type KeySource func(ctx context.Context) ([]byte, error)
+
+// HTTPKeySource gets the JWKS document from the url, like "http://uauth:9999/.well-known/jwks.json".
+func HTTPKeySource(url string, client *http.Client) KeySource {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return func(ctx context.Context) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch %s: %s", url, resp.Status)
+		}
+		return io.ReadAll(resp.Body)
+	}
+}
+
+type RemoteKeySetOptions struct {
+	// Interval is how often the keys are fetched, 10 minutes if zero.
+	Interval time.Duration
+	// MinInterval limits the fetches for the tokens of an unknown kid, 30 seconds if zero.
+	MinInterval time.Duration
+}
+
+// RemoteKeySet verifies the tokens with the keys fetched from the issuer.
+// The keys are cached, and fetched again when a token of a new key comes.
+type RemoteKeySet struct {
+	opts   RemoteKeySetOptions
+	source KeySource
+	keys   *KeySet
+
+	mu        sync.Mutex
+	fetchedAt time.Time
+
+	stopOnce sync.Once
+	chStop   chan struct{}
+}
+
+func NewRemoteKeySet(source KeySource, opts RemoteKeySetOptions) *RemoteKeySet {
+	if opts.Interval == 0 {
+		opts.Interval = 10 * time.Minute
+	}
+	if opts.MinInterval == 0 {
+		opts.MinInterval = 30 * time.Second
+	}
+	return &RemoteKeySet{
+		opts:   opts,
+		source: source,
+		keys:   NewKeySet(),
+		chStop: make(chan struct{}),
+	}
+}
+
+// Refresh fetches the keys, the cached keys are kept if it fails.
+func (r *RemoteKeySet) Refresh(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refresh(ctx)
+}
+
+func (r *RemoteKeySet) refresh(ctx context.Context) error {
+	r.fetchedAt = time.Now()
+	raw, err := r.source(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(raw)
+	if err != nil {
+		return err
+	}
+	r.keys.Replace(keys)
+	return nil
+}
+
+// refreshForUnknown fetches the keys if they are not fetched in MinInterval.
+func (r *RemoteKeySet) refreshForUnknown() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.fetchedAt) < r.opts.MinInterval {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.refresh(ctx); err != nil {
+		log.Warnf("fetch token keys failed: %v", err)
+		return false
+	}
+	return true
+}
+
+func (r *RemoteKeySet) Verify(token []byte) (*UserInfo, error) {
+	uinfo, err := r.keys.Verify(token)
+	if errors.Is(err, ErrUnknownKey) && r.refreshForUnknown() {
+		return r.keys.Verify(token)
+	}
+	return uinfo, err
+}
+
+// Key returns the key of the kid, the keys are fetched again for an unknown kid,
+// so the tokens other than the surf tokens, like the id tokens of OIDC, are verified by the caller.
+func (r *RemoteKeySet) Key(kid string) *rsa.PublicKey {
+	if pk := r.keys.Get(kid); pk != nil {
+		return pk
+	}
+	if r.refreshForUnknown() {
+		return r.keys.Get(kid)
+	}
+	return nil
+}
+
+func (r *RemoteKeySet) Keys() *KeySet {
+	return r.keys
+}
+
+// Start fetches the keys every Interval in the background.
+func (r *RemoteKeySet) Start() {
+	go func() {
+		ticker := time.NewTicker(r.opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.chStop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				if err := r.Refresh(ctx); err != nil {
+					log.Warnf("fetch token keys failed: %v", err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+func (r *RemoteKeySet) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.chStop)
+	})
+}
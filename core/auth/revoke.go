@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/event"
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// Revocation is a revoked token, or the tokens of a uid issued until ValidAfter.
+// The tokens are issued in seconds, so the ones of the same second as ValidAfter are revoked too.
+type Revocation = msgcore.TokenRevoked
+
+// RevokedTopic is the topic of the revocation events.
+var RevokedTopic = string(proto.MessageName(&Revocation{}))
+
+// RevocationEvent wraps the revocation to publish.
+func RevocationEvent(r *Revocation) (*event.Event, error) {
+	raw, err := proto.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return &event.Event{Topic: RevokedTopic, Data: raw, Timestamp: time.Now().Unix()}, nil
+}
+
+// ParseRevocationEvent returns false if the event is not a revocation.
+func ParseRevocationEvent(e *event.Event) (*Revocation, bool) {
+	if e.Topic != RevokedTopic {
+		return nil, false
+	}
+	r := &Revocation{}
+	if err := proto.Unmarshal(e.Data, r); err != nil {
+		return nil, false
+	}
+	return r, true
+}
+
+// RevokeChecker tells whether the token of the user is revoked.
+type RevokeChecker interface {
+	IsRevoked(u User) bool
+}
+
+// WithRevocation denies the tokens revoked by the checker after the verifier accepts them.
+func WithRevocation(v Verifier, checker RevokeChecker) Verifier {
+	return &revokeVerifier{Verifier: v, checker: checker}
+}
+
+type revokeVerifier struct {
+	Verifier
+	checker RevokeChecker
+}
+
+func (v *revokeVerifier) Verify(token []byte) (*UserInfo, error) {
+	uinfo, err := v.Verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if v.checker.IsRevoked(uinfo) {
+		return nil, ErrTokenRevoked
+	}
+	return uinfo, nil
+}
+
+type revokedUser struct {
+	validAfter int64
+	expireAt   int64
+}
+
+// DenyList keeps the revocations in memory, it is fed by the revocation events.
+// A revocation is dropped after its expiry, when the tokens it revokes are expired anyway.
+type DenyList struct {
+	mu     sync.RWMutex
+	tokens map[string]int64 // jti -> expire at
+	users  map[uint32]revokedUser
+}
+
+func NewDenyList() *DenyList {
+	return &DenyList{
+		tokens: make(map[string]int64),
+		users:  make(map[uint32]revokedUser),
+	}
+}
+
+func (d *DenyList) Add(r *Revocation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prune(time.Now().Unix())
+
+	if r.TokenId != "" {
+		d.tokens[r.TokenId] = r.ExpireAt
+		return
+	}
+	if old, has := d.users[r.Uid]; has && old.validAfter > r.ValidAfter {
+		return
+	}
+	d.users[r.Uid] = revokedUser{validAfter: r.ValidAfter, expireAt: r.ExpireAt}
+}
+
+func (d *DenyList) prune(now int64) {
+	for jti, exp := range d.tokens {
+		if exp < now {
+			delete(d.tokens, jti)
+		}
+	}
+	for uid, u := range d.users {
+		if u.expireAt < now {
+			delete(d.users, uid)
+		}
+	}
+}
+
+// IsRevoked checks the token of a TokenUser, a user without token info is revoked if its uid is.
+func (d *DenyList) IsRevoked(u User) bool {
+	var token *UserInfo
+	if tu, ok := u.(TokenUser); ok {
+		token = tu.TokenInfo()
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if token != nil && token.TokenID != "" {
+		if _, has := d.tokens[token.TokenID]; has {
+			return true
+		}
+	}
+	ru, has := d.users[u.UserID()]
+	if !has || ru.expireAt < time.Now().Unix() {
+		return false
+	}
+	return token == nil || token.IssuedAt <= ru.validAfter
+}
+
+// OnEvent adds the revocations of the events.
+func (d *DenyList) OnEvent(e *event.Event) {
+	if r, ok := ParseRevocationEvent(e); ok {
+		d.Add(r)
+	}
+}
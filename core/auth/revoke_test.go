@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDenyList(t *testing.T) {
+	ring, err := NewKeyRing(KeyRingOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deny := NewDenyList()
+	verifier := WithRevocation(ring, deny)
+
+	uinfo := &UserInfo{UId: 10001, UName: "surf_user"}
+	first, err := ring.Sign(uinfo, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := ring.Sign(uinfo, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := verifier.Verify([]byte(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.TokenID == "" || u.IssuedAt == 0 || u.ExpireAt == 0 {
+		t.Fatal("no token info:", u)
+	}
+
+	// revoke the first token by the event.
+	e, err := RevocationEvent(&Revocation{TokenId: u.TokenID, Uid: u.UId, ExpireAt: u.ExpireAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deny.OnEvent(e)
+	if _, err := verifier.Verify([]byte(first)); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatal("expected ErrTokenRevoked, got", err)
+	}
+	if _, err := verifier.Verify([]byte(second)); err != nil {
+		t.Fatal(err)
+	}
+
+	// revoke the tokens of the uid issued before now.
+	deny.Add(&Revocation{Uid: u.UId, ValidAfter: time.Now().Unix() + 1, ExpireAt: time.Now().Add(time.Minute).Unix()})
+	if _, err := verifier.Verify([]byte(second)); !errors.Is(err, ErrTokenRevoked) {
+		t.Fatal("expected ErrTokenRevoked, got", err)
+	}
+	if !deny.IsRevoked(&mockUser{uid: u.UId}) {
+		t.Fatal("the user without token info is not revoked")
+	}
+	if deny.IsRevoked(&mockUser{uid: 10002}) {
+		t.Fatal("another user is revoked")
+	}
+
+	// the expired revocations are dropped.
+	deny.Add(&Revocation{Uid: 10003, ValidAfter: time.Now().Unix(), ExpireAt: time.Now().Unix() - 1})
+	if deny.IsRevoked(&mockUser{uid: 10003}) {
+		t.Fatal("the expired revocation is not dropped")
+	}
+}
+
+type mockUser struct {
+	uid uint32
+}
+
+func (u *mockUser) UserID() uint32   { return u.uid }
+func (u *mockUser) UserName() string { return "" }
+func (u *mockUser) UserRole() uint32 { return 0 }
@@ -6,19 +6,30 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// Verifier verifies the tokens, a *KeySet, a *RemoteKeySet or an *AuthClient.
+type Verifier interface {
+	Verify(token []byte) (*UserInfo, error)
+}
+
+// VerifyToken verifies the token with the key, the kid of the token is not checked.
 func VerifyToken(pk *rsa.PublicKey, tokenRaw []byte) (*UserInfo, error) {
 	claims := make(jwt.MapClaims)
 	token, err := jwt.ParseWithClaims(string(tokenRaw), claims, func(t *jwt.Token) (interface{}, error) {
 		return pk, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
 	if err != nil {
 		return nil, err
 	}
 	if !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	return userInfoOf(claims), nil
+}
+
+func userInfoOf(claims jwt.MapClaims) *UserInfo {
 	ret := &UserInfo{}
 	if uname, has := claims["aud"]; has {
 		ret.UName = uname.(string)
@@ -29,9 +40,18 @@ func VerifyToken(pk *rsa.PublicKey, tokenRaw []byte) (*UserInfo, error) {
 	if role, has := claims["urid"]; has {
 		ret.URole = uint32(role.(float64))
 	}
-	return ret, nil
+	ret.TokenID, _ = claims["jti"].(string)
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		ret.IssuedAt = iat.Unix()
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		ret.ExpireAt = exp.Unix()
+	}
+	return ret
 }
 
+// GenerateToken signs the token with the key, the kid header is the KeyID of the key,
+// and the jti claim is the TokenID of the uinfo, or a new id if it is empty, to revoke the token.
 func GenerateToken(pk *rsa.PrivateKey, uinfo *UserInfo, validity time.Duration) (string, error) {
 	if validity == 0 {
 		validity = 24 * time.Hour
@@ -39,10 +59,15 @@ func GenerateToken(pk *rsa.PrivateKey, uinfo *UserInfo, validity time.Duration)
 	claims := make(jwt.MapClaims)
 	claims["exp"] = time.Now().Add(validity).Unix()
 	claims["iat"] = time.Now().Unix()
+	claims["jti"] = uinfo.TokenID
+	if uinfo.TokenID == "" {
+		claims["jti"] = uuid.NewString()
+	}
 	claims["uid"] = float64(uinfo.UId)
 	claims["aud"] = uinfo.UName
 	claims["urid"] = uinfo.URole
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID(&pk.PublicKey)
 	return token.SignedString(pk)
 }
 
@@ -5,3 +5,9 @@ type User interface {
 	UserName() string
 	UserRole() uint32
 }
+
+// TokenUser is a User authenticated by a token, *UserInfo and the types which embed it.
+type TokenUser interface {
+	User
+	TokenInfo() *UserInfo
+}
@@ -0,0 +1,39 @@
+// Package client calls the methods of surf services, over a tcp or ws conn, or over http.
+// the typed clients generated by protoc-gen-surf are built on a Transport:
+//
+//	conn, err := client.DialTcp("localhost:8080", client.ConnOptions{})
+//	mailbox := mailbox.NewMailBoxClient(conn)
+//	resp, err := mailbox.RecvMail(ctx, &mailbox.RecvMailRequest{})
+package client
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Method describes a method of a service for the transports.
+type Method struct {
+	// Name is the key of the method in the calltable of the server.
+	Name string
+	// HttpPath and HttpVerb are declared by the method options, empty if not declared.
+	HttpPath string
+	HttpVerb string
+}
+
+// Transport sends the calls to the server.
+type Transport interface {
+	// Call sends the request, and decodes the response into out.
+	// the error from the server is an *errors.Error, or an *errors.MultiError of the invalid fields.
+	Call(ctx context.Context, m *Method, in proto.Message, out proto.Message) error
+	// Stream opens a server stream, the stream is closed when ctx is done.
+	Stream(ctx context.Context, m *Method, in proto.Message) (Stream, error)
+}
+
+// Stream receives the responses of a server stream.
+type Stream interface {
+	// Recv decodes the next response into m, it returns io.EOF after the stream ends successfully.
+	Recv(m proto.Message) error
+	// Close cancels the stream.
+	Close() error
+}
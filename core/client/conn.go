@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/network"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+// DefaultStreamCredits is the window of the streams opened by Conn.
+var DefaultStreamCredits uint32 = 16
+
+var ErrConnClosed = errors.Unavailable("conn closed")
+
+type ConnOptions struct {
+	// OnPacket and OnClose of ClientOptions are taken by Conn.
+	network.ClientOptions
+
+	// NamePrefix is put before the method names, like "mailbox/" to call through a gateway.
+	NamePrefix string
+
+	// OnAsync receives the async messages pushed by the server, it must not block.
+	OnAsync func(*msg.AsyncMsgWrap)
+
+	// StreamCredits is the window of a stream, DefaultStreamCredits if zero.
+	StreamCredits uint32
+}
+
+// Conn calls the methods of core.Surf over a tcp or ws conn.
+type Conn struct {
+	opts ConnOptions
+	conn *network.ClientConn
+
+	seqid    uint32
+	streamid uint32
+
+	mu      sync.Mutex
+	pending map[uint32]chan *msg.ResponseMsgWrap
+	streams map[uint32]*connStream
+}
+
+func DialTcp(addr string, opts ConnOptions) (*Conn, error) {
+	return dial(opts, func(copts network.ClientOptions) (*network.ClientConn, error) {
+		return network.DialTcp(addr, copts)
+	})
+}
+
+func DialWS(url string, opts ConnOptions) (*Conn, error) {
+	return dial(opts, func(copts network.ClientOptions) (*network.ClientConn, error) {
+		return network.DialWS(url, copts)
+	})
+}
+
+func dial(opts ConnOptions, f func(network.ClientOptions) (*network.ClientConn, error)) (*Conn, error) {
+	if opts.StreamCredits == 0 {
+		opts.StreamCredits = DefaultStreamCredits
+	}
+	c := &Conn{
+		opts:    opts,
+		pending: make(map[uint32]chan *msg.ResponseMsgWrap),
+		streams: make(map[uint32]*connStream),
+	}
+	copts := opts.ClientOptions
+	copts.OnPacket = c.onPacket
+	copts.OnClose = c.onClose
+
+	conn, err := f(copts)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	return c, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) Call(ctx context.Context, m *Method, in proto.Message, out proto.Message) error {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return err
+	}
+	seqid := atomic.AddUint32(&c.seqid, 1)
+	ch := make(chan *msg.ResponseMsgWrap, 1)
+
+	c.mu.Lock()
+	c.pending[seqid] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, seqid)
+		c.mu.Unlock()
+	}()
+
+	req := &msg.RequestMsgWrap{Name: c.opts.NamePrefix + m.Name, Body: body, Seqid: seqid}
+	if err := c.send(msg.MsgType_Request, req); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if err := network.ErrorFromMsgs(resp.Err, resp.Errors); err != nil {
+			return err
+		}
+		return proto.Unmarshal(resp.Body, out)
+	case <-ctx.Done():
+		return ctxError(ctx)
+	case <-c.conn.Done():
+		return ErrConnClosed
+	}
+}
+
+func (c *Conn) Stream(ctx context.Context, m *Method, in proto.Message) (Stream, error) {
+	body, err := proto.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	s := &connStream{
+		id:     atomic.AddUint32(&c.streamid, 1),
+		conn:   c,
+		window: c.opts.StreamCredits,
+		// the server sends no more than the credits, and a close frame.
+		frames: make(chan *msg.StreamMsgWrap, c.opts.StreamCredits+1),
+		done:   make(chan struct{}),
+	}
+
+	c.mu.Lock()
+	c.streams[s.id] = s
+	c.mu.Unlock()
+
+	open := &msg.StreamMsgWrap{
+		StreamId: s.id,
+		Frame:    msg.StreamFrame_StreamOpen,
+		Name:     c.opts.NamePrefix + m.Name,
+		Body:     body,
+		Credits:  s.window,
+	}
+	if err := c.send(msg.MsgType_Stream, open); err != nil {
+		c.removeStream(s.id)
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Close()
+		case <-s.done:
+		}
+	}()
+	return s, nil
+}
+
+func (c *Conn) send(typ msg.MsgType, wrap proto.Message) error {
+	raw, err := proto.Marshal(wrap)
+	if err != nil {
+		return err
+	}
+	pk := network.NewHVPacket()
+	pk.SetFlag(network.HVPacketFlagPacket)
+	pk.SetSubFlag(uint8(typ))
+	pk.SetBody(raw)
+	if err := c.conn.Send(pk); err != nil {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+func (c *Conn) removeStream(id uint32) *connStream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.streams[id]
+	delete(c.streams, id)
+	return s
+}
+
+func (c *Conn) onPacket(_ *network.ClientConn, pk *network.HVPacket) {
+	switch msg.MsgType(pk.GetSubFlag()) {
+	case msg.MsgType_Response:
+		resp := &msg.ResponseMsgWrap{}
+		if err := proto.Unmarshal(pk.GetBody(), resp); err != nil {
+			log.Warnf("client unmarshal response failed: %v", err)
+			return
+		}
+		c.mu.Lock()
+		ch := c.pending[resp.Seqid]
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- resp
+		}
+	case msg.MsgType_Stream:
+		wrap := &msg.StreamMsgWrap{}
+		if err := proto.Unmarshal(pk.GetBody(), wrap); err != nil {
+			log.Warnf("client unmarshal stream frame failed: %v", err)
+			return
+		}
+		var s *connStream
+		if wrap.Frame == msg.StreamFrame_StreamClose {
+			s = c.removeStream(wrap.StreamId)
+		} else {
+			c.mu.Lock()
+			s = c.streams[wrap.StreamId]
+			c.mu.Unlock()
+		}
+		if s != nil {
+			s.frames <- wrap
+		}
+	case msg.MsgType_Async:
+		wrap := &msg.AsyncMsgWrap{}
+		if err := proto.Unmarshal(pk.GetBody(), wrap); err != nil {
+			log.Warnf("client unmarshal async msg failed: %v", err)
+			return
+		}
+		if c.opts.OnAsync != nil {
+			c.opts.OnAsync(wrap)
+		}
+	}
+}
+
+func (c *Conn) onClose(_ *network.ClientConn) {
+	c.mu.Lock()
+	streams := c.streams
+	c.streams = make(map[uint32]*connStream)
+	c.mu.Unlock()
+
+	for _, s := range streams {
+		s.finish(ErrConnClosed)
+	}
+}
+
+func ctxError(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.Wrap(ctx.Err(), errors.CodeDeadlineExceeded, "call timeout")
+	}
+	return errors.Wrap(ctx.Err(), errors.CodeCanceled, "call canceled")
+}
+
+// connStream grants the credits back to the server when half of the window is received.
+type connStream struct {
+	id     uint32
+	conn   *Conn
+	window uint32
+	frames chan *msg.StreamMsgWrap
+
+	received uint32
+
+	once sync.Once
+	done chan struct{}
+	err  error
+}
+
+func (s *connStream) Recv(m proto.Message) error {
+	var wrap *msg.StreamMsgWrap
+	select {
+	case wrap = <-s.frames:
+	case <-s.done:
+		// the frames which arrived before the end are still received.
+		select {
+		case wrap = <-s.frames:
+		default:
+			return s.err
+		}
+	}
+
+	if wrap.Frame == msg.StreamFrame_StreamClose {
+		err := network.ErrorFromMsgs(wrap.Err, wrap.Errors)
+		if err == nil {
+			err = io.EOF
+		}
+		s.finish(err)
+		return err
+	}
+
+	s.received++
+	if s.received*2 >= s.window {
+		credit := &msg.StreamMsgWrap{StreamId: s.id, Frame: msg.StreamFrame_StreamCredit, Credits: s.received}
+		s.received = 0
+		if err := s.conn.send(msg.MsgType_Stream, credit); err != nil {
+			return err
+		}
+	}
+	return proto.Unmarshal(wrap.Body, m)
+}
+
+func (s *connStream) Close() error {
+	if s.conn.removeStream(s.id) == nil {
+		return nil
+	}
+	s.finish(errors.Canceled("stream canceled"))
+	return s.conn.send(msg.MsgType_Stream, &msg.StreamMsgWrap{StreamId: s.id, Frame: msg.StreamFrame_StreamCancel})
+}
+
+func (s *connStream) finish(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
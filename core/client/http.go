@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/marshal"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+const protobufContentType = "application/protobuf"
+
+// HTTP calls the methods served by network.HttpSvr or core.Surf over http, in protobuf.
+type HTTP struct {
+	// BaseURL is where the methods are served, like "http://localhost:8080/MailBox".
+	BaseURL string
+	Client  *http.Client
+	// Token returns the bearer token of the calls, nil for anonymous calls.
+	Token func() string
+}
+
+func (c *HTTP) client() *http.Client {
+	if c.Client == nil {
+		return http.DefaultClient
+	}
+	return c.Client
+}
+
+// url returns the declared http path on the host of BaseURL, or the method name under BaseURL.
+func (c *HTTP) url(m *Method) string {
+	base := strings.TrimSuffix(c.BaseURL, "/")
+	if len(m.HttpPath) == 0 {
+		return base + "/" + m.Name
+	}
+	if i := strings.Index(base, "://"); i >= 0 {
+		if j := strings.Index(base[i+3:], "/"); j >= 0 {
+			base = base[:i+3+j]
+		}
+	}
+	return base + m.HttpPath
+}
+
+func (c *HTTP) Call(ctx context.Context, m *Method, in proto.Message, out proto.Message) error {
+	var r *http.Request
+	var err error
+	if m.HttpVerb == http.MethodGet {
+		query, err := marshal.MarshalQuery(in)
+		if err != nil {
+			return err
+		}
+		r, err = http.NewRequestWithContext(ctx, http.MethodGet, c.url(m)+"?"+query.Encode(), nil)
+		if err != nil {
+			return err
+		}
+	} else {
+		body, err := proto.Marshal(in)
+		if err != nil {
+			return err
+		}
+		verb := m.HttpVerb
+		if len(verb) == 0 {
+			verb = http.MethodPost
+		}
+		r, err = http.NewRequestWithContext(ctx, verb, c.url(m), bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Content-Type", protobufContentType)
+	}
+	r.Header.Set("Accept", protobufContentType)
+	if c.Token != nil {
+		if token := c.Token(); len(token) > 0 {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.client().Do(r)
+	if err != nil {
+		return errors.Wrap(err, errors.CodeUnavailable, "http call failed")
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, errors.CodeUnavailable, "http read failed")
+	}
+
+	wrap := &msg.ResponseMsgWrap{}
+	if err := proto.Unmarshal(raw, wrap); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return errors.New(int32(resp.StatusCode), string(raw))
+		}
+		return err
+	}
+	if err := network.ErrorFromMsgs(wrap.Err, wrap.Errors); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(int32(resp.StatusCode), http.StatusText(resp.StatusCode))
+	}
+	return proto.Unmarshal(wrap.Body, out)
+}
+
+// Stream is not supported, the server streams are not served over http.
+func (c *HTTP) Stream(ctx context.Context, m *Method, in proto.Message) (Stream, error) {
+	return nil, errors.Unimplemented("stream %s is not served over http", m.Name)
+}
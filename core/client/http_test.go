@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+)
+
+func find(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if in.Value != "a" {
+		return nil, errors.NotFound("%s not found", in.Value)
+	}
+	return wrapperspb.String("found:" + in.Value), nil
+}
+
+func TestHTTP(t *testing.T) {
+	m := calltable.NewMethod(find)
+	ct := calltable.NewCallTable[string]()
+	ct.Add("Find", m)
+	svr := &network.HttpSvr{Mux: http.NewServeMux()}
+	svr.ServerCallTable(ct)
+	ts := httptest.NewServer(svr.Mux)
+	defer ts.Close()
+
+	c := &HTTP{BaseURL: ts.URL}
+	out := &wrapperspb.StringValue{}
+	if err := c.Call(context.Background(), &Method{Name: "Find"}, wrapperspb.String("a"), out); err != nil || out.Value != "found:a" {
+		t.Fatalf("unexpected response: %v, %v", out, err)
+	}
+
+	err := c.Call(context.Background(), &Method{Name: "Find", HttpVerb: http.MethodGet}, wrapperspb.String("b"), out)
+	if e, ok := errors.As(err); !ok || e.Code != errors.CodeNotFound || e.Detail != "b not found" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	gocontext "context"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/ajenpan/surf/core/client"
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+)
+
+func echo(ctx Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if len(in.Value) == 0 {
+		return nil, errors.InvalidArgument("empty value")
+	}
+	return wrapperspb.String("echo:" + in.Value), nil
+}
+
+// remoteAddr takes a plain context.Context like the generated handlers, the call context is found by ContextOf.
+func remoteAddr(ctx gocontext.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	c := ContextOf(ctx)
+	if c == nil {
+		return nil, errors.Internal("no call context")
+	}
+	return wrapperspb.String(c.RemoteAddr()), nil
+}
+
+func TestClientConn(t *testing.T) {
+	h := newStreamSurf()
+	h.CTByName.Add("Echo", calltable.NewMethod(echo))
+	// the timeout derives the context of the handler.
+	m := calltable.NewMethod(remoteAddr)
+	m.Meta = &calltable.MethodMeta{Timeout: time.Second}
+	h.CTByName.Add("RemoteAddr", m)
+
+	svr, err := network.NewTcpServer(network.TcpServerOptions{
+		ListenAddr:   "127.0.0.1:0",
+		OnConnPacket: h.onConnPacket,
+		OnConnEnable: h.onConnStatus,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.Start()
+	defer svr.Stop()
+
+	c, err := client.DialTcp(svr.Address().String(), client.ConnOptions{StreamCredits: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := gocontext.Background()
+	out := &wrapperspb.StringValue{}
+	if err := c.Call(ctx, &client.Method{Name: "Echo"}, wrapperspb.String("a"), out); err != nil || out.Value != "echo:a" {
+		t.Fatalf("unexpected response: %v, %v", out, err)
+	}
+
+	err = c.Call(ctx, &client.Method{Name: "Echo"}, wrapperspb.String(""), out)
+	if e, ok := errors.As(err); !ok || e.Code != errors.CodeInvalidArgument || e.Message != "invalid argument" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := c.Call(ctx, &client.Method{Name: "RemoteAddr"}, wrapperspb.String(""), out); err != nil || out.Value == "" {
+		t.Fatalf("unexpected remote addr: %v, %v", out, err)
+	}
+
+	s, err := c.Stream(ctx, &client.Method{Name: "Count"}, wrapperspb.UInt32(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint32(0); i < 5; i++ {
+		m := &wrapperspb.UInt32Value{}
+		if err := s.Recv(m); err != nil || m.Value != i {
+			t.Fatalf("unexpected frame %d: %v, %v", i, m, err)
+		}
+	}
+	if err := s.Recv(&wrapperspb.UInt32Value{}); err != io.EOF {
+		t.Fatalf("the stream is not ended: %v", err)
+	}
+}
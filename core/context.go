@@ -1,29 +1,134 @@
 package core
 
 import (
+	gocontext "context"
+	"fmt"
+	"net"
+
+	"google.golang.org/protobuf/proto"
+
 	"github.com/ajenpan/surf/core/auth"
 	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	msg "github.com/ajenpan/surf/msg/core"
 )
 
+// Context is the call context of a method, it is a context.Context too, so the methods generated
+// by protoc-gen-surf take it. The caller is kept by auth.WithUser, see ContextOf for the rest.
 type Context interface {
+	gocontext.Context
 	Response(msg interface{}, err error)
 	SendAsync(msg interface{}) error
 	Caller() auth.User
+	// RemoteAddr is the address of the caller, like "1.2.3.4:5678", empty if it is unknown.
+	RemoteAddr() string
+	// Stream sends the responses of a server streaming call, it's nil for the unary calls.
+	Stream() calltable.Stream
+}
+
+// callResult records the response of an async handler,
+// the transport writes it back after the call, see calltable.Responder.
+type callResult struct {
+	resp      interface{}
+	err       error
+	responded bool
+}
+
+func (r *callResult) Response(msg interface{}, err error) {
+	r.resp, r.err, r.responded = msg, err, true
+}
+
+func (r *callResult) Responded() (interface{}, error, bool) {
+	return r.resp, r.err, r.responded
+}
+
+type callContextKey struct{}
+
+// ContextOf is the call context which ctx is derived from, nil if ctx is not of a call.
+func ContextOf(ctx gocontext.Context) Context {
+	c, _ := ctx.Value(callContextKey{}).(Context)
+	return c
+}
+
+// withCaller derives the context of a call, the caller is kept by auth.WithUser if it is known.
+func withCaller(parent gocontext.Context, u auth.User) gocontext.Context {
+	if u == nil {
+		return parent
+	}
+	return auth.WithUser(parent, u)
 }
 
 type context struct {
+	gocontext.Context
+	callResult
 	Conn network.Conn
 	Core *Surf
+
+	stream *serverStream
 }
 
-func (ctx *context) Response(msg interface{}, err error) {
+// newContext is the context of a call on the conn, it is canceled with the stream if it is a stream call.
+func newContext(c network.Conn, h *Surf, s *serverStream) *context {
+	parent := gocontext.Background()
+	if s != nil {
+		parent = s.ctx
+	}
+	return &context{Context: withCaller(parent, network.ConnUser(c)), Conn: c, Core: h, stream: s}
+}
 
+func (ctx *context) Value(key interface{}) interface{} {
+	if key == (callContextKey{}) {
+		return ctx
+	}
+	return ctx.Context.Value(key)
 }
 
-func (ctx *context) SendAsync(msg interface{}) error {
-	return nil
+func (ctx *context) SendAsync(m interface{}) error {
+	pbmsg, ok := m.(proto.Message)
+	if !ok {
+		return fmt.Errorf("SendAsync: %T is not a proto message", m)
+	}
+	body, err := proto.Marshal(pbmsg)
+	if err != nil {
+		return err
+	}
+	wrap := &msg.AsyncMsgWrap{
+		Name: string(pbmsg.ProtoReflect().Descriptor().Name()),
+		Body: body,
+	}
+	return sendMsgWrap(ctx.Conn, msg.MsgType_Async, wrap)
 }
 
 func (ctx *context) Caller() auth.User {
-	return nil
+	return network.ConnUser(ctx.Conn)
+}
+
+func (ctx *context) RemoteAddr() string {
+	c, ok := ctx.Conn.(interface{ RemoteAddr() net.Addr })
+	if !ok {
+		return ""
+	}
+	if addr := c.RemoteAddr(); addr != nil {
+		return addr.String()
+	}
+	return ""
+}
+
+func (ctx *context) Stream() calltable.Stream {
+	if ctx.stream == nil {
+		return nil
+	}
+	return ctx.stream
+}
+
+func sendMsgWrap(c network.Conn, typ msg.MsgType, wrap proto.Message) error {
+	raw, err := proto.Marshal(wrap)
+	if err != nil {
+		return err
+	}
+	pk := network.NewHVPacket()
+	pk.SetFlag(network.HVPacketFlagPacket)
+	pk.SetSubFlag(uint8(typ))
+	pk.SetBody(raw)
+	return c.Send(pk)
 }
@@ -1,19 +1,24 @@
 package core
 
 import (
-	"crypto/rsa"
-	"io"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/ajenpan/surf/core/log"
 
+	"google.golang.org/protobuf/proto"
+
 	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/event"
 	"github.com/ajenpan/surf/core/network"
 	"github.com/ajenpan/surf/core/registry"
 	"github.com/ajenpan/surf/core/utils/calltable"
-	"github.com/ajenpan/surf/core/utils/marshal"
+	"github.com/ajenpan/surf/core/utils/msgid"
+	"github.com/ajenpan/surf/core/utils/openapi"
+	msg "github.com/ajenpan/surf/msg/core"
 )
 
 type Options struct {
@@ -24,16 +29,38 @@ type Options struct {
 	HttpListenAddr string
 	WsListenAddr   string
 	TcpListenAddr  string
+	// HttpMaxBodySize limits the request bodies of the http methods, network.DefaultMaxHttpBodySize if zero.
+	HttpMaxBodySize int64
 
 	CTByName *calltable.CallTable[string]
 	CTById   *calltable.CallTable[int32]
+
+	// ServeDocs serves the openapi document of CTByName at /openapi.json and the docs page at /docs.
+	ServeDocs bool
+
+	// TokenVerifier authenticates the conns and the bearer tokens of the http requests,
+	// the callers are anonymous if it is nil.
+	TokenVerifier auth.Verifier
+	// Permissions grants the permissions declared by the methods to the roles of the callers,
+	// calltable.DefaultPermissions if nil.
+	Permissions *calltable.Permissions
+
+	// HttpHandlers are served by the http server besides the methods, keyed by the pattern.
+	HttpHandlers map[string]http.Handler
+
+	// EventStreams are the event hubs of uauth, like http://uauth:9998/events,
+	// their revocations are fed to OnEvent. The revoked tokens are accepted until they expire if it is empty.
+	EventStreams []string
 }
 
 func New(opt Options) *Surf {
 	s := &Surf{
-		Options: opt,
+		Options:    opt,
+		dispatcher: calltable.NewDispatcher(),
+		revoked:    auth.NewDenyList(),
 		// routeClient: make(map[string]*network.TcpClient),
 	}
+	s.dispatcher.Permissions = opt.Permissions
 
 	// for _, addr := range opt.RouteAddrs {
 	// 	opts := &network.TcpClientOptions{
@@ -103,22 +130,26 @@ func New(opt Options) *Surf {
 
 type Surf struct {
 	Options
-	pk  *rsa.PublicKey
 	Reg *registry.Registry
 
 	tcpsvr  *network.TcpServer
 	wssvr   *network.WSServer
 	httpsvr *http.Server
+
+	dispatcher *calltable.Dispatcher
+	streams    streamTable
+	revoked    *auth.DenyList
+	events     []*event.Subscriber
 }
 
 func (s *Surf) init() error {
 	var err error
 	tcpsvr, err := network.NewTcpServer(network.TcpServerOptions{
-		ListenAddr:       ":9999",
+		ListenAddr:       s.TcpListenAddr,
 		HeatbeatInterval: 30 * time.Second,
 		OnConnPacket:     s.onConnPacket,
 		OnConnEnable:     s.onConnStatus,
-		OnConnAuth:       s.onConnAuth,
+		OnConnAuth:       s.connAuth(),
 	})
 	if err != nil {
 		return err
@@ -128,11 +159,18 @@ func (s *Surf) init() error {
 }
 
 func (s *Surf) Close() error {
-
+	for _, sub := range s.events {
+		sub.Stop()
+	}
 	return nil
 }
 
 func (s *Surf) Start() error {
+	// the msgids claimed twice by the linked proto files are refused before serving.
+	if err := msgid.Load(); err != nil {
+		return fmt.Errorf("load msgids: %w", err)
+	}
+
 	if len(s.HttpListenAddr) > 1 {
 		s.startHttpSvr()
 	}
@@ -144,6 +182,12 @@ func (s *Surf) Start() error {
 	if len(s.TcpListenAddr) > 1 {
 		s.startTcpSvr()
 	}
+
+	for _, url := range s.EventStreams {
+		sub := event.NewSubscriber(event.SubscriberOptions{URL: url, Topics: []string{auth.RevokedTopic}}, s)
+		sub.Start()
+		s.events = append(s.events, sub)
+	}
 	// quit := make(chan struct{})
 	// s.httpsvr = &network.HttpSvr{
 	// 	Addr:    s.HttpListenAddr,
@@ -167,22 +211,38 @@ func (s *Surf) Start() error {
 func (s *Surf) startHttpSvr() {
 	log.Infof("startHttpSvr")
 
+	svr := &http.Server{
+		Addr:    s.HttpListenAddr,
+		Handler: s.HttpHandler(),
+	}
+	s.httpsvr = svr
+	go svr.ListenAndServe()
+}
+
+// HttpHandler serves the methods at "/<name>", or their http routes, and the HttpHandlers.
+func (s *Surf) HttpHandler() http.Handler {
 	mux := http.NewServeMux()
 	s.CTByName.Range(func(key string, method *calltable.Method) bool {
+		if method.Style == calltable.StyleServerStream {
+			return true
+		}
 		if !strings.HasPrefix(key, "/") {
 			key = "/" + key
 		}
+		key, _ = method.HttpRoute(key)
 		cb := s.WrapMethod(method)
 		mux.HandleFunc(key, cb)
 		return true
 	})
 
-	svr := &http.Server{
-		Addr:    s.HttpListenAddr,
-		Handler: mux,
+	if s.ServeDocs {
+		doc := openapi.Generate(s.CTByName, openapi.Options{Title: "surf", PathPrefix: "/"})
+		openapi.Serve(mux, doc, true)
 	}
-	s.httpsvr = svr
-	go svr.ListenAndServe()
+	for pattern, handler := range s.HttpHandlers {
+		mux.Handle(pattern, handler)
+	}
+	return mux
 }
 
 func (s *Surf) startWsSvr() {
@@ -192,9 +252,9 @@ func (s *Surf) startWsSvr() {
 		ListenAddr:   s.WsListenAddr,
 		OnConnPacket: s.onConnPacket,
 		OnConnEnable: s.onConnStatus,
-		OnConnAuth:   s.onConnAuth,
+		OnConnAuth:   s.connAuth(),
 	})
-
+	s.wssvr = ws
 	ws.Start()
 }
 
@@ -202,11 +262,11 @@ func (s *Surf) startTcpSvr() {
 	log.Infof("startTcpSvr")
 
 	tcpsvr, err := network.NewTcpServer(network.TcpServerOptions{
-		ListenAddr:       ":9999",
+		ListenAddr:       s.TcpListenAddr,
 		HeatbeatInterval: 30 * time.Second,
 		OnConnPacket:     s.onConnPacket,
 		OnConnEnable:     s.onConnStatus,
-		OnConnAuth:       s.onConnAuth,
+		OnConnAuth:       s.connAuth(),
 	})
 	if err != nil {
 		panic(err)
@@ -215,23 +275,130 @@ func (s *Surf) startTcpSvr() {
 	tcpsvr.Start()
 }
 
-func (h *Surf) onConnPacket(s network.Conn, pk *network.HVPacket) {
-	// ctx := &Context{Session: s, UId: m.GetUid()}
-	// h.OnServerMsgWraper(ctx, m)
-	sf := pk.GetSubFlag()
-	switch sf {
-	case 1:
-		pk.GetBody()
+func (h *Surf) onConnPacket(c network.Conn, pk *network.HVPacket) {
+	switch msg.MsgType(pk.GetSubFlag()) {
+	case msg.MsgType_Async:
+		wrap := &msg.AsyncMsgWrap{}
+		if err := proto.Unmarshal(pk.GetBody(), wrap); err != nil {
+			log.Warnf("conn:%s, unmarshal async msg failed: %v", c.ConnID(), err)
+			return
+		}
+		if _, err := h.call(newContext(c, h, nil), wrap.Name, wrap.Body); err != nil {
+			log.Warnf("conn:%s, call %s failed: %v", c.ConnID(), wrap.Name, err)
+		}
+	case msg.MsgType_Request:
+		wrap := &msg.RequestMsgWrap{}
+		if err := proto.Unmarshal(pk.GetBody(), wrap); err != nil {
+			log.Warnf("conn:%s, unmarshal request msg failed: %v", c.ConnID(), err)
+			return
+		}
+		resp := &msg.ResponseMsgWrap{
+			Name:  wrap.Name,
+			Seqid: wrap.Seqid,
+		}
+		out, err := h.call(newContext(c, h, nil), wrap.Name, wrap.Body)
+		if err == nil && out != nil {
+			if pbout, ok := out.(proto.Message); ok {
+				resp.Body, err = proto.Marshal(pbout)
+			} else {
+				err = fmt.Errorf("response %T is not a proto message", out)
+			}
+		}
+		if err != nil {
+			network.SetResponseError(resp, err)
+		}
+		if err := sendMsgWrap(c, msg.MsgType_Response, resp); err != nil {
+			log.Warnf("conn:%s, send response failed: %v", c.ConnID(), err)
+		}
+	case msg.MsgType_Stream:
+		wrap := &msg.StreamMsgWrap{}
+		if err := proto.Unmarshal(pk.GetBody(), wrap); err != nil {
+			log.Warnf("conn:%s, unmarshal stream msg failed: %v", c.ConnID(), err)
+			return
+		}
+		h.onStreamFrame(c, wrap)
 	default:
+		log.Warnf("conn:%s, unknown msg type: %d", c.ConnID(), pk.GetSubFlag())
 	}
 }
 
+// call decodes the request of the named method, and invokes it in whatever style it is.
+func (h *Surf) call(ctx Context, name string, body []byte) (interface{}, error) {
+	if h.CTByName == nil {
+		return nil, errors.NotFound("method not found: %s", name)
+	}
+	method := h.CTByName.Get(name)
+	if method == nil {
+		return nil, errors.NotFound("method not found: %s", name)
+	}
+	req, ok := method.NewRequest().(proto.Message)
+	if !ok {
+		return nil, calltable.ErrInvalidRequest
+	}
+	if err := proto.Unmarshal(body, req); err != nil {
+		return nil, errors.Wrap(err, errors.CodeInvalidArgument, "invalid request body")
+	}
+	var caller calltable.Caller
+	if u := ctx.Caller(); u != nil {
+		caller = u
+	}
+	return h.dispatcher.Invoke(method, caller, ctx, req)
+}
+
 func (h *Surf) onConnAuth(data []byte) (auth.User, error) {
-	return auth.VerifyToken(h.pk, data)
+	return h.verifyToken(data)
+}
+
+// verifyToken denies the tokens revoked by the events besides the TokenVerifier.
+func (h *Surf) verifyToken(token []byte) (*auth.UserInfo, error) {
+	user, err := h.TokenVerifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if h.revoked.IsRevoked(user) {
+		return nil, auth.ErrTokenRevoked
+	}
+	return user, nil
+}
+
+// OnEvent receives the revocation events, the revoked tokens are denied
+// and the conns authenticated by them are closed.
+func (h *Surf) OnEvent(e *event.Event) {
+	r, ok := auth.ParseRevocationEvent(e)
+	if !ok {
+		return
+	}
+	h.revoked.Add(r)
+
+	revoked := func(c network.Conn) bool {
+		u := network.ConnUser(c)
+		return u != nil && h.revoked.IsRevoked(u)
+	}
+	kicked := 0
+	if h.tcpsvr != nil {
+		kicked += h.tcpsvr.Conns().CloseIf(revoked)
+	}
+	if h.wssvr != nil {
+		kicked += h.wssvr.Conns().CloseIf(revoked)
+	}
+	if kicked > 0 {
+		log.Infof("closed %d conns of the revoked tokens, uid: %d", kicked, r.Uid)
+	}
+}
+
+// connAuth is the OnConnAuth of the servers, nil accepts the conns without token.
+func (h *Surf) connAuth() network.FuncOnConnAuth {
+	if h.TokenVerifier == nil {
+		return nil
+	}
+	return h.onConnAuth
 }
 
 func (h *Surf) onConnStatus(s network.Conn, enable bool) {
 	// log.Infof("route onstatus: %v, %v", s.SessionID(), enable)
+	if !enable {
+		h.streams.removeConn(s.ConnID())
+	}
 }
 
 // func (h *Surf) OnAsync(s network.Session, uid uint32, m *network.AsyncMsg) {
@@ -282,32 +449,41 @@ func (h *Surf) onConnStatus(s network.Conn, enable bool) {
 // }
 
 func (s *Surf) WrapMethod(method *calltable.Method) http.HandlerFunc {
+	codecs := network.DefaultHttpCodecs
+	_, verb := method.HttpRoute("")
 	return func(w http.ResponseWriter, r *http.Request) {
-		raw, err := io.ReadAll(r.Body)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+		if len(verb) > 0 && r.Method != verb {
+			network.WriteHttpResponse(w, codecs.Default, method.FuncName, nil, errors.MethodNotAllowed("method %s not allowed", r.Method))
 			return
 		}
 
-		req := method.NewRequest()
-
-		if err = (&marshal.JSONPb{}).Unmarshal(raw, req); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+		req, out, err := network.ReadHttpRequest(codecs, r, method, s.HttpMaxBodySize)
+		if err != nil {
+			network.WriteHttpResponse(w, out, method.FuncName, nil, err)
 			return
 		}
 
-		contenttype := r.Header.Get("Content-Type")
-		w.Header().Set("Content-Type", contenttype)
+		ctx := &HttpCallContext{
+			Context: r.Context(),
+			w:       w,
+			r:       r,
+			core:    s,
+			codec:   out,
+			name:    method.FuncName,
+		}
 
-		var ctx Context = &HttpCallContext{
-			w:    w,
-			r:    r,
-			core: s,
+		var caller calltable.Caller
+		if token := auth.BearerToken(r); len(token) > 0 && s.TokenVerifier != nil {
+			user, err := s.verifyToken([]byte(token))
+			if err != nil {
+				ctx.writeResponse(nil, errors.Wrap(err, errors.CodeUnauthenticated, "invalid token"))
+				return
+			}
+			ctx.caller, caller = user, user
+			ctx.Context = withCaller(ctx.Context, user)
 		}
 
-		// here call method
-		method.Call(ctx, req)
+		resp, err := s.dispatcher.Invoke(method, caller, ctx, req)
+		ctx.writeResponse(resp, err)
 	}
 }
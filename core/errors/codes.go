@@ -0,0 +1,161 @@
+package errors
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// The well-known codes, they are the http statuses of themselves.
+const (
+	CodeInvalidArgument   int32 = http.StatusBadRequest
+	CodeUnauthenticated   int32 = http.StatusUnauthorized
+	CodePermissionDenied  int32 = http.StatusForbidden
+	CodeNotFound          int32 = http.StatusNotFound
+	CodeMethodNotAllowed  int32 = http.StatusMethodNotAllowed
+	CodeNotAcceptable     int32 = http.StatusNotAcceptable
+	CodeAlreadyExists     int32 = http.StatusConflict
+	CodeResourceExhausted int32 = http.StatusTooManyRequests
+	CodeRequestTooLarge   int32 = http.StatusRequestEntityTooLarge
+	CodeUnsupportedMedia  int32 = http.StatusUnsupportedMediaType
+	CodeCanceled          int32 = 499 // client closed request
+	CodeInternal          int32 = http.StatusInternalServerError
+	CodeUnimplemented     int32 = http.StatusNotImplemented
+	CodeUnavailable       int32 = http.StatusServiceUnavailable
+	CodeDeadlineExceeded  int32 = http.StatusGatewayTimeout
+)
+
+// CodeInfo is how a code is shown to the client.
+type CodeInfo struct {
+	Code int32
+	// Status is the http status of the code.
+	Status int
+	// Message is the client-facing message of the code.
+	Message string
+}
+
+var codes = struct {
+	sync.RWMutex
+	m map[int32]CodeInfo
+}{m: map[int32]CodeInfo{}}
+
+func init() {
+	Register(CodeInvalidArgument, http.StatusBadRequest, "invalid argument")
+	Register(CodeUnauthenticated, http.StatusUnauthorized, "unauthenticated")
+	Register(CodePermissionDenied, http.StatusForbidden, "permission denied")
+	Register(CodeNotFound, http.StatusNotFound, "not found")
+	Register(CodeMethodNotAllowed, http.StatusMethodNotAllowed, "method not allowed")
+	Register(CodeNotAcceptable, http.StatusNotAcceptable, "not acceptable")
+	Register(CodeAlreadyExists, http.StatusConflict, "already exists")
+	Register(CodeResourceExhausted, http.StatusTooManyRequests, "too many requests")
+	Register(CodeRequestTooLarge, http.StatusRequestEntityTooLarge, "request too large")
+	Register(CodeUnsupportedMedia, http.StatusUnsupportedMediaType, "unsupported media type")
+	Register(CodeCanceled, int(CodeCanceled), "canceled")
+	Register(CodeInternal, http.StatusInternalServerError, "internal error")
+	Register(CodeUnimplemented, http.StatusNotImplemented, "unimplemented")
+	Register(CodeUnavailable, http.StatusServiceUnavailable, "service unavailable")
+	Register(CodeDeadlineExceeded, http.StatusGatewayTimeout, "deadline exceeded")
+}
+
+// Register maps the code to the http status and the client-facing message,
+// services register their own codes in init, a registered code is overwritten.
+func Register(code int32, status int, message string) {
+	codes.Lock()
+	defer codes.Unlock()
+	codes.m[code] = CodeInfo{Code: code, Status: status, Message: message}
+}
+
+// Lookup returns the registered info of the code.
+func Lookup(code int32) (CodeInfo, bool) {
+	codes.RLock()
+	defer codes.RUnlock()
+	info, ok := codes.m[code]
+	return info, ok
+}
+
+// StatusOf returns the http status of the code,
+// an unregistered code is a http status itself if it looks like one, or a bad request.
+func StatusOf(code int32) int {
+	if info, ok := Lookup(code); ok {
+		return info.Status
+	}
+	if code >= 400 && code < 600 {
+		return int(code)
+	}
+	return http.StatusBadRequest
+}
+
+// MessageOf returns the client-facing message of the code.
+func MessageOf(code int32) string {
+	if info, ok := Lookup(code); ok {
+		return info.Message
+	}
+	if text := http.StatusText(StatusOf(code)); text != "" {
+		return text
+	}
+	return "error"
+}
+
+func newf(code int32, format string, args ...interface{}) error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+func InvalidArgument(format string, args ...interface{}) error {
+	return newf(CodeInvalidArgument, format, args...)
+}
+
+func Unauthenticated(format string, args ...interface{}) error {
+	return newf(CodeUnauthenticated, format, args...)
+}
+
+func PermissionDenied(format string, args ...interface{}) error {
+	return newf(CodePermissionDenied, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return newf(CodeNotFound, format, args...)
+}
+
+func MethodNotAllowed(format string, args ...interface{}) error {
+	return newf(CodeMethodNotAllowed, format, args...)
+}
+
+func NotAcceptable(format string, args ...interface{}) error {
+	return newf(CodeNotAcceptable, format, args...)
+}
+
+func AlreadyExists(format string, args ...interface{}) error {
+	return newf(CodeAlreadyExists, format, args...)
+}
+
+func ResourceExhausted(format string, args ...interface{}) error {
+	return newf(CodeResourceExhausted, format, args...)
+}
+
+func RequestTooLarge(format string, args ...interface{}) error {
+	return newf(CodeRequestTooLarge, format, args...)
+}
+
+func UnsupportedMedia(format string, args ...interface{}) error {
+	return newf(CodeUnsupportedMedia, format, args...)
+}
+
+func Canceled(format string, args ...interface{}) error {
+	return newf(CodeCanceled, format, args...)
+}
+
+func Internal(format string, args ...interface{}) error {
+	return newf(CodeInternal, format, args...)
+}
+
+func Unimplemented(format string, args ...interface{}) error {
+	return newf(CodeUnimplemented, format, args...)
+}
+
+func Unavailable(format string, args ...interface{}) error {
+	return newf(CodeUnavailable, format, args...)
+}
+
+func DeadlineExceeded(format string, args ...interface{}) error {
+	return newf(CodeDeadlineExceeded, format, args...)
+}
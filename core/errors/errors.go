@@ -5,6 +5,12 @@ package errors
 import (
 	"encoding/json"
 	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/log"
 )
 
 func (e *Error) Error() string {
@@ -31,10 +37,16 @@ func Parse(err string) *Error {
 	return e
 }
 
+// Is reports an *Error of the same code and detail, so a wrapped error is still its sentinel.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t != nil && t.Code == e.Code && t.Detail == e.Detail
+}
+
 // Equal tries to compare errors
 func Equal(err1 error, err2 error) bool {
-	verr1, ok1 := err1.(*Error)
-	verr2, ok2 := err2.(*Error)
+	verr1, ok1 := As(err1)
+	verr2, ok2 := As(err2)
 
 	if ok1 != ok2 {
 		return false
@@ -56,7 +68,7 @@ func FromError(err error) *Error {
 	if err == nil {
 		return nil
 	}
-	if verr, ok := err.(*Error); ok && verr != nil {
+	if verr, ok := As(err); ok && verr != nil {
 		return verr
 	}
 
@@ -93,3 +105,112 @@ func (e *MultiError) Error() string {
 	b, _ := json.Marshal(e)
 	return string(b)
 }
+
+// HTTPStatus maps the error to a http status.
+// an *Error has the status of its code by StatusOf,
+// a MultiError is a bad request, and any other error is an internal error.
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var merr *MultiError
+	if errors.As(err, &merr) {
+		return http.StatusBadRequest
+	}
+	if e, ok := As(err); ok {
+		return StatusOf(e.Code)
+	}
+	return http.StatusInternalServerError
+}
+
+// wrapped is an *Error which keeps the error it's caused by.
+type wrapped struct {
+	err   *Error
+	cause error
+}
+
+func (w *wrapped) Error() string {
+	return w.err.Error() + ": " + w.cause.Error()
+}
+
+func (w *wrapped) Unwrap() []error {
+	return []error{w.err, w.cause}
+}
+
+// Wrap returns an *Error of the code, which keeps err as its cause.
+// the cause is never sent to the client, but errors.Is and errors.As still find it.
+func Wrap(err error, code int32, detail string) error {
+	if err == nil {
+		return nil
+	}
+	return &wrapped{err: &Error{Code: code, Detail: detail}, cause: err}
+}
+
+// WithMeta attaches the key value pairs to the *Error of err,
+// err is wrapped as an internal error if it is not an *Error, its text is kept only in the cause.
+func WithMeta(err error, kv ...string) error {
+	if err == nil {
+		return nil
+	}
+	e, ok := As(err)
+	if ok {
+		e = proto.Clone(e).(*Error)
+	} else {
+		e = internalError()
+	}
+	if e.Metadata == nil {
+		e.Metadata = make(map[string]string, len(kv)/2)
+	}
+	for i := 0; i+1 < len(kv); i += 2 {
+		e.Metadata[kv[i]] = kv[i+1]
+	}
+	return &wrapped{err: e, cause: err}
+}
+
+// Cause returns the error which err is wrapped from, or err itself.
+func Cause(err error) error {
+	var w *wrapped
+	for errors.As(err, &w) {
+		err = w.cause
+	}
+	return err
+}
+
+// internalError is the client-facing error of a go error, whose text may leak the internals.
+func internalError() *Error {
+	return &Error{Code: CodeInternal, Detail: MessageOf(CodeInternal)}
+}
+
+// Envelope returns the client-facing error of err, with the invalid fields of a MultiError.
+// the code of a go error is CodeInternal with a generic detail, the text of the go error is logged instead,
+// and the message is filled by MessageOf.
+func Envelope(err error) (*Error, []*Error) {
+	if err == nil {
+		return nil, nil
+	}
+	var merr *MultiError
+	if errors.As(err, &merr) {
+		details := make([]string, 0, len(merr.Errors))
+		for _, e := range merr.Errors {
+			details = append(details, e.Detail)
+		}
+		return &Error{
+			Code:    CodeInvalidArgument,
+			Message: MessageOf(CodeInvalidArgument),
+			Detail:  strings.Join(details, "; "),
+		}, merr.Errors
+	}
+	e, ok := As(err)
+	if ok {
+		e = proto.Clone(e).(*Error)
+	} else {
+		e = internalError()
+	}
+	if _, known := Cause(err).(*Error); !known && e.Code == CodeInternal {
+		log.Errorf("internal error: %v", err)
+	}
+	if e.Message == "" {
+		e.Message = MessageOf(e.Code)
+	}
+	return e, nil
+}
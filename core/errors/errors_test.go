@@ -0,0 +1,59 @@
+package errors
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	err := WithMeta(Wrap(io.EOF, CodeUnavailable, "db closed"), "db", "users")
+
+	if !errors.Is(err, io.EOF) || Cause(err) != io.EOF {
+		t.Fatal("the cause is lost")
+	}
+	if HTTPStatus(err) != http.StatusServiceUnavailable {
+		t.Fatalf("status: %d", HTTPStatus(err))
+	}
+
+	e, fields := Envelope(err)
+	if len(fields) != 0 || e.Code != CodeUnavailable || e.Detail != "db closed" || e.Message != "service unavailable" {
+		t.Fatalf("unexpected envelope: %v", e)
+	}
+	if e.Metadata["db"] != "users" {
+		t.Fatalf("unexpected metadata: %v", e.Metadata)
+	}
+
+	sentinel := New(CodeNotFound, "user not found")
+	if !errors.Is(WithMeta(sentinel, "uid", "1"), sentinel) {
+		t.Fatal("the sentinel is lost")
+	}
+	if sentinel.(*Error).Metadata != nil {
+		t.Fatal("the sentinel is modified")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(1001, http.StatusConflict, "name is taken")
+	if HTTPStatus(New(1001, "uname: x")) != http.StatusConflict || MessageOf(1001) != "name is taken" {
+		t.Fatal("the registered code is not mapped")
+	}
+	if HTTPStatus(New(1002, "")) != http.StatusBadRequest {
+		t.Fatal("an unknown code is a bad request")
+	}
+
+	e, _ := Envelope(io.EOF)
+	if e.Code != CodeInternal || HTTPStatus(io.EOF) != http.StatusInternalServerError {
+		t.Fatalf("unexpected envelope: %v", e)
+	}
+	if e, _ := Envelope(WithMeta(io.EOF, "db", "users")); e.Detail != "internal error" {
+		t.Fatalf("the go error is sent to the client: %v", e)
+	}
+
+	merr := NewMultiError()
+	merr.Append(&Error{Code: 400, Detail: "uname is required", Field: "uname"})
+	if e, fields := Envelope(merr); e.Code != CodeInvalidArgument || len(fields) != 1 {
+		t.Fatalf("unexpected envelope: %v, %v", e, fields)
+	}
+}
@@ -0,0 +1,354 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+
+	log "github.com/ajenpan/surf/core/log"
+)
+
+// StreamContentType is the content type of the event stream, the events are length-delimited protobuf.
+const StreamContentType = "application/x-protobuf-stream"
+
+// DefaultHeartbeat is how often the hub writes an event without topic, so the subscribers find a dead stream,
+// its timestamp is the time of the hub.
+const DefaultHeartbeat = 30 * time.Second
+
+// RecverFunc adapts a function to Recver.
+type RecverFunc func(e *Event)
+
+func (f RecverFunc) OnEvent(e *Event) { f(e) }
+
+type HubOptions struct {
+	// Retain is how long the events are kept for the subscribers which reconnect, none is kept if zero.
+	Retain time.Duration
+	// Heartbeat is DefaultHeartbeat if zero.
+	Heartbeat time.Duration
+	// Buffer is the events queued to a subscriber, a subscriber falling behind it is disconnected
+	// and gets the retained events when it reconnects. 256 if zero.
+	Buffer int
+	// MaxSubscribers refuses the subscribers more than it by 503, 64 if zero.
+	MaxSubscribers int
+}
+
+// Hub is a Publisher which streams the events to the subscribers over http, see Subscriber.
+// The stream is served by ServeHTTP, "topic" selects the topics, all if none,
+// and "since" replays the retained events of the timestamp or later.
+// The stream is not authenticated, serve it on a listener which only the servers can reach.
+type Hub struct {
+	opts HubOptions
+
+	mu       sync.Mutex
+	subs     map[*hubSub]struct{}
+	retained []*Event
+}
+
+type hubSub struct {
+	topics map[string]bool
+	ch     chan *Event
+	// full is closed when the subscriber falls behind.
+	full     chan struct{}
+	fullOnce sync.Once
+}
+
+func (s *hubSub) wants(e *Event) bool {
+	return len(s.topics) == 0 || s.topics[e.Topic]
+}
+
+func NewHub(opts HubOptions) *Hub {
+	if opts.Heartbeat == 0 {
+		opts.Heartbeat = DefaultHeartbeat
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = 256
+	}
+	if opts.MaxSubscribers == 0 {
+		opts.MaxSubscribers = 64
+	}
+	return &Hub{opts: opts, subs: make(map[*hubSub]struct{})}
+}
+
+// Publish sends the event to the subscribers, the timestamp is set if it is zero.
+func (h *Hub) Publish(e *Event) {
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().Unix()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.opts.Retain > 0 {
+		h.retained = append(h.retained, e)
+		h.prune(time.Now().Add(-h.opts.Retain).Unix())
+	}
+	for s := range h.subs {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.fullOnce.Do(func() { close(s.full) })
+		}
+	}
+}
+
+func (h *Hub) prune(before int64) {
+	i := 0
+	for i < len(h.retained) && h.retained[i].Timestamp < before {
+		i++
+	}
+	h.retained = h.retained[i:]
+}
+
+// subscribe registers the subscriber with the retained events since the timestamp queued,
+// so no event is lost between the replay and the live ones. It is nil if the subscribers are full.
+func (h *Hub) subscribe(topics []string, since int64) *hubSub {
+	s := &hubSub{topics: make(map[string]bool, len(topics)), full: make(chan struct{})}
+	for _, t := range topics {
+		s.topics[t] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.subs) >= h.opts.MaxSubscribers {
+		return nil
+	}
+	var replay []*Event
+	if since > 0 {
+		for _, e := range h.retained {
+			if e.Timestamp >= since && s.wants(e) {
+				replay = append(replay, e)
+			}
+		}
+	}
+	s.ch = make(chan *Event, h.opts.Buffer+len(replay))
+	for _, e := range replay {
+		s.ch <- e
+	}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+func (h *Hub) unsubscribe(s *hubSub) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, s)
+}
+
+// Subscribers is the number of the connected subscribers.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	q := r.URL.Query()
+	since, _ := strconv.ParseInt(q.Get("since"), 10, 64)
+	s := h.subscribe(q["topic"], since)
+	if s == nil {
+		http.Error(w, "too many subscribers", http.StatusServiceUnavailable)
+		return
+	}
+	defer h.unsubscribe(s)
+
+	w.Header().Set("Content-Type", StreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	heartbeat := time.NewTicker(h.opts.Heartbeat)
+	defer heartbeat.Stop()
+	// the first heartbeat tells the subscriber where to replay from if it reconnects before any event.
+	e := &Event{Timestamp: time.Now().Unix()}
+	for {
+		if _, err := protodelim.MarshalTo(w, e); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.full:
+			log.Warnf("event subscriber %s falls behind, disconnected", r.RemoteAddr)
+			return
+		case <-heartbeat.C:
+			e = &Event{Timestamp: time.Now().Unix()}
+		case e = <-s.ch:
+		}
+	}
+}
+
+type SubscriberOptions struct {
+	// URL is the stream of a Hub, like http://uauth:9998/events.
+	URL    string
+	Topics []string
+	// Client has no timeout, the stream is long-lived. http.DefaultClient if nil.
+	Client *http.Client
+	// Heartbeat is the one of the hub, the stream is reconnected if nothing comes in twice of it.
+	// DefaultHeartbeat if zero.
+	Heartbeat time.Duration
+	// RetryInterval is the wait before reconnecting, 1s if zero, it doubles up to 30s while the hub is down.
+	RetryInterval time.Duration
+}
+
+// Subscriber receives the events of a Hub, and keeps reconnecting until it is stopped.
+// The first connect replays all the events the hub retains, like the revocations made while the subscriber was down,
+// and a reconnect replays the ones since the last event or heartbeat received,
+// so an event may be delivered again, the receivers must be idempotent.
+type Subscriber struct {
+	opts   SubscriberOptions
+	recver Recver
+
+	// since is the timestamp of the last event or heartbeat received, 1 replays all the retained ones.
+	since int64
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	chStop   chan struct{}
+	chDone   chan struct{}
+}
+
+func NewSubscriber(opts SubscriberOptions, recver Recver) *Subscriber {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Heartbeat == 0 {
+		opts.Heartbeat = DefaultHeartbeat
+	}
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = time.Second
+	}
+	return &Subscriber{
+		opts:   opts,
+		recver: recver,
+		since:  1,
+		chStop: make(chan struct{}),
+		chDone: make(chan struct{}),
+	}
+}
+
+// Start receives the events in the background.
+func (s *Subscriber) Start() {
+	if !s.started.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		defer close(s.chDone)
+		retry := s.opts.RetryInterval
+		for {
+			err := s.stream()
+			select {
+			case <-s.chStop:
+				return
+			default:
+			}
+			if err == nil {
+				retry = s.opts.RetryInterval
+			} else {
+				log.Warnf("subscribe events of %s failed: %v", s.opts.URL, err)
+			}
+			select {
+			case <-s.chStop:
+				return
+			case <-time.After(retry):
+			}
+			if retry *= 2; retry > 30*time.Second {
+				retry = 30 * time.Second
+			}
+		}
+	}()
+}
+
+// Stop closes the stream and waits for the receiving to end.
+func (s *Subscriber) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.chStop)
+	})
+	if s.started.Load() {
+		<-s.chDone
+	}
+}
+
+// stream receives the events until the stream ends, it returns nil if any event is received.
+func (s *Subscriber) stream() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.chStop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	u, err := url.Parse(s.opts.URL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	for _, t := range s.opts.Topics {
+		q.Add("topic", t)
+	}
+	if s.since > 0 {
+		q.Set("since", strconv.FormatInt(s.since, 10))
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("subscribe: %s", resp.Status)
+	}
+
+	// the stream is dead if neither an event nor a heartbeat comes in time.
+	watchdog := time.AfterFunc(2*s.opts.Heartbeat, cancel)
+	defer watchdog.Stop()
+
+	r := bufio.NewReader(resp.Body)
+	received := false
+	for {
+		e := &Event{}
+		if err := protodelim.UnmarshalFrom(r, e); err != nil {
+			if received && (errors.Is(err, io.EOF) || ctx.Err() != nil) {
+				return nil
+			}
+			return err
+		}
+		watchdog.Reset(2 * s.opts.Heartbeat)
+		received = true
+		if e.Timestamp > s.since {
+			s.since = e.Timestamp
+		}
+		if e.Topic != "" {
+			s.recver.OnEvent(e)
+		}
+	}
+}
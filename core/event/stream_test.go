@@ -0,0 +1,112 @@
+package event
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// collect receives the events into a channel.
+type collect chan *Event
+
+func (c collect) OnEvent(e *Event) { c <- e }
+
+func (c collect) wait(t *testing.T, topic string) *Event {
+	t.Helper()
+	select {
+	case e := <-c:
+		if e.Topic != topic {
+			t.Fatalf("expected %s, got %s", topic, e.Topic)
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event of", topic)
+	}
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	hub := NewHub(HubOptions{Retain: time.Minute})
+	svr := httptest.NewServer(hub)
+	defer svr.Close()
+
+	recv := make(collect, 16)
+	sub := NewSubscriber(SubscriberOptions{URL: svr.URL, Topics: []string{"a"}, RetryInterval: 10 * time.Millisecond}, recv)
+	sub.Start()
+	defer sub.Stop()
+
+	subscribed := func() bool { return hub.Subscribers() == 1 }
+	for i := 0; !subscribed(); i++ {
+		if i > 500 {
+			t.Fatal("the subscriber is not connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hub.Publish(&Event{Topic: "b"})
+	hub.Publish(&Event{Topic: "a", Data: []byte("1")})
+	if e := recv.wait(t, "a"); string(e.Data) != "1" {
+		t.Fatal("wrong data:", string(e.Data))
+	}
+
+	// the events published while the stream is down are replayed after the reconnect.
+	svr.CloseClientConnections()
+	for i := 0; subscribed(); i++ {
+		if i > 500 {
+			t.Fatal("the subscriber is not disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	hub.Publish(&Event{Topic: "a", Data: []byte("2")})
+	for {
+		// "1" may be delivered again, it is of the same second.
+		if e := recv.wait(t, "a"); string(e.Data) == "2" {
+			break
+		}
+	}
+}
+
+func TestSubscriberStop(t *testing.T) {
+	// a subscriber which is never started stops at once.
+	NewSubscriber(SubscriberOptions{URL: "http://127.0.0.1:1"}, RecverFunc(func(*Event) {})).Stop()
+
+	sub := NewSubscriber(SubscriberOptions{URL: "http://127.0.0.1:1", RetryInterval: time.Hour}, RecverFunc(func(*Event) {}))
+	sub.Start()
+	done := make(chan struct{})
+	go func() {
+		sub.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subscriber is not stopped")
+	}
+}
+
+func TestStreamReplayRetained(t *testing.T) {
+	hub := NewHub(HubOptions{Retain: time.Minute, MaxSubscribers: 1})
+	svr := httptest.NewServer(hub)
+	defer svr.Close()
+
+	// published while no subscriber is up, like a revocation while a server restarts.
+	hub.Publish(&Event{Topic: "a", Data: []byte("1")})
+
+	recv := make(collect, 16)
+	sub := NewSubscriber(SubscriberOptions{URL: svr.URL, Topics: []string{"a"}, RetryInterval: 10 * time.Millisecond}, recv)
+	sub.Start()
+	defer sub.Stop()
+	if e := recv.wait(t, "a"); string(e.Data) != "1" {
+		t.Fatal("wrong data:", string(e.Data))
+	}
+
+	resp, err := http.Get(svr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("the subscriber over the max is not refused: %s", resp.Status)
+	}
+}
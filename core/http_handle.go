@@ -1,34 +1,33 @@
 package core
 
 import (
-	"encoding/json"
+	gocontext "context"
 	"fmt"
 	"net/http"
 
 	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	"github.com/ajenpan/surf/core/utils/marshal"
 )
 
 type HttpCallContext struct {
-	w    http.ResponseWriter
-	r    *http.Request
-	core *Surf
+	gocontext.Context
+	callResult
+	w      http.ResponseWriter
+	r      *http.Request
+	core   *Surf
+	caller auth.User
+
+	codec marshal.Marshaler
+	name  string
 }
 
-func (ctx *HttpCallContext) Response(msg interface{}, err error) {
-	type httpWrap struct {
-		Error error       `json:"err"`
-		Data  interface{} `json:"data"`
-	}
-
-	enc := json.NewEncoder(ctx.w)
-	encerr := enc.Encode(&httpWrap{Data: msg, Error: err})
-
-	if encerr != nil {
-		ctx.w.WriteHeader(http.StatusInternalServerError)
-		ctx.w.Write([]byte(encerr.Error()))
-	} else {
-		ctx.w.WriteHeader(http.StatusOK)
+func (ctx *HttpCallContext) Value(key interface{}) interface{} {
+	if key == (callContextKey{}) {
+		return ctx
 	}
+	return ctx.Context.Value(key)
 }
 
 func (ctx *HttpCallContext) SendAsync(msg interface{}) error {
@@ -36,5 +35,24 @@ func (ctx *HttpCallContext) SendAsync(msg interface{}) error {
 }
 
 func (ctx *HttpCallContext) Caller() auth.User {
+	return ctx.caller
+}
+
+// RemoteAddr is the peer of the http conn, the forwarded headers are not trusted.
+func (ctx *HttpCallContext) RemoteAddr() string {
+	return ctx.r.RemoteAddr
+}
+
+// Request is the http request of the call, like for its headers.
+func (ctx *HttpCallContext) Request() *http.Request {
+	return ctx.r
+}
+
+// Stream is nil, the server streams are not served over http.
+func (ctx *HttpCallContext) Stream() calltable.Stream {
 	return nil
 }
+
+func (ctx *HttpCallContext) writeResponse(msg interface{}, err error) {
+	network.WriteHttpResponse(ctx.w, ctx.codec, ctx.name, msg, err)
+}
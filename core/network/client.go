@@ -0,0 +1,210 @@
+package network
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ws "github.com/gorilla/websocket"
+)
+
+type ClientOptions struct {
+	// HeatbeatInterval is how often the client sends a heartbeat,
+	// it must be less than the timeout of the server.
+	HeatbeatInterval time.Duration
+
+	AppVersion string
+	Features   HandShakeFeature
+
+	// Token answers the auth cmd of the servers which authenticate the conns by OnConnAuth.
+	Token []byte
+
+	OnPacket func(*ClientConn, *HVPacket)
+	OnClose  func(*ClientConn)
+}
+
+// packetConn is the client side of a transport, TcpConn and WSConn read and write the packets.
+type packetConn interface {
+	readPacket() (*HVPacket, error)
+	writePacket(*HVPacket) error
+	setReadDeadline(time.Time)
+	setWriteDeadline(time.Time)
+}
+
+// ClientConn is the client side of a HVPacket connection to TcpServer or WSServer.
+type ClientConn struct {
+	opts   ClientOptions
+	conn   packetConn
+	closer io.Closer
+	result *HandShakeResult
+
+	chWrite   chan *HVPacket
+	chClosed  chan struct{}
+	closeOnce sync.Once
+
+	lastRecvAt int64
+}
+
+func DialTcp(addr string, opts ClientOptions) (*ClientConn, error) {
+	c, err := net.DialTimeout("tcp", addr, time.Duration(DefaultTimeoutSec)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return newClientConn(&TcpConn{conn: c}, c, opts)
+}
+
+func DialWS(url string, opts ClientOptions) (*ClientConn, error) {
+	c, _, err := ws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return newClientConn(&WSConn{imp: c}, c, opts)
+}
+
+func newClientConn(conn packetConn, closer io.Closer, opts ClientOptions) (*ClientConn, error) {
+	if opts.HeatbeatInterval <= 0 {
+		opts.HeatbeatInterval = time.Duration(DefaultMinTimeoutSec) * time.Second / 2
+	}
+	c := &ClientConn{
+		opts:     opts,
+		conn:     conn,
+		closer:   closer,
+		chWrite:  make(chan *HVPacket, 10),
+		chClosed: make(chan struct{}),
+	}
+	if err := c.handshake(); err != nil {
+		closer.Close()
+		return nil, err
+	}
+	atomic.StoreInt64(&c.lastRecvAt, time.Now().Unix())
+	go c.writeWork()
+	go c.readWork()
+	return c, nil
+}
+
+func (c *ClientConn) handshake() error {
+	deadline := time.Now().Add(time.Duration(DefaultTimeoutSec) * time.Second)
+	c.conn.setReadDeadline(deadline)
+	c.conn.setWriteDeadline(deadline)
+	defer c.conn.setReadDeadline(time.Time{})
+
+	req := &HandShakeRequest{
+		Version:    HVProtocolVersion,
+		Features:   c.opts.Features,
+		AppVersion: c.opts.AppVersion,
+	}
+	pk := NewHVPacket()
+	pk.SetFlag(HVPacketFlagHandShake)
+	pk.SetBody(req.Marshal())
+	if err := c.conn.writePacket(pk); err != nil {
+		return err
+	}
+
+	pk, err := c.conn.readPacket()
+	if err != nil {
+		return err
+	}
+	if pk.GetFlag() == HVPacketFlagCmd {
+		reply := NewHVPacket()
+		reply.SetFlag(HVPacketFlagCmdResult)
+		reply.SetSubFlag(pk.GetSubFlag())
+		reply.SetBody(c.opts.Token)
+		if err := c.conn.writePacket(reply); err != nil {
+			return err
+		}
+		if pk, err = c.conn.readPacket(); err != nil {
+			return err
+		}
+	}
+	if pk.GetFlag() != HVPacketFlagHandShakeResult {
+		return ErrInvalidPacket
+	}
+
+	result := &HandShakeResult{}
+	if err := result.Unmarshal(pk.GetBody()); err != nil {
+		return err
+	}
+	if !result.Accepted() {
+		return result
+	}
+	c.result = result
+	return nil
+}
+
+// ConnID is the id given by the server.
+func (c *ClientConn) ConnID() string {
+	return c.result.ConnID
+}
+
+func (c *ClientConn) HasFeature(f HandShakeFeature) bool {
+	return c.result.Features&f == f
+}
+
+func (c *ClientConn) Send(p *HVPacket) error {
+	select {
+	case <-c.chClosed:
+		return ErrDisconn
+	case c.chWrite <- p:
+		return nil
+	}
+}
+
+func (c *ClientConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.chClosed)
+		c.closer.Close()
+		if c.opts.OnClose != nil {
+			c.opts.OnClose(c)
+		}
+	})
+	return nil
+}
+
+// Done is closed after the conn is closed.
+func (c *ClientConn) Done() <-chan struct{} {
+	return c.chClosed
+}
+
+func (c *ClientConn) writeWork() {
+	defer c.Close()
+
+	ticker := time.NewTicker(c.opts.HeatbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		var p *HVPacket
+		select {
+		case <-c.chClosed:
+			return
+		case p = <-c.chWrite:
+		case <-ticker.C:
+			if time.Since(time.Unix(atomic.LoadInt64(&c.lastRecvAt), 0)) > 3*c.opts.HeatbeatInterval {
+				return
+			}
+			p = NewHVPacket()
+			p.SetFlag(HVPacketFlagHeartbeat)
+		}
+		c.conn.setWriteDeadline(time.Now().Add(time.Duration(DefaultTimeoutSec) * time.Second))
+		if err := c.conn.writePacket(p); err != nil {
+			return
+		}
+	}
+}
+
+func (c *ClientConn) readWork() {
+	defer c.Close()
+
+	for {
+		pk, err := c.conn.readPacket()
+		if err != nil {
+			return
+		}
+		atomic.StoreInt64(&c.lastRecvAt, time.Now().Unix())
+
+		if pk.GetFlag() == HVPacketFlagPacket && c.opts.OnPacket != nil {
+			c.opts.OnPacket(c, pk)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package network
 
 import (
 	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/ajenpan/surf/core/auth"
 )
@@ -20,6 +23,16 @@ const (
 	Connected    ConnStatus = iota
 )
 
+type FuncOnConnPacket func(Conn, *HVPacket)
+type FuncOnConnEnable func(Conn, bool)
+type FuncOnConnAuth func(data []byte) (auth.User, error)
+
+var sid uint64 = 0
+
+func GenConnID() string {
+	return fmt.Sprintf("%d_%d", atomic.AddUint64(&sid, 1), time.Now().Unix())
+}
+
 type Conn interface {
 	auth.User
 
@@ -27,4 +40,110 @@ type Conn interface {
 	Send(*HVPacket) error
 	Close() error
 	Enable() bool
+
+	ProtocolVersion() uint16
+	HasFeature(HandShakeFeature) bool
+	ClientAppVersion() string
+}
+
+// connBase is the transport independent part of a server side connection.
+type connBase struct {
+	auth.User
+	handShakeInfo
+
+	id string
+
+	chWrite  chan *HVPacket
+	chRead   chan *HVPacket
+	chClosed chan struct{}
+
+	timeOut time.Duration
+
+	lastSendAt int64
+	lastRecvAt int64
+
+	status ConnStatus
+
+	writeSize int64
+	readSize  int64
+}
+
+func newConnBase(timeOut time.Duration) connBase {
+	return connBase{
+		id:       GenConnID(),
+		timeOut:  timeOut,
+		status:   Connectting,
+		chClosed: make(chan struct{}),
+		chWrite:  make(chan *HVPacket, 10),
+		chRead:   make(chan *HVPacket, 10),
+	}
+}
+
+func (c *connBase) base() *connBase {
+	return c
+}
+
+func (c *connBase) ConnID() string {
+	return c.id
+}
+
+func (c *connBase) Send(p *HVPacket) error {
+	if !c.Enable() {
+		return ErrDisconn
+	}
+	select {
+	case <-c.chClosed:
+		return ErrDisconn
+	case c.chWrite <- p:
+		return nil
+	}
+}
+
+// Close only closes chClosed, the read and write works select on it,
+// so that they never send to a closed channel.
+func (c *connBase) Close() error {
+	old := atomic.SwapInt32((*int32)(&c.status), int32(Disconnected))
+	if old != Connected {
+		return nil
+	}
+
+	select {
+	case <-c.chClosed:
+		return nil
+	default:
+		close(c.chClosed)
+		return nil
+	}
+}
+
+func (c *connBase) Enable() bool {
+	return c.Status() == Connected
+}
+
+func (c *connBase) Status() ConnStatus {
+	return ConnStatus(atomic.LoadInt32((*int32)(&c.status)))
+}
+
+func (c *connBase) setStatus(s ConnStatus) {
+	atomic.StoreInt32((*int32)(&c.status), int32(s))
+}
+
+// serverConn is implemented by the transports(tcp, websocket) to plug into connServer.
+type serverConn interface {
+	Conn
+
+	base() *connBase
+	readPacket() (*HVPacket, error)
+	writePacket(*HVPacket) error
+	setReadDeadline(time.Time)
+	setWriteDeadline(time.Time)
+}
+
+// ConnUser returns the auth.User returned by OnConnAuth for the connection,
+// which allows the caller to get back its own implementation of auth.User.
+func ConnUser(c Conn) auth.User {
+	if sc, ok := c.(serverConn); ok {
+		return sc.base().User
+	}
+	return nil
 }
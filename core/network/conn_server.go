@@ -0,0 +1,204 @@
+package network
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// connServerOptions is the part of the server options shared by all transports.
+type connServerOptions struct {
+	HeatbeatInterval time.Duration
+	OnConnPacket     FuncOnConnPacket
+	OnConnEnable     FuncOnConnEnable
+	OnConnAuth       FuncOnConnAuth
+	HandShakePolicy  *HandShakePolicy
+}
+
+// connServer is the shared connection handling core of TcpServer and WSServer:
+// the handshake, the packet dispatch loop, the connection table and the metrics.
+type connServer struct {
+	opts    connServerOptions
+	die     chan bool
+	conns   *ConnTable
+	metrics Metrics
+}
+
+func newConnServer(opts connServerOptions) connServer {
+	if opts.HeatbeatInterval < time.Duration(DefaultMinTimeoutSec)*time.Second {
+		opts.HeatbeatInterval = time.Duration(DefaultTimeoutSec) * time.Second
+	}
+	if opts.HandShakePolicy == nil {
+		opts.HandShakePolicy = DefaultHandShakePolicy
+	}
+	return connServer{
+		opts:  opts,
+		die:   make(chan bool),
+		conns: NewConnTable(),
+	}
+}
+
+func (s *connServer) Conns() *ConnTable {
+	return s.conns
+}
+
+func (s *connServer) Metrics() Metrics {
+	return s.metrics.Snapshot()
+}
+
+func (s *connServer) SocketCount() int {
+	return s.conns.Len()
+}
+
+// shutdown returns false if the server is already stopped.
+func (s *connServer) shutdown() bool {
+	select {
+	case <-s.die:
+		return false
+	default:
+		close(s.die)
+	}
+	return true
+}
+
+func (s *connServer) serve(conn serverConn) {
+	atomic.AddInt64(&s.metrics.Accepted, 1)
+
+	if err := s.handshake(conn); err != nil {
+		atomic.AddInt64(&s.metrics.Rejected, 1)
+		return
+	}
+
+	base := conn.base()
+	base.setStatus(Connected)
+
+	s.conns.Store(conn)
+	defer s.conns.RemoveIfSame(conn)
+
+	atomic.AddInt64(&s.metrics.Active, 1)
+	defer atomic.AddInt64(&s.metrics.Active, -1)
+
+	// the connection is established here
+	go func() {
+		defer conn.Close()
+		s.writeWork(conn)
+	}()
+
+	go func() {
+		defer conn.Close()
+		s.readWork(conn)
+	}()
+
+	if s.opts.OnConnEnable != nil {
+		s.opts.OnConnEnable(conn, true)
+		defer s.opts.OnConnEnable(conn, false)
+	}
+
+	for {
+		select {
+		case <-base.chClosed:
+			return
+		case <-s.die:
+			conn.Close()
+			return
+		case packet := <-base.chRead:
+			switch packet.GetFlag() {
+			case HVPacketFlagHeartbeat:
+				conn.Send(packet)
+			case HVPacketFlagPacket:
+				if s.opts.OnConnPacket != nil {
+					s.opts.OnConnPacket(conn, packet)
+				}
+			}
+		}
+	}
+}
+
+func (s *connServer) handshake(conn serverConn) error {
+	deadline := time.Now().Add(s.opts.HeatbeatInterval * 2)
+	conn.setReadDeadline(deadline)
+	conn.setWriteDeadline(deadline)
+
+	pk, err := conn.readPacket()
+	if err != nil {
+		return err
+	}
+
+	if pk.GetFlag() != HVPacketFlagHandShake {
+		return ErrInvalidPacket
+	}
+
+	hsreq := &HandShakeRequest{}
+	if err = hsreq.Unmarshal(pk.GetBody()); err != nil {
+		result := &HandShakeResult{Status: HandShakeRejectInvalidBody, Message: err.Error()}
+		conn.writePacket(newHandShakeResultPacket(hsreq, result))
+		return result
+	}
+
+	result := s.opts.HandShakePolicy.Negotiate(hsreq)
+	if !result.Accepted() {
+		conn.writePacket(newHandShakeResultPacket(hsreq, result))
+		return result
+	}
+
+	base := conn.base()
+	if s.opts.OnConnAuth != nil {
+		pk := NewHVPacket()
+		pk.SetFlag(HVPacketFlagCmd)
+		pk.SetSubFlag(1)
+		pk.SetBody([]byte("auth"))
+		if err = conn.writePacket(pk); err != nil {
+			return err
+		}
+		if pk, err = conn.readPacket(); err != nil {
+			return err
+		}
+		if base.User, err = s.opts.OnConnAuth(pk.GetBody()); err != nil {
+			result := &HandShakeResult{Status: HandShakeRejectAuth, Version: hsreq.Version, Message: err.Error()}
+			conn.writePacket(newHandShakeResultPacket(hsreq, result))
+			return err
+		}
+	}
+
+	base.handShakeInfo = newHandShakeInfo(hsreq, result)
+	result.ConnID = base.ConnID()
+	return conn.writePacket(newHandShakeResultPacket(hsreq, result))
+}
+
+func (s *connServer) writeWork(conn serverConn) error {
+	base := conn.base()
+	for {
+		select {
+		case <-base.chClosed:
+			return nil
+		case p := <-base.chWrite:
+			conn.setWriteDeadline(time.Now().Add(base.timeOut))
+			if err := conn.writePacket(p); err != nil {
+				return err
+			}
+			s.metrics.onPacketOut(p)
+			atomic.AddInt64(&base.writeSize, int64(hvPackMetaLen+len(p.GetBody())))
+			atomic.StoreInt64(&base.lastSendAt, time.Now().Unix())
+		}
+	}
+}
+
+func (s *connServer) readWork(conn serverConn) error {
+	base := conn.base()
+	for {
+		conn.setReadDeadline(time.Now().Add(base.timeOut))
+		pk, err := conn.readPacket()
+		if err != nil {
+			return err
+		}
+
+		s.metrics.onPacketIn(pk)
+		atomic.AddInt64(&base.readSize, int64(hvPackMetaLen+len(pk.GetBody())))
+		atomic.StoreInt64(&base.lastRecvAt, time.Now().Unix())
+
+		select {
+		case <-base.chClosed:
+			return nil
+		case base.chRead <- pk:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package network
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConnServerDispatch(t *testing.T) {
+	recv := make(chan *HVPacket, 1)
+	svr, err := NewTcpServer(TcpServerOptions{
+		ListenAddr: "127.0.0.1:0",
+		OnConnPacket: func(c Conn, pk *HVPacket) {
+			recv <- pk
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.Start()
+	defer svr.Stop()
+
+	c, err := net.Dial("tcp", svr.Address().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pk := NewHVPacket()
+	pk.SetFlag(HVPacketFlagHandShake)
+	pk.SetBody((&HandShakeRequest{Version: HVProtocolVersion}).Marshal())
+	pk.WriteTo(c)
+	if _, err := pk.ReadFrom(c); err != nil || pk.GetSubFlag() != HandShakeAccepted {
+		t.Fatal("handshake failed", err)
+	}
+
+	pk = NewHVPacket()
+	pk.SetFlag(HVPacketFlagHeartbeat)
+	pk.WriteTo(c)
+	if _, err := pk.ReadFrom(c); err != nil || pk.GetFlag() != HVPacketFlagHeartbeat {
+		t.Fatal("heartbeat failed", err)
+	}
+
+	pk = NewHVPacket()
+	pk.SetFlag(HVPacketFlagPacket)
+	pk.SetBody([]byte("hello"))
+	pk.WriteTo(c)
+
+	select {
+	case got := <-recv:
+		if string(got.GetBody()) != "hello" {
+			t.Fatalf("unexpected body: %s", got.GetBody())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("packet not dispatched")
+	}
+
+	if svr.SocketCount() != 1 {
+		t.Fatalf("expected 1 conn, got %d", svr.SocketCount())
+	}
+	m := svr.Metrics()
+	if m.Accepted != 1 || m.Active != 1 || m.PacketsIn != 2 || m.PacketsOut != 1 {
+		t.Fatalf("unexpected metrics: %+v", m)
+	}
+}
@@ -0,0 +1,68 @@
+package network
+
+import (
+	"sync"
+)
+
+// ConnTable keeps the established connections of a server, keyed by conn id.
+type ConnTable struct {
+	mu    sync.RWMutex
+	conns map[string]Conn
+}
+
+func NewConnTable() *ConnTable {
+	return &ConnTable{
+		conns: make(map[string]Conn),
+	}
+}
+
+func (t *ConnTable) Store(c Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.conns[c.ConnID()] = c
+}
+
+func (t *ConnTable) RemoveIfSame(c Conn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if v, has := t.conns[c.ConnID()]; has && v == c {
+		delete(t.conns, c.ConnID())
+	}
+}
+
+func (t *ConnTable) Get(connid string) Conn {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.conns[connid]
+}
+
+func (t *ConnTable) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.conns)
+}
+
+func (t *ConnTable) Range(f func(Conn) bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, c := range t.conns {
+		if !f(c) {
+			return
+		}
+	}
+}
+
+// CloseIf closes the conns which match, and returns how many are closed.
+func (t *ConnTable) CloseIf(match func(Conn) bool) int {
+	var matched []Conn
+	t.Range(func(c Conn) bool {
+		if match(c) {
+			matched = append(matched, c)
+		}
+		return true
+	})
+	for _, c := range matched {
+		c.Close()
+	}
+	return len(matched)
+}
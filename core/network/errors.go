@@ -0,0 +1,61 @@
+package network
+
+import (
+	"github.com/ajenpan/surf/core/errors"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+// SetResponseError fills the envelope of the response by errors.Envelope,
+// so a response on tcp and ws carries the same error as on http.
+func SetResponseError(resp *msg.ResponseMsgWrap, err error) {
+	resp.Err, resp.Errors = ErrorMsgs(err)
+}
+
+// ErrorMsgs converts errors.Envelope of err into the messages of the wrappers.
+func ErrorMsgs(err error) (*msg.Error, []*msg.Error) {
+	e, fields := errors.Envelope(err)
+	var ret []*msg.Error
+	for _, f := range fields {
+		ret = append(ret, errorMsg(f))
+	}
+	return errorMsg(e), ret
+}
+
+func errorMsg(e *errors.Error) *msg.Error {
+	if e == nil {
+		return nil
+	}
+	return &msg.Error{
+		Code:     e.Code,
+		Detail:   e.Detail,
+		Message:  e.Message,
+		Field:    e.Field,
+		Metadata: e.Metadata,
+	}
+}
+
+// ErrorFromMsgs is the reverse of ErrorMsgs, it returns a *errors.MultiError
+// if there are invalid fields, or the *errors.Error, or nil.
+func ErrorFromMsgs(e *msg.Error, fields []*msg.Error) error {
+	if len(fields) > 0 {
+		merr := errors.NewMultiError()
+		for _, f := range fields {
+			merr.Append(fromErrorMsg(f))
+		}
+		return merr
+	}
+	if e == nil {
+		return nil
+	}
+	return fromErrorMsg(e)
+}
+
+func fromErrorMsg(e *msg.Error) *errors.Error {
+	return &errors.Error{
+		Code:     e.Code,
+		Detail:   e.Detail,
+		Message:  e.Message,
+		Field:    e.Field,
+		Metadata: e.Metadata,
+	}
+}
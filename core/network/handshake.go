@@ -0,0 +1,241 @@
+package network
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// HVProtocolVersion is the current version of the HVPacket framing protocol.
+// Version 0 is reserved for legacy clients which send an empty handshake body.
+const HVProtocolVersion uint16 = 1
+
+type HandShakeFeature = uint32
+
+const (
+	HandShakeFeatureCompress     HandShakeFeature = 1 << 0
+	HandShakeFeatureEncrypt      HandShakeFeature = 1 << 1
+	HandShakeFeatureLargeFrame   HandShakeFeature = 1 << 2
+	HandShakeFeatureAll          HandShakeFeature = HandShakeFeatureCompress | HandShakeFeatureEncrypt | HandShakeFeatureLargeFrame
+	handShakeAppVersionMaxLen                     = 0xFF
+	handShakeResultMessageMaxLen                  = 0xFFFF
+)
+
+// HandShakeStatus is carried in the sub flag of a HVPacketFlagHandShakeResult packet.
+type HandShakeStatus = uint8
+
+const (
+	HandShakeAccepted          HandShakeStatus = 0
+	HandShakeRejectVersion     HandShakeStatus = 1
+	HandShakeRejectFeature     HandShakeStatus = 2
+	HandShakeRejectAuth        HandShakeStatus = 3
+	HandShakeRejectInvalidBody HandShakeStatus = 4
+)
+
+// HandShakeRequest is the body of the HVPacketFlagHandShake packet sent by the client.
+//
+//	| version uint16 | features uint32 | app_version_len uint8 | app_version |
+type HandShakeRequest struct {
+	Version    uint16
+	Features   HandShakeFeature
+	AppVersion string
+}
+
+func (r *HandShakeRequest) Marshal() []byte {
+	app := r.AppVersion
+	if len(app) > handShakeAppVersionMaxLen {
+		app = app[:handShakeAppVersionMaxLen]
+	}
+	ret := make([]byte, 7+len(app))
+	binary.LittleEndian.PutUint16(ret[0:], r.Version)
+	binary.LittleEndian.PutUint32(ret[2:], r.Features)
+	ret[6] = byte(len(app))
+	copy(ret[7:], app)
+	return ret
+}
+
+// Unmarshal decodes the handshake body. An empty body is a legacy handshake of version 0.
+func (r *HandShakeRequest) Unmarshal(body []byte) error {
+	*r = HandShakeRequest{}
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) < 7 {
+		return ErrInvalidPacket
+	}
+	r.Version = binary.LittleEndian.Uint16(body[0:])
+	r.Features = binary.LittleEndian.Uint32(body[2:])
+	applen := int(body[6])
+	if len(body) < 7+applen {
+		return ErrInvalidPacket
+	}
+	r.AppVersion = string(body[7 : 7+applen])
+	return nil
+}
+
+// HandShakeResult is the body of the HVPacketFlagHandShakeResult packet sent by the server.
+// The Status is duplicated in the packet sub flag, so that a client can tell
+// an accept from a reject without decoding the body.
+//
+//	| status uint8 | version uint16 | features uint32 | conn_id_len uint8 | conn_id | msg_len uint16 | msg |
+type HandShakeResult struct {
+	Status   HandShakeStatus
+	Version  uint16
+	Features HandShakeFeature
+	ConnID   string
+	Message  string
+}
+
+func (r *HandShakeResult) Marshal() []byte {
+	connid := r.ConnID
+	if len(connid) > 0xFF {
+		connid = connid[:0xFF]
+	}
+	msg := r.Message
+	if len(msg) > handShakeResultMessageMaxLen {
+		msg = msg[:handShakeResultMessageMaxLen]
+	}
+	ret := make([]byte, 10+len(connid)+len(msg))
+	ret[0] = r.Status
+	binary.LittleEndian.PutUint16(ret[1:], r.Version)
+	binary.LittleEndian.PutUint32(ret[3:], r.Features)
+	ret[7] = byte(len(connid))
+	copy(ret[8:], connid)
+	pos := 8 + len(connid)
+	binary.LittleEndian.PutUint16(ret[pos:], uint16(len(msg)))
+	copy(ret[pos+2:], msg)
+	return ret
+}
+
+func (r *HandShakeResult) Unmarshal(body []byte) error {
+	*r = HandShakeResult{}
+	if len(body) < 10 {
+		return ErrInvalidPacket
+	}
+	r.Status = body[0]
+	r.Version = binary.LittleEndian.Uint16(body[1:])
+	r.Features = binary.LittleEndian.Uint32(body[3:])
+	connidLen := int(body[7])
+	if len(body) < 10+connidLen {
+		return ErrInvalidPacket
+	}
+	r.ConnID = string(body[8 : 8+connidLen])
+	pos := 8 + connidLen
+	msgLen := int(binary.LittleEndian.Uint16(body[pos:]))
+	if len(body) < pos+2+msgLen {
+		return ErrInvalidPacket
+	}
+	r.Message = string(body[pos+2 : pos+2+msgLen])
+	return nil
+}
+
+func (r *HandShakeResult) Accepted() bool {
+	return r.Status == HandShakeAccepted
+}
+
+func (r *HandShakeResult) Error() string {
+	return fmt.Sprintf("handshake status:%d, msg:%s", r.Status, r.Message)
+}
+
+// HandShakePolicy decides whether a client handshake is accepted,
+// and which features are enabled on the connection.
+type HandShakePolicy struct {
+	// MinVersion and MaxVersion bound the accepted protocol versions.
+	// a zero MaxVersion means HVProtocolVersion.
+	MinVersion uint16
+	MaxVersion uint16
+
+	// Features the server is able to serve; unsupported features requested
+	// by a client are downgraded (masked out) instead of rejected.
+	Features HandShakeFeature
+
+	// RequiredFeatures must be requested by the client, otherwise the handshake is rejected.
+	RequiredFeatures HandShakeFeature
+
+	// RejectLegacy rejects clients which send an empty (version 0) handshake body.
+	RejectLegacy bool
+}
+
+var DefaultHandShakePolicy = &HandShakePolicy{
+	MinVersion: 0,
+	MaxVersion: HVProtocolVersion,
+}
+
+// Negotiate applies the policy to the client request.
+func (p *HandShakePolicy) Negotiate(req *HandShakeRequest) *HandShakeResult {
+	maxVersion := p.MaxVersion
+	if maxVersion == 0 {
+		maxVersion = HVProtocolVersion
+	}
+
+	if req.Version == 0 && p.RejectLegacy {
+		return &HandShakeResult{
+			Status:  HandShakeRejectVersion,
+			Version: maxVersion,
+			Message: "legacy handshake is not supported",
+		}
+	}
+
+	if req.Version < p.MinVersion || req.Version > maxVersion {
+		return &HandShakeResult{
+			Status:  HandShakeRejectVersion,
+			Version: maxVersion,
+			Message: fmt.Sprintf("protocol version %d is not in [%d,%d]", req.Version, p.MinVersion, maxVersion),
+		}
+	}
+
+	if req.Features&p.RequiredFeatures != p.RequiredFeatures {
+		return &HandShakeResult{
+			Status:   HandShakeRejectFeature,
+			Version:  req.Version,
+			Features: p.RequiredFeatures,
+			Message:  fmt.Sprintf("required features %#x are missing", p.RequiredFeatures&^req.Features),
+		}
+	}
+
+	return &HandShakeResult{
+		Status:   HandShakeAccepted,
+		Version:  req.Version,
+		Features: req.Features & p.Features,
+	}
+}
+
+// handShakeInfo keeps the negotiated result of a connection.
+type handShakeInfo struct {
+	version    uint16
+	features   HandShakeFeature
+	appVersion string
+}
+
+func newHandShakeInfo(req *HandShakeRequest, result *HandShakeResult) handShakeInfo {
+	return handShakeInfo{
+		version:    result.Version,
+		features:   result.Features,
+		appVersion: req.AppVersion,
+	}
+}
+
+func (h *handShakeInfo) ProtocolVersion() uint16 {
+	return h.version
+}
+
+func (h *handShakeInfo) HasFeature(f HandShakeFeature) bool {
+	return h.features&f == f
+}
+
+func (h *handShakeInfo) ClientAppVersion() string {
+	return h.appVersion
+}
+
+// newHandShakeResultPacket builds the packet replied to the client.
+// legacy clients(version 0) keep receiving the raw conn id as the body.
+func newHandShakeResultPacket(req *HandShakeRequest, result *HandShakeResult) *HVPacket {
+	pk := NewHVPacket()
+	pk.SetFlag(HVPacketFlagHandShakeResult)
+	pk.SetSubFlag(result.Status)
+	if req.Version == 0 && result.Accepted() {
+		pk.SetBody([]byte(result.ConnID))
+	} else {
+		pk.SetBody(result.Marshal())
+	}
+	return pk
+}
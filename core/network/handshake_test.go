@@ -0,0 +1,126 @@
+package network
+
+import (
+	"net"
+	"testing"
+)
+
+func TestHandShakeMarshal(t *testing.T) {
+	req := &HandShakeRequest{
+		Version:    HVProtocolVersion,
+		Features:   HandShakeFeatureCompress | HandShakeFeatureLargeFrame,
+		AppVersion: "1.2.3",
+	}
+	got := &HandShakeRequest{}
+	if err := got.Unmarshal(req.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if *got != *req {
+		t.Fatalf("expected %v got %v", req, got)
+	}
+
+	if err := got.Unmarshal(nil); err != nil || got.Version != 0 {
+		t.Fatal("empty body should be a legacy handshake")
+	}
+	if err := got.Unmarshal([]byte{1, 0}); err == nil {
+		t.Fatal("short body should be invalid")
+	}
+
+	result := &HandShakeResult{
+		Status:   HandShakeRejectFeature,
+		Version:  HVProtocolVersion,
+		Features: HandShakeFeatureEncrypt,
+		ConnID:   "1_1",
+		Message:  "required features",
+	}
+	gotResult := &HandShakeResult{}
+	if err := gotResult.Unmarshal(result.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	if *gotResult != *result {
+		t.Fatalf("expected %v got %v", result, gotResult)
+	}
+}
+
+func TestHandShakePolicy(t *testing.T) {
+	policy := &HandShakePolicy{
+		MinVersion:       1,
+		Features:         HandShakeFeatureCompress,
+		RequiredFeatures: HandShakeFeatureLargeFrame,
+	}
+
+	testData := []struct {
+		req    HandShakeRequest
+		status HandShakeStatus
+		feat   HandShakeFeature
+	}{
+		{HandShakeRequest{Version: 0}, HandShakeRejectVersion, 0},
+		{HandShakeRequest{Version: HVProtocolVersion + 1, Features: HandShakeFeatureLargeFrame}, HandShakeRejectVersion, 0},
+		{HandShakeRequest{Version: 1, Features: HandShakeFeatureCompress}, HandShakeRejectFeature, HandShakeFeatureLargeFrame},
+		{HandShakeRequest{Version: 1, Features: HandShakeFeatureAll}, HandShakeAccepted, HandShakeFeatureCompress},
+	}
+
+	for _, d := range testData {
+		res := policy.Negotiate(&d.req)
+		if res.Status != d.status || res.Features != d.feat {
+			t.Fatalf("req %v: expected %d/%#x got %d/%#x", d.req, d.status, d.feat, res.Status, res.Features)
+		}
+	}
+}
+
+func dialHandShake(t *testing.T, addr string, body []byte) *HVPacket {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	pk := NewHVPacket()
+	pk.SetFlag(HVPacketFlagHandShake)
+	pk.SetBody(body)
+	if _, err := pk.WriteTo(c); err != nil {
+		t.Fatal(err)
+	}
+	ret := NewHVPacket()
+	if _, err := ret.ReadFrom(c); err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestTcpServerHandShake(t *testing.T) {
+	svr, err := NewTcpServer(TcpServerOptions{
+		ListenAddr:   "127.0.0.1:0",
+		OnConnPacket: func(Conn, *HVPacket) {},
+		HandShakePolicy: &HandShakePolicy{
+			Features: HandShakeFeatureCompress,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.Start()
+	defer svr.Stop()
+
+	addr := svr.Address().String()
+
+	// legacy client: empty body, the conn id is returned as raw body
+	pk := dialHandShake(t, addr, nil)
+	if pk.GetFlag() != HVPacketFlagHandShakeResult || pk.GetSubFlag() != HandShakeAccepted || len(pk.GetBody()) == 0 {
+		t.Fatal("legacy handshake failed")
+	}
+
+	pk = dialHandShake(t, addr, (&HandShakeRequest{Version: HVProtocolVersion, Features: HandShakeFeatureAll}).Marshal())
+	result := &HandShakeResult{}
+	if err := result.Unmarshal(pk.GetBody()); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Accepted() || result.Features != HandShakeFeatureCompress || result.ConnID == "" {
+		t.Fatalf("unexpected result: %v", result)
+	}
+
+	pk = dialHandShake(t, addr, (&HandShakeRequest{Version: HVProtocolVersion + 1}).Marshal())
+	if pk.GetSubFlag() != HandShakeRejectVersion {
+		t.Fatalf("expected reject version, got %d", pk.GetSubFlag())
+	}
+}
@@ -0,0 +1,195 @@
+package network
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"io"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	"github.com/ajenpan/surf/core/utils/marshal"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+// DefaultHttpCodecs are used by the http servers which don't set their own.
+var DefaultHttpCodecs = marshal.DefaultCodecs()
+
+// DefaultMaxHttpBodySize limits the request bodies of the servers which don't set their own limit.
+var DefaultMaxHttpBodySize int64 = 4 << 20
+
+// httpEnvelope is the json response of a method:
+//
+//	{"err": {"code", "message", "detail", "metadata"}, "errors": [{"code", "message", "detail", "field"}], "data": response}
+//
+// the protobuf response is a core.ResponseMsgWrap, the same as on tcp and ws.
+type httpEnvelope struct {
+	Err    *errors.Error   `json:"err,omitempty"`
+	Errors []*errors.Error `json:"errors,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// ReadHttpRequest decodes the request message of the method,
+// from the query of a GET request, or from the body by the codec of its Content-Type.
+// out is the codec selected by Accept, which the response is written in,
+// it is the default codec if none is acceptable.
+// A body larger than maxBodySize, DefaultMaxHttpBodySize if not positive, is refused with CodeRequestTooLarge.
+func ReadHttpRequest(codecs *marshal.Codecs, r *http.Request, method *calltable.Method, maxBodySize int64) (req interface{}, out marshal.Marshaler, err error) {
+	out, ok := codecs.ForAccept(r.Header.Get("Accept"))
+	if !ok {
+		return nil, codecs.Default, errors.NotAcceptable("not acceptable: %s", r.Header.Get("Accept"))
+	}
+
+	req = method.NewRequest()
+
+	if r.Method == http.MethodGet {
+		if err := marshal.UnmarshalQuery(r.URL.Query(), req); err != nil {
+			return nil, out, errors.InvalidArgument("%v", err)
+		}
+		return req, out, nil
+	}
+
+	in, ok := codecs.ForContentType(r.Header.Get("Content-Type"))
+	if !ok {
+		return nil, out, errors.UnsupportedMedia("unsupported content type: %s", r.Header.Get("Content-Type"))
+	}
+
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxHttpBodySize
+	}
+	raw, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if stderrors.As(err, &tooLarge) {
+			return nil, out, errors.RequestTooLarge("request body larger than %d bytes", maxBodySize)
+		}
+		return nil, out, errors.InvalidArgument("%v", err)
+	}
+	if len(raw) > 0 {
+		if err := in.Unmarshal(raw, req); err != nil {
+			return nil, out, errors.InvalidArgument("%v", err)
+		}
+	}
+	return req, out, nil
+}
+
+// WriteHttpResponse writes the response or the error in the envelope of the codec,
+// the http status is mapped from the error by errors.HTTPStatus.
+func WriteHttpResponse(w http.ResponseWriter, out marshal.Marshaler, name string, resp interface{}, err error) {
+	switch out.(type) {
+	case *marshal.ProtoMarshaler:
+		writeProtoResponse(w, out, name, resp, err)
+		return
+	case marshal.BytesMarshaler:
+		// raw bytes have no envelope, the errors are written in json.
+		if err == nil {
+			raw, merr := out.Marshal(resp)
+			if merr == nil {
+				writeHttp(w, http.StatusOK, out.ContentType(resp), raw)
+				return
+			}
+			err = merr
+		}
+		out = DefaultHttpCodecs.Default
+	}
+	writeJSONResponse(w, out, resp, err)
+}
+
+// ReadHttpResponse decodes the envelope of WriteHttpResponse in the codec of the content type,
+// it returns the encoded response message, or the error of the envelope with its registry code.
+func ReadHttpResponse(contentType string, status int, raw []byte) ([]byte, error) {
+	codec, _ := DefaultHttpCodecs.ForContentType(contentType)
+	switch codec.(type) {
+	case *marshal.ProtoMarshaler:
+		wrap := &msg.ResponseMsgWrap{}
+		if err := proto.Unmarshal(raw, wrap); err != nil {
+			return nil, statusError(status)
+		}
+		if err := ErrorFromMsgs(wrap.Err, wrap.Errors); err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, statusError(status)
+		}
+		return wrap.Body, nil
+	case marshal.BytesMarshaler:
+		if status == http.StatusOK {
+			return raw, nil
+		}
+	}
+
+	env := &httpEnvelope{}
+	if err := json.Unmarshal(raw, env); err != nil {
+		return nil, statusError(status)
+	}
+	if len(env.Errors) > 0 {
+		merr := errors.NewMultiError()
+		for _, e := range env.Errors {
+			merr.Append(e)
+		}
+		return nil, merr
+	}
+	if env.Err != nil {
+		return nil, env.Err
+	}
+	if status != http.StatusOK {
+		return nil, statusError(status)
+	}
+	return env.Data, nil
+}
+
+// statusError is the error of a response without the envelope, the body is not trusted as the detail.
+func statusError(status int) error {
+	if status == http.StatusOK {
+		return errors.New(errors.CodeInternal, "invalid response envelope")
+	}
+	return errors.New(int32(status), http.StatusText(status))
+}
+
+func writeHttp(w http.ResponseWriter, status int, contentType string, raw []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(raw)
+}
+
+func writeJSONResponse(w http.ResponseWriter, out marshal.Marshaler, resp interface{}, err error) {
+	env := &httpEnvelope{}
+	if err == nil && resp != nil {
+		data, merr := out.Marshal(resp)
+		if merr != nil {
+			err = merr
+		} else {
+			env.Data = data
+		}
+	}
+	env.Err, env.Errors = errors.Envelope(err)
+
+	raw, merr := json.Marshal(env)
+	if merr != nil {
+		writeHttp(w, http.StatusInternalServerError, "text/plain; charset=utf-8", []byte(merr.Error()))
+		return
+	}
+	writeHttp(w, errors.HTTPStatus(err), out.ContentType(resp)+"; charset=utf-8", raw)
+}
+
+func writeProtoResponse(w http.ResponseWriter, out marshal.Marshaler, name string, resp interface{}, err error) {
+	wrap := &msg.ResponseMsgWrap{Name: name}
+	if err == nil && resp != nil {
+		body, merr := out.Marshal(resp)
+		if merr != nil {
+			err = merr
+		} else {
+			wrap.Body = body
+		}
+	}
+	SetResponseError(wrap, err)
+
+	raw, merr := proto.Marshal(wrap)
+	if merr != nil {
+		writeHttp(w, http.StatusInternalServerError, "text/plain; charset=utf-8", []byte(merr.Error()))
+		return
+	}
+	writeHttp(w, errors.HTTPStatus(err), out.ContentType(resp), raw)
+}
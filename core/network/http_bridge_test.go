@@ -0,0 +1,114 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+func echo(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	if in.Value == "fail" {
+		return nil, calltable.ErrPermissionDenied
+	}
+	return wrapperspb.String("echo:" + in.Value), nil
+}
+
+func TestHttpBridge(t *testing.T) {
+	svr := &HttpSvr{Mux: http.NewServeMux()}
+	h := svr.WrapMethod(calltable.NewMethod(echo))
+
+	do := func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	// GET binds the query
+	w := do(httptest.NewRequest(http.MethodGet, "/echo?value=a", nil))
+	var env httpEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("status:%d, body:%s", w.Code, w.Body.String())
+	}
+	if string(env.Data) != `"echo:a"` {
+		t.Fatalf("unexpected data: %s", env.Data)
+	}
+
+	// protobuf in and out
+	body, _ := proto.Marshal(wrapperspb.String("b"))
+	r := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/x-protobuf")
+	r.Header.Set("Accept", "application/protobuf")
+	w = do(r)
+	wrap := &msg.ResponseMsgWrap{}
+	if err := proto.Unmarshal(w.Body.Bytes(), wrap); err != nil {
+		t.Fatal(err)
+	}
+	resp := &wrapperspb.StringValue{}
+	if err := proto.Unmarshal(wrap.Body, resp); err != nil || resp.Value != "echo:b" {
+		t.Fatalf("unexpected response: %v, %v", resp, err)
+	}
+
+	// errors are mapped to the http status
+	w = do(httptest.NewRequest(http.MethodGet, "/echo?value=fail", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("x"))
+	r.Header.Set("Content-Type", "text/xml")
+	if w = do(r); w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/echo", nil)
+	r.Header.Set("Accept", "text/html")
+	if w = do(r); w.Code != http.StatusNotAcceptable {
+		t.Fatalf("status: %d", w.Code)
+	}
+
+	svr.MaxBodySize = 8
+	r = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader(`{"value":"too long"}`))
+	r.Header.Set("Content-Type", "application/json")
+	if w = do(r); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status: %d", w.Code)
+	}
+}
+
+func TestReadHttpResponse(t *testing.T) {
+	svr := &HttpSvr{Mux: http.NewServeMux()}
+	h := svr.WrapMethod(calltable.NewMethod(echo))
+
+	for _, accept := range []string{"application/json", "application/protobuf"} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/echo?value=fail", nil)
+		r.Header.Set("Accept", accept)
+		h(w, r)
+		_, err := ReadHttpResponse(w.Header().Get("Content-Type"), w.Code, w.Body.Bytes())
+		if e, ok := errors.As(err); !ok || e.Code != errors.CodePermissionDenied {
+			t.Fatalf("%s: the error of the envelope is lost: %v", accept, err)
+		}
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodGet, "/echo?value=a", nil)
+		r.Header.Set("Accept", accept)
+		h(w, r)
+		data, err := ReadHttpResponse(w.Header().Get("Content-Type"), w.Code, w.Body.Bytes())
+		if err != nil || len(data) == 0 {
+			t.Fatalf("%s: the data is lost: %v", accept, err)
+		}
+	}
+
+	if _, err := ReadHttpResponse("text/plain", http.StatusBadGateway, []byte("upstream secret")); err == nil || strings.Contains(err.Error(), "secret") {
+		t.Fatal("the body without the envelope is echoed:", err)
+	}
+}
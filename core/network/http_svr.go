@@ -1,19 +1,30 @@
 package network
 
 import (
-	"io"
 	"net/http"
 	"strings"
 
+	"github.com/ajenpan/surf/core/errors"
 	"github.com/ajenpan/surf/core/utils/calltable"
 	"github.com/ajenpan/surf/core/utils/marshal"
+	"github.com/ajenpan/surf/core/utils/openapi"
 )
 
 type HttpSvr struct {
-	Addr    string
+	Addr string
+	// Marshal is the default codec, the bodies of the other content types are
+	// decoded by DefaultHttpCodecs. it is ignored if Codecs is set.
 	Marshal marshal.Marshaler
+	Codecs  *marshal.Codecs
 	Mux     *http.ServeMux
 	svr     *http.Server
+
+	// Dispatcher enforces the method options, a default one is used if it is nil.
+	Dispatcher *calltable.Dispatcher
+	// Authenticate returns the caller of the request, nil for anonymous callers.
+	Authenticate func(r *http.Request) (calltable.Caller, error)
+	// MaxBodySize limits the request bodies, DefaultMaxHttpBodySize if zero.
+	MaxBodySize int64
 }
 
 func (s *HttpSvr) Run() error {
@@ -28,65 +39,74 @@ func (s *HttpSvr) Stop() error {
 	return s.svr.Close()
 }
 
+// ServerCallTable serves the methods of the calltable, except the server streams.
 func (s *HttpSvr) ServerCallTable(ct *calltable.CallTable[string]) {
 	ct.Range(func(key string, method *calltable.Method) bool {
+		if method.Style == calltable.StyleServerStream {
+			return true
+		}
 		if !strings.HasPrefix(key, "/") {
 			key = "/" + key
 		}
+		key, _ = method.HttpRoute(key)
 		cb := s.WrapMethod(method)
 		s.Mux.HandleFunc(key, cb)
 		return true
 	})
 }
 
+// ServeOpenAPI serves the openapi document of the calltable.
+func (s *HttpSvr) ServeOpenAPI(ct *calltable.CallTable[string], title string, withDocs bool) {
+	doc := openapi.Generate(ct, openapi.Options{
+		Title:      title,
+		PathPrefix: "/",
+	})
+	openapi.Serve(s.Mux, doc, withDocs)
+}
+
 func (s *HttpSvr) HandleMethod(name string, method *calltable.Method) {
 	s.Mux.HandleFunc(name, s.WrapMethod(method))
 }
 
+func (s *HttpSvr) codecs() *marshal.Codecs {
+	if s.Codecs != nil {
+		return s.Codecs
+	}
+	if s.Marshal == nil {
+		return DefaultHttpCodecs
+	}
+	s.Codecs = marshal.DefaultCodecs()
+	s.Codecs.Default = s.Marshal
+	return s.Codecs
+}
+
 func (s *HttpSvr) WrapMethod(method *calltable.Method) http.HandlerFunc {
+	if s.Dispatcher == nil {
+		s.Dispatcher = calltable.NewDispatcher()
+	}
+	codecs := s.codecs()
+	_, verb := method.HttpRoute("")
 	return func(w http.ResponseWriter, r *http.Request) {
-		raw, err := io.ReadAll(r.Body)
-		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
+		if len(verb) > 0 && r.Method != verb {
+			WriteHttpResponse(w, codecs.Default, method.FuncName, nil, errors.MethodNotAllowed("method %s not allowed", r.Method))
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		req := method.GetRequest()
-		defer method.PutRequest(req)
-
-		if err := s.Marshal.Unmarshal(raw, req); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(err.Error()))
-			return
-		}
-
-		// here call method
-		respArgs := method.Call(r.Context(), req)
-
-		if len(respArgs) != 2 {
-			return
-		}
-
-		var respErr error
-
-		if !respArgs[1].IsNil() {
-			respErr = respArgs[1].Interface().(error)
-			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(respErr.Error()))
+		req, out, err := ReadHttpRequest(codecs, r, method, s.MaxBodySize)
+		if err != nil {
+			WriteHttpResponse(w, out, method.FuncName, nil, err)
 			return
 		}
 
-		if !respArgs[0].IsNil() {
-			respData, err := s.Marshal.Marshal(respArgs[0].Interface())
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(err.Error()))
+		var caller calltable.Caller
+		if s.Authenticate != nil {
+			if caller, err = s.Authenticate(r); err != nil {
+				WriteHttpResponse(w, out, method.FuncName, nil, errors.Wrap(err, errors.CodeUnauthenticated, "invalid token"))
 				return
 			}
-			w.Write(respData)
 		}
-		w.WriteHeader(http.StatusOK)
+
+		resp, err := s.Dispatcher.Invoke(method, caller, r.Context(), req)
+		WriteHttpResponse(w, out, method.FuncName, resp, err)
 	}
 }
@@ -0,0 +1,40 @@
+package network
+
+import (
+	"sync/atomic"
+)
+
+// Metrics counts the connections and traffic of a server.
+// Read it by Snapshot, the fields are updated atomically.
+type Metrics struct {
+	Accepted int64
+	Rejected int64
+	Active   int64
+
+	PacketsIn  int64
+	PacketsOut int64
+	BytesIn    int64
+	BytesOut   int64
+}
+
+func (m *Metrics) Snapshot() Metrics {
+	return Metrics{
+		Accepted:   atomic.LoadInt64(&m.Accepted),
+		Rejected:   atomic.LoadInt64(&m.Rejected),
+		Active:     atomic.LoadInt64(&m.Active),
+		PacketsIn:  atomic.LoadInt64(&m.PacketsIn),
+		PacketsOut: atomic.LoadInt64(&m.PacketsOut),
+		BytesIn:    atomic.LoadInt64(&m.BytesIn),
+		BytesOut:   atomic.LoadInt64(&m.BytesOut),
+	}
+}
+
+func (m *Metrics) onPacketIn(pk *HVPacket) {
+	atomic.AddInt64(&m.PacketsIn, 1)
+	atomic.AddInt64(&m.BytesIn, int64(hvPackMetaLen+len(pk.GetBody())))
+}
+
+func (m *Metrics) onPacketOut(pk *HVPacket) {
+	atomic.AddInt64(&m.PacketsOut, 1)
+	atomic.AddInt64(&m.BytesOut, int64(hvPackMetaLen+len(pk.GetBody())))
+}
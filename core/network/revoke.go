@@ -0,0 +1,32 @@
+package network
+
+import (
+	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
+	"github.com/ajenpan/surf/core/log"
+)
+
+// CloseRevoked receives the revocation events of uauth, the revocations are added to the deny list,
+// which denies the tokens at OnConnAuth if the verifier is wrapped by auth.WithRevocation,
+// and the conns authenticated by the revoked tokens are closed.
+// The revocations made while the server was down are replayed when its event.Subscriber connects,
+// as long as the hub retains them.
+func CloseRevoked(revoked *auth.DenyList, tables ...*ConnTable) event.Recver {
+	return event.RecverFunc(func(e *event.Event) {
+		r, ok := auth.ParseRevocationEvent(e)
+		if !ok {
+			return
+		}
+		revoked.Add(r)
+		kicked := 0
+		for _, t := range tables {
+			kicked += t.CloseIf(func(c Conn) bool {
+				u := ConnUser(c)
+				return u != nil && revoked.IsRevoked(u)
+			})
+		}
+		if kicked > 0 {
+			log.Infof("closed %d conns of the revoked tokens, uid: %d", kicked, r.Uid)
+		}
+	})
+}
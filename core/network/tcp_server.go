@@ -3,10 +3,7 @@ package network
 import (
 	"fmt"
 	"net"
-	"sync"
 	"time"
-
-	"github.com/ajenpan/surf/core/auth"
 )
 
 type TcpServerOptions struct {
@@ -17,18 +14,22 @@ type TcpServerOptions struct {
 	OnConnEnable  FuncOnConnEnable
 	OnConnAuth    FuncOnConnAuth
 	OnConnAccpect func(net.Conn) bool
+
+	HandShakePolicy *HandShakePolicy
 }
 
 type TcpServerOption func(*TcpServerOptions)
 
 func NewTcpServer(opts TcpServerOptions) (*TcpServer, error) {
 	ret := &TcpServer{
-		opts:    opts,
-		sockets: make(map[string]*TcpConn),
-		die:     make(chan bool),
-	}
-	if ret.opts.HeatbeatInterval < time.Duration(DefaultMinTimeoutSec)*time.Second {
-		ret.opts.HeatbeatInterval = time.Duration(DefaultTimeoutSec) * time.Second
+		opts: opts,
+		connServer: newConnServer(connServerOptions{
+			HeatbeatInterval: opts.HeatbeatInterval,
+			OnConnPacket:     opts.OnConnPacket,
+			OnConnEnable:     opts.OnConnEnable,
+			OnConnAuth:       opts.OnConnAuth,
+			HandShakePolicy:  opts.HandShakePolicy,
+		}),
 	}
 
 	listener, err := net.Listen("tcp", opts.ListenAddr)
@@ -40,19 +41,14 @@ func NewTcpServer(opts TcpServerOptions) (*TcpServer, error) {
 }
 
 type TcpServer struct {
+	connServer
 	opts     TcpServerOptions
-	mu       sync.RWMutex
-	sockets  map[string]*TcpConn
-	die      chan bool
 	listener net.Listener
 }
 
 func (s *TcpServer) Stop() error {
-	select {
-	case <-s.die:
+	if !s.shutdown() {
 		return nil
-	default:
-		close(s.die)
 	}
 	s.listener.Close()
 	return nil
@@ -100,128 +96,12 @@ func (s *TcpServer) onAccept(c net.Conn) {
 		}
 	}
 
-	conn, err := s.handshake(c)
-	if err != nil {
-		return
-	}
-
-	conn.status = Connected
-
-	// the connection is established here
-	go func() {
-		defer conn.Close()
-		conn.writeWork()
-	}()
-
-	go func() {
-		defer conn.Close()
-		conn.readWork()
-	}()
-
-	if s.opts.OnConnEnable != nil {
-		s.opts.OnConnEnable(conn, true)
-		defer s.opts.OnConnEnable(conn, false)
-	}
-
-	for {
-		select {
-		case <-conn.chClosed:
-			return
-		case <-s.die:
-			conn.Close()
-			return
-		case packet, ok := <-conn.chRead:
-			if !ok {
-				return
-			}
-			switch packet.GetFlag() {
-			case HVPacketFlagHeartbeat:
-				conn.Send(packet)
-			case HVPacketFlagPacket:
-				s.opts.OnConnPacket(conn, packet)
-			}
-
-		}
-	}
-}
-
-func (s *TcpServer) handshake(conn net.Conn) (*TcpConn, error) {
-	deadline := time.Now().Add(s.opts.HeatbeatInterval * 2)
-	conn.SetReadDeadline(deadline)
-	conn.SetWriteDeadline(deadline)
-	pk := NewHVPacket()
-	_, err := pk.ReadFrom(conn)
-	if err != nil {
-		return nil, err
-	}
-
-	if pk.GetFlag() != HVPacketFlagHandShake && len(pk.GetBody()) != 0 {
-		return nil, ErrInvalidPacket
-	}
-
-	var us auth.User
-	if s.opts.OnConnAuth != nil {
-		pk.Reset()
-		pk.SetFlag(HVPacketFlagCmd)
-		if _, err = pk.WriteTo(conn); err != nil {
-			return nil, err
-		}
-		if _, err = pk.ReadFrom(conn); err != nil {
-			return nil, err
-		}
-		if us, err = s.opts.OnConnAuth(pk.GetBody()); err != nil {
-			return nil, err
-		}
-	}
-
-	// var userinfo *UserInfo
-	// // auth token
-	// if s.opts.AuthFunc != nil {
-	// 	p.SetFlag(hvPacketFlagActionRequire)
-	// 	p.SetBody([]byte("auth"))
-	// 	if _, err = WritePacket(conn, p); err != nil {
-	// 		return nil, err
-	// 	}
-	// 	if p, err = ReadPacketT[*HVPacket](conn); err != nil || p.GetFlag() != hvPacketFlagDoAction {
-	// 		return nil, err
-	// 	}
-	// 	if userinfo, err = s.opts.AuthFunc(p.GetBody()); err != nil {
-	// 		p.SetFlag(hvPacketFlagAckResult)
-	// 		p.SetBody([]byte("fail"))
-	// 		WritePacket(conn, p)
-	// 		return nil, err
-	// 	}
-	// }
-
-	socketid := GenConnID()
-
-	socket := &TcpConn{
-		User:     us,
-		id:       socketid,
-		conn:     conn,
-		timeOut:  s.opts.HeatbeatInterval,
-		chClosed: make(chan struct{}),
-		status:   Disconnected,
-		chWrite:  make(chan *HVPacket, 10),
-		chRead:   make(chan *HVPacket, 10),
-	}
-
-	pk.SetFlag(HVPacketFlagHandShakeResult)
-	pk.SetSubFlag(0)
-	pk.SetBody([]byte(socketid))
-	if _, err := pk.WriteTo(conn); err != nil {
-		return nil, err
-	}
-
-	return socket, nil
+	s.serve(&TcpConn{
+		connBase: newConnBase(s.connServer.opts.HeatbeatInterval),
+		conn:     c,
+	})
 }
 
 func (s *TcpServer) Address() net.Addr {
 	return s.listener.Addr()
 }
-
-func (s *TcpServer) SocketCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.sockets)
-}
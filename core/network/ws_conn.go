@@ -1,58 +1,30 @@
 package network
 
 import (
-	"sync/atomic"
+	"net"
 	"time"
 
-	"github.com/ajenpan/surf/core/auth"
 	ws "github.com/gorilla/websocket"
 )
 
 type WSConn struct {
-	auth.User
+	connBase
 
-	imp      *ws.Conn
-	status   ConnStatus
-	chClosed chan struct{}
-	timeOut  time.Duration
-
-	chWrite chan *HVPacket
-	chRead  chan *HVPacket
-
-	id string
-}
-
-func (c *WSConn) Send(p *HVPacket) error {
-	return c.writePacket(p)
+	imp *ws.Conn
 }
 
-func (c *WSConn) ConnID() string {
-	return c.id
-}
-
-func (c *WSConn) Close() error {
-	old := atomic.SwapInt32((*int32)(&c.status), int32(Disconnected))
-	if old != Connected {
+func (c *WSConn) RemoteAddr() net.Addr {
+	if !c.Enable() {
 		return nil
 	}
+	return c.imp.RemoteAddr()
+}
 
-	select {
-	case <-c.chClosed:
-		return nil
-	default:
-		close(c.chClosed)
-		close(c.chWrite)
-		close(c.chRead)
+func (c *WSConn) LocalAddr() net.Addr {
+	if !c.Enable() {
 		return nil
 	}
-}
-
-func (c *WSConn) Enable() bool {
-	return c.Status() == Connected
-}
-
-func (c *WSConn) Status() ConnStatus {
-	return ConnStatus(atomic.LoadInt32((*int32)(&c.status)))
+	return c.imp.LocalAddr()
 }
 
 func (c *WSConn) writePacket(h *HVPacket) error {
@@ -63,7 +35,6 @@ func (c *WSConn) writePacket(h *HVPacket) error {
 	defer writer.Close()
 	_, err = h.WriteTo(writer)
 	return err
-
 }
 
 func (c *WSConn) readPacket() (*HVPacket, error) {
@@ -76,36 +47,10 @@ func (c *WSConn) readPacket() (*HVPacket, error) {
 	return pk, err
 }
 
-func (c *WSConn) writeWork() error {
-	for {
-		select {
-		case <-c.chClosed:
-			return nil
-		case p, ok := <-c.chWrite:
-			if !ok {
-				return nil
-			}
-			c.imp.SetWriteDeadline(time.Now().Add(c.timeOut))
-			err := c.writePacket(p)
-			if err != nil {
-				return err
-			}
-		}
-	}
+func (c *WSConn) setReadDeadline(t time.Time) {
+	c.imp.SetReadDeadline(t)
 }
 
-func (c *WSConn) readWork() error {
-	for {
-		c.imp.SetReadDeadline(time.Now().Add(c.timeOut))
-		pk, err := c.readPacket()
-		if err != nil {
-			return nil
-		}
-		select {
-		case <-c.chClosed:
-			return nil
-		case c.chRead <- pk:
-
-		}
-	}
+func (c *WSConn) setWriteDeadline(t time.Time) {
+	c.imp.SetWriteDeadline(t)
 }
@@ -2,12 +2,9 @@ package network
 
 import (
 	"net/http"
-	"sync"
 	"time"
 
 	ws "github.com/gorilla/websocket"
-
-	"github.com/ajenpan/surf/core/auth"
 )
 
 type WSServerOptions struct {
@@ -18,6 +15,8 @@ type WSServerOptions struct {
 	OnConnEnable  FuncOnConnEnable
 	OnConnAuth    FuncOnConnAuth
 	OnConnAccpect func(r *http.Request) bool
+
+	HandShakePolicy *HandShakePolicy
 }
 
 type WSServerOption func(*WSServerOptions)
@@ -25,11 +24,13 @@ type WSServerOption func(*WSServerOptions)
 func NewWSServer(opts WSServerOptions) *WSServer {
 	ret := &WSServer{
 		WSServerOptions: opts,
-		sockets:         make(map[string]*WSConn),
-		die:             make(chan bool),
-	}
-	if ret.HeatbeatInterval < time.Duration(DefaultMinTimeoutSec)*time.Second {
-		ret.HeatbeatInterval = time.Duration(DefaultTimeoutSec) * time.Second
+		connServer: newConnServer(connServerOptions{
+			HeatbeatInterval: opts.HeatbeatInterval,
+			OnConnPacket:     opts.OnConnPacket,
+			OnConnEnable:     opts.OnConnEnable,
+			OnConnAuth:       opts.OnConnAuth,
+			HandShakePolicy:  opts.HandShakePolicy,
+		}),
 	}
 	h := &http.ServeMux{}
 	h.HandleFunc("/", ret.ServeHTTP)
@@ -46,9 +47,7 @@ func NewWSServer(opts WSServerOptions) *WSServer {
 
 type WSServer struct {
 	WSServerOptions
-	mu       sync.RWMutex
-	sockets  map[string]*WSConn
-	die      chan bool
+	connServer
 	listener *http.Server
 
 	upgrader ws.Upgrader
@@ -60,11 +59,8 @@ func (s *WSServer) Start() error {
 }
 
 func (s *WSServer) Stop() error {
-	select {
-	case <-s.die:
+	if !s.shutdown() {
 		return nil
-	default:
-		close(s.die)
 	}
 	s.listener.Close()
 	return nil
@@ -77,97 +73,12 @@ func (s *WSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer c.Close()
 
-	conn := &WSConn{
-		timeOut:  s.HeatbeatInterval,
+	s.serve(&WSConn{
+		connBase: newConnBase(s.connServer.opts.HeatbeatInterval),
 		imp:      c,
-		status:   Connectting,
-		id:       GenConnID(),
-		chClosed: make(chan struct{}),
-		chWrite:  make(chan *HVPacket, 10),
-		chRead:   make(chan *HVPacket, 10),
-	}
-
-	deadline := time.Now().Add(s.HeatbeatInterval * 2)
-	c.SetReadDeadline(deadline)
-	c.SetWriteDeadline(deadline)
-
-	if pk, err := conn.readPacket(); err != nil {
-		return
-	} else {
-		if pk.GetFlag() != HVPacketFlagHandShake && len(pk.GetBody()) != 0 {
-			return
-		}
-	}
-
-	var us auth.User
-	if s.OnConnAuth != nil {
-		pk := NewHVPacket()
-		pk.SetFlag(HVPacketFlagCmd)
-		pk.SetSubFlag(1)
-		pk.SetBody([]byte("auth"))
-		if err := conn.writePacket(pk); err != nil {
-			return
-		}
-		if pk, err := conn.readPacket(); err != nil {
-			return
-		} else {
-			if us, err = s.OnConnAuth(pk.GetBody()); err != nil {
-				return
-			}
-			conn.User = us
-		}
-	}
-
-	pk := NewHVPacket()
-	pk.SetFlag(HVPacketFlagHandShakeResult)
-	pk.SetBody([]byte(conn.ConnID()))
-	conn.writePacket(pk)
-
-	// the connection is established here
-	go func() {
-		defer conn.Close()
-		conn.writeWork()
-	}()
-
-	go func() {
-		defer conn.Close()
-		conn.readWork()
-	}()
-
-	if s.OnConnEnable != nil {
-		s.OnConnEnable(conn, true)
-		defer s.OnConnEnable(conn, false)
-	}
-
-	for {
-		select {
-		case <-conn.chClosed:
-			return
-		case <-s.die:
-			conn.Close()
-			return
-		case packet, ok := <-conn.chRead:
-			if !ok {
-				return
-			}
-			switch packet.GetFlag() {
-			case HVPacketFlagHeartbeat:
-				conn.Send(packet)
-			case HVPacketFlagPacket:
-				if s.OnConnPacket != nil {
-					s.OnConnPacket(conn, packet)
-				}
-			}
-		}
-	}
+	})
 }
 
 func (s *WSServer) Address() string {
 	return s.listener.Addr
 }
-
-func (s *WSServer) SocketCount() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.sockets)
-}
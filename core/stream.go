@@ -0,0 +1,235 @@
+package core
+
+import (
+	gocontext "context"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+// DefaultStreamCredits is granted to a stream which is opened without credits.
+var DefaultStreamCredits uint32 = 16
+
+var (
+	ErrStreamCanceled = errors.Canceled("stream canceled")
+	ErrStreamTimeout  = errors.DeadlineExceeded("stream timeout")
+)
+
+// serverStream is the calltable.Stream of a server streaming call on a conn.
+type serverStream struct {
+	id   uint32
+	name string
+	conn network.Conn
+
+	ctx    gocontext.Context
+	cancel gocontext.CancelFunc
+
+	mu      sync.Mutex
+	credits uint32
+	granted chan struct{}
+}
+
+func newServerStream(c network.Conn, wrap *msg.StreamMsgWrap, meta *calltable.MethodMeta) *serverStream {
+	s := &serverStream{
+		id:      wrap.StreamId,
+		name:    wrap.Name,
+		conn:    c,
+		credits: wrap.Credits,
+		granted: make(chan struct{}, 1),
+	}
+	if s.credits == 0 {
+		s.credits = DefaultStreamCredits
+	}
+	if meta != nil && meta.Timeout > 0 {
+		s.ctx, s.cancel = gocontext.WithTimeout(gocontext.Background(), meta.Timeout)
+	} else {
+		s.ctx, s.cancel = gocontext.WithCancel(gocontext.Background())
+	}
+	return s
+}
+
+func (s *serverStream) Context() gocontext.Context {
+	return s.ctx
+}
+
+func (s *serverStream) Send(m proto.Message) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	body, err := proto.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return sendMsgWrap(s.conn, msg.MsgType_Stream, &msg.StreamMsgWrap{
+		StreamId: s.id,
+		Frame:    msg.StreamFrame_StreamData,
+		Body:     body,
+	})
+}
+
+// acquire takes a credit, it waits for the peer to grant one if there is none.
+func (s *serverStream) acquire() error {
+	for {
+		s.mu.Lock()
+		if s.credits > 0 {
+			s.credits--
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-s.granted:
+		case <-s.ctx.Done():
+			return s.err()
+		}
+	}
+}
+
+func (s *serverStream) grant(n uint32) {
+	s.mu.Lock()
+	s.credits += n
+	s.mu.Unlock()
+
+	select {
+	case s.granted <- struct{}{}:
+	default:
+	}
+}
+
+func (s *serverStream) err() error {
+	if s.ctx.Err() == gocontext.DeadlineExceeded {
+		return ErrStreamTimeout
+	}
+	return ErrStreamCanceled
+}
+
+func (s *serverStream) close(err error) {
+	wrap := &msg.StreamMsgWrap{
+		StreamId: s.id,
+		Frame:    msg.StreamFrame_StreamClose,
+		Name:     s.name,
+	}
+	wrap.Err, wrap.Errors = network.ErrorMsgs(err)
+	if err := sendMsgWrap(s.conn, msg.MsgType_Stream, wrap); err != nil {
+		log.Warnf("conn:%s, close stream %d failed: %v", s.conn.ConnID(), s.id, err)
+	}
+}
+
+// streamTable keeps the open streams of the conns.
+type streamTable struct {
+	mu     sync.Mutex
+	byConn map[string]map[uint32]*serverStream
+}
+
+func (t *streamTable) add(s *serverStream) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.byConn == nil {
+		t.byConn = make(map[string]map[uint32]*serverStream)
+	}
+	streams, has := t.byConn[s.conn.ConnID()]
+	if !has {
+		streams = make(map[uint32]*serverStream)
+		t.byConn[s.conn.ConnID()] = streams
+	}
+	if _, has := streams[s.id]; has {
+		return false
+	}
+	streams[s.id] = s
+	return true
+}
+
+func (t *streamTable) get(connID string, id uint32) *serverStream {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.byConn[connID][id]
+}
+
+// remove drops the stream, returns false if it is removed already.
+func (t *streamTable) remove(s *serverStream) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	streams := t.byConn[s.conn.ConnID()]
+	if streams[s.id] != s {
+		return false
+	}
+	delete(streams, s.id)
+	if len(streams) == 0 {
+		delete(t.byConn, s.conn.ConnID())
+	}
+	return true
+}
+
+// removeConn drops and cancels the streams of a closed conn.
+func (t *streamTable) removeConn(connID string) {
+	t.mu.Lock()
+	streams := t.byConn[connID]
+	delete(t.byConn, connID)
+	t.mu.Unlock()
+
+	for _, s := range streams {
+		s.cancel()
+	}
+}
+
+func (h *Surf) onStreamFrame(c network.Conn, wrap *msg.StreamMsgWrap) {
+	switch wrap.Frame {
+	case msg.StreamFrame_StreamOpen:
+		h.openStream(c, wrap)
+	case msg.StreamFrame_StreamCredit:
+		if s := h.streams.get(c.ConnID(), wrap.StreamId); s != nil {
+			s.grant(wrap.Credits)
+		}
+	case msg.StreamFrame_StreamCancel:
+		if s := h.streams.get(c.ConnID(), wrap.StreamId); s != nil && h.streams.remove(s) {
+			s.cancel()
+		}
+	default:
+		log.Warnf("conn:%s, unexpected stream frame: %v", c.ConnID(), wrap.Frame)
+	}
+}
+
+// openStream invokes the server streaming method in its own goroutine,
+// the stream is closed with the result of the method when it returns.
+func (h *Surf) openStream(c network.Conn, wrap *msg.StreamMsgWrap) {
+	var method *calltable.Method
+	if h.CTByName != nil {
+		method = h.CTByName.Get(wrap.Name)
+	}
+	var meta *calltable.MethodMeta
+	if method != nil {
+		meta = method.Meta
+	}
+	s := newServerStream(c, wrap, meta)
+
+	if method == nil || method.Style != calltable.StyleServerStream {
+		s.cancel()
+		s.close(errors.NotFound("stream method not found: %s", wrap.Name))
+		return
+	}
+	if !h.streams.add(s) {
+		s.cancel()
+		s.close(errors.AlreadyExists("stream %d is open", wrap.StreamId))
+		return
+	}
+
+	go func() {
+		defer s.cancel()
+		_, err := h.call(newContext(c, h, s), wrap.Name, wrap.Body)
+		if !h.streams.remove(s) {
+			// canceled by the peer or the conn is closed, nobody is waiting for the result.
+			return
+		}
+		if err == nil && s.ctx.Err() != nil {
+			err = s.err()
+		}
+		s.close(err)
+	}()
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+type fakeConn struct {
+	network.Conn
+	sent chan *msg.StreamMsgWrap
+}
+
+func (c *fakeConn) ConnID() string {
+	return "fake"
+}
+
+func (c *fakeConn) Send(pk *network.HVPacket) error {
+	wrap := &msg.StreamMsgWrap{}
+	if err := proto.Unmarshal(pk.GetBody(), wrap); err != nil {
+		return err
+	}
+	c.sent <- wrap
+	return nil
+}
+
+func (c *fakeConn) recv(t *testing.T) *msg.StreamMsgWrap {
+	select {
+	case wrap := <-c.sent:
+		return wrap
+	case <-time.After(time.Second):
+		t.Fatal("no frame is sent")
+		return nil
+	}
+}
+
+func (c *fakeConn) idle(t *testing.T) {
+	select {
+	case wrap := <-c.sent:
+		t.Fatalf("unexpected frame: %v", wrap)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func count(ctx Context, in *wrapperspb.UInt32Value) error {
+	for i := uint32(0); i < in.Value; i++ {
+		if err := ctx.Stream().Send(wrapperspb.UInt32(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newStreamSurf() *Surf {
+	m := calltable.NewMethod(count)
+	m.Style = calltable.StyleServerStream
+	ct := calltable.NewCallTable[string]()
+	ct.Add("Count", m)
+	return &Surf{Options: Options{CTByName: ct}, dispatcher: calltable.NewDispatcher()}
+}
+
+func openFrame(id uint32, n uint32, credits uint32) *msg.StreamMsgWrap {
+	body, _ := proto.Marshal(wrapperspb.UInt32(n))
+	return &msg.StreamMsgWrap{StreamId: id, Frame: msg.StreamFrame_StreamOpen, Name: "Count", Body: body, Credits: credits}
+}
+
+func TestStreamCredits(t *testing.T) {
+	h := newStreamSurf()
+	c := &fakeConn{sent: make(chan *msg.StreamMsgWrap, 10)}
+
+	h.onStreamFrame(c, openFrame(1, 3, 2))
+	for i := 0; i < 2; i++ {
+		if wrap := c.recv(t); wrap.Frame != msg.StreamFrame_StreamData || wrap.StreamId != 1 {
+			t.Fatalf("unexpected frame: %v", wrap)
+		}
+	}
+	c.idle(t)
+
+	h.onStreamFrame(c, &msg.StreamMsgWrap{StreamId: 1, Frame: msg.StreamFrame_StreamCredit, Credits: 1})
+	if wrap := c.recv(t); wrap.Frame != msg.StreamFrame_StreamData {
+		t.Fatalf("unexpected frame: %v", wrap)
+	}
+	if wrap := c.recv(t); wrap.Frame != msg.StreamFrame_StreamClose || wrap.Err != nil {
+		t.Fatalf("unexpected frame: %v", wrap)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	h := newStreamSurf()
+	c := &fakeConn{sent: make(chan *msg.StreamMsgWrap, 10)}
+
+	h.onStreamFrame(c, openFrame(1, 3, 1))
+	c.recv(t)
+	h.onStreamFrame(c, &msg.StreamMsgWrap{StreamId: 1, Frame: msg.StreamFrame_StreamCancel})
+	c.idle(t)
+	if h.streams.get(c.ConnID(), 1) != nil {
+		t.Fatal("the stream is not removed")
+	}
+
+	h.onStreamFrame(c, openFrame(2, 3, 1))
+	c.recv(t)
+	h.onConnStatus(c, false)
+	c.idle(t)
+
+	h.onStreamFrame(c, &msg.StreamMsgWrap{StreamId: 3, Frame: msg.StreamFrame_StreamOpen, Name: "Missing"})
+	if wrap := c.recv(t); wrap.Frame != msg.StreamFrame_StreamClose || wrap.Err.GetCode() != 404 {
+		t.Fatalf("unexpected frame: %v", wrap)
+	}
+}
@@ -57,6 +57,9 @@ func (m *CallTable[T]) Merge(other *CallTable[T], overWrite bool) int {
 }
 
 func (m *CallTable[T]) Add(name T, method *Method) bool {
+	if method == nil {
+		return false
+	}
 	m.Lock()
 	defer m.Unlock()
 	if _, has := m.list[name]; has {
@@ -0,0 +1,173 @@
+package calltable
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/utils/validate"
+)
+
+// the errors of the dispatcher, in the well-known codes of errors.
+var (
+	ErrUnauthenticated  = errors.New(errors.CodeUnauthenticated, "unauthenticated")
+	ErrPermissionDenied = errors.New(errors.CodePermissionDenied, "permission denied")
+	ErrRateLimited      = errors.New(errors.CodeResourceExhausted, "rate limited")
+	ErrTimeout          = errors.New(errors.CodeDeadlineExceeded, "call timeout")
+)
+
+// Caller is the identity of who calls a method, auth.User satisfies it.
+type Caller interface {
+	UserID() uint32
+	UserRole() uint32
+}
+
+// Dispatcher invokes the methods and enforces their MethodMeta,
+// so that the handlers don't check the permissions by themselves.
+type Dispatcher struct {
+	// Permissions grants the permissions of the methods to the roles, DefaultPermissions if nil.
+	Permissions *Permissions
+
+	mu       sync.Mutex
+	limiters map[limiterKey]*tokenBucket
+}
+
+type limiterKey struct {
+	method *Method
+	uid    uint32
+}
+
+const maxIdleLimiters = 10000
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		limiters: make(map[limiterKey]*tokenBucket),
+	}
+}
+
+// Check returns an error if the caller is not allowed to call the method.
+// caller is nil for anonymous callers, which share one rate limit per method.
+func (d *Dispatcher) Check(m *Method, caller Caller) error {
+	meta := m.Meta
+	if meta == nil {
+		return nil
+	}
+	authed := caller != nil && caller.UserID() != 0
+	if (meta.AuthRequired || meta.Role != 0 || meta.Permission != "") && !authed {
+		return ErrUnauthenticated
+	}
+	if meta.Role != 0 && caller.UserRole() < meta.Role {
+		return ErrPermissionDenied
+	}
+	if meta.Permission != "" && !d.permissions().Allowed(caller.UserRole(), meta.Permission) {
+		return ErrPermissionDenied
+	}
+	if meta.RateLimit > 0 {
+		var uid uint32
+		if authed {
+			uid = caller.UserID()
+		}
+		if !d.allow(limiterKey{method: m, uid: uid}, meta, time.Now()) {
+			return ErrRateLimited
+		}
+	}
+	return nil
+}
+
+// Invoke checks the caller and validates the request by its field rules,
+// then invokes the method within its timeout.
+// the handler keeps running in background after it timeouts,
+// a context.Context ctx is canceled to tell it to give up, if the handler takes a plain context.Context.
+// a server stream is not timed here, its Stream is bounded by the timeout instead.
+func (d *Dispatcher) Invoke(m *Method, caller Caller, ctx interface{}, req interface{}) (interface{}, error) {
+	if err := d.Check(m, caller); err != nil {
+		return nil, err
+	}
+	if msg, ok := req.(proto.Message); ok {
+		if err := validate.Validate(msg); err != nil {
+			return nil, err
+		}
+	}
+	if m.Meta == nil || m.Meta.Timeout <= 0 || m.Style == StyleServerStream {
+		return m.Invoke(ctx, req)
+	}
+
+	timer := time.NewTimer(m.Meta.Timeout)
+	defer timer.Stop()
+
+	if c, ok := ctx.(context.Context); ok && m.takesContext() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(c, m.Meta.Timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		resp interface{}
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := m.Invoke(ctx, req)
+		done <- result{resp, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.resp, r.err
+	case <-timer.C:
+		return nil, ErrTimeout
+	}
+}
+
+func (d *Dispatcher) permissions() *Permissions {
+	if d.Permissions == nil {
+		return DefaultPermissions
+	}
+	return d.Permissions
+}
+
+func (d *Dispatcher) allow(key limiterKey, meta *MethodMeta, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	b, has := d.limiters[key]
+	if !has {
+		if len(d.limiters) >= maxIdleLimiters {
+			d.pruneLocked(now)
+		}
+		b = &tokenBucket{tokens: float64(meta.RateBurst), last: now}
+		d.limiters[key] = b
+	}
+	return b.take(float64(meta.RateLimit), float64(meta.RateBurst), now)
+}
+
+// pruneLocked drops the buckets which are refilled, they are the same as new ones.
+func (d *Dispatcher) pruneLocked(now time.Time) {
+	for k, b := range d.limiters {
+		rate, burst := float64(k.method.Meta.RateLimit), float64(k.method.Meta.RateBurst)
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(d.limiters, k)
+		}
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(rate, burst float64, now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
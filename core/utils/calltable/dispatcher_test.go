@@ -0,0 +1,86 @@
+package calltable
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testCaller struct {
+	uid, role uint32
+}
+
+func (c *testCaller) UserID() uint32   { return c.uid }
+func (c *testCaller) UserRole() uint32 { return c.role }
+
+func TestDispatcherCheck(t *testing.T) {
+	d := NewDispatcher()
+	m := NewMethod(styleHandler{}.GRpc)
+	m.Meta = &MethodMeta{AuthRequired: true, Role: 100, RateLimit: 1, RateBurst: 2}
+
+	if err := d.Check(m, nil); err != ErrUnauthenticated {
+		t.Fatalf("anonymous caller: %v", err)
+	}
+	if err := d.Check(m, &testCaller{uid: 1, role: 1}); err != ErrPermissionDenied {
+		t.Fatalf("user caller: %v", err)
+	}
+	admin := &testCaller{uid: 2, role: 100}
+	for i := 0; i < 2; i++ {
+		if err := d.Check(m, admin); err != nil {
+			t.Fatalf("admin call %d: %v", i, err)
+		}
+	}
+	if err := d.Check(m, admin); err != ErrRateLimited {
+		t.Fatalf("burst exceeded: %v", err)
+	}
+}
+
+func TestDispatcherPermission(t *testing.T) {
+	d := NewDispatcher()
+	m := NewMethod(styleHandler{}.GRpc)
+	m.Meta = &MethodMeta{Permission: "mail.send"}
+
+	if err := d.Check(m, nil); err != ErrUnauthenticated {
+		t.Fatalf("anonymous caller: %v", err)
+	}
+	if err := d.Check(m, &testCaller{uid: 1, role: 1}); err != ErrPermissionDenied {
+		t.Fatalf("user caller: %v", err)
+	}
+	if err := d.Check(m, &testCaller{uid: 2, role: 100}); err != nil {
+		t.Fatalf("admin caller by the default permissions: %v", err)
+	}
+
+	p, err := ParsePermissions([]byte(`{"RoleUser": ["mail.recv"], "50": ["mail.*"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Permissions = p
+	if err := d.Check(m, &testCaller{uid: 1, role: 1}); err != ErrPermissionDenied {
+		t.Fatalf("user caller: %v", err)
+	}
+	// the larger role has the permissions of the smaller ones.
+	if err := d.Check(m, &testCaller{uid: 3, role: 60}); err != nil {
+		t.Fatalf("granted by the prefix: %v", err)
+	}
+	if !p.Allowed(60, "mail.recv") || p.Allowed(60, "mailbox.send") || p.Allowed(100, "giftcode.list") {
+		t.Fatal("wrong permissions")
+	}
+	if _, err := ParsePermissions([]byte(`{"RoleUnknown": ["*"]}`)); err == nil {
+		t.Fatal("an unknown role is parsed")
+	}
+}
+
+func TestDispatcherTimeout(t *testing.T) {
+	d := NewDispatcher()
+	m := NewMethod(func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	m.Meta = &MethodMeta{Timeout: 10 * time.Millisecond}
+
+	if _, err := d.Invoke(m, nil, context.Background(), wrapperspb.String("a")); err == nil {
+		t.Fatal("expected timeout")
+	}
+}
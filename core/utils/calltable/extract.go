@@ -1,25 +1,122 @@
 package calltable
 
 import (
+	"context"
 	"reflect"
+	"runtime"
+	"strings"
 
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/ajenpan/surf/core/utils/msgid"
+)
+
+var (
+	ctxType   = reflect.TypeOf((*context.Context)(nil)).Elem()
+	pbMsgType = reflect.TypeOf((*proto.Message)(nil)).Elem()
 )
 
+func isProtoPtr(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Implements(pbMsgType)
+}
+
+// StyleOf detects the MethodStyle of a func type, the receiver must be bound already.
+//
+//	func (any, *Req) [error]                     StyleAsync
+//	func (any, *Req) (*Resp, error)              StyleRequest
+//	func (any, *Req, *Resp) error                StyleMicro
+//	func (context.Context, *Req) (*Resp, error)  StyleGRpc
+func StyleOf(t reflect.Type) (MethodStyle, bool) {
+	if t.Kind() != reflect.Func {
+		return 0, false
+	}
+	switch t.NumIn() {
+	case 2:
+		if !isProtoPtr(t.In(1)) {
+			return 0, false
+		}
+		switch t.NumOut() {
+		case 0:
+			return StyleAsync, true
+		case 1:
+			return StyleAsync, t.Out(0) == errType
+		case 2:
+			if !isProtoPtr(t.Out(0)) || t.Out(1) != errType {
+				return 0, false
+			}
+			if t.In(0) == ctxType {
+				return StyleGRpc, true
+			}
+			return StyleRequest, true
+		}
+	case 3:
+		if !isProtoPtr(t.In(1)) || !isProtoPtr(t.In(2)) {
+			return 0, false
+		}
+		if t.NumOut() == 1 && t.Out(0) == errType {
+			return StyleMicro, true
+		}
+	}
+	return 0, false
+}
+
+// NewMethod creates a method from a func or a bound method value, like h.Login.
+// returns nil if the signature is not in any MethodStyle.
+func NewMethod(f interface{}) *Method {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func {
+		return nil
+	}
+	return newMethod(funcName(fv), fv)
+}
+
+// funcName turns the runtime name like "pkg.(*Auth).Login-fm" into "Login".
+func funcName(fv reflect.Value) string {
+	fn := runtime.FuncForPC(fv.Pointer())
+	if fn == nil {
+		return ""
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+func newMethod(name string, fv reflect.Value) *Method {
+	if !fv.IsValid() {
+		return nil
+	}
+	t := fv.Type()
+	style, ok := StyleOf(t)
+	if !ok {
+		return nil
+	}
+	m := &Method{
+		FuncName:    name,
+		Func:        fv,
+		Style:       style,
+		RequestType: t.In(1).Elem(),
+	}
+	switch style {
+	case StyleRequest, StyleGRpc:
+		m.ResponseType = t.Out(0).Elem()
+	case StyleMicro:
+		m.ResponseType = t.In(2).Elem()
+	}
+	m.InitPool()
+	return m
+}
+
+// ExtractParseGRpcMethod extracts the methods of h named after the rpcs of the services.
+// the methods may be in any MethodStyle.
 func ExtractParseGRpcMethod(ms protoreflect.ServiceDescriptors, h interface{}) *CallTable[string] {
 	refh := reflect.TypeOf(h)
+	refv := reflect.ValueOf(h)
 
 	ret := NewCallTable[string]()
 
-	// ctxType := reflect.TypeOf((*context.Context)(nil)).Elem()
-	pbMsgType := reflect.TypeOf((*proto.Message)(nil)).Elem()
-	errType := reflect.TypeOf((*error)(nil)).Elem()
-
 	for i := 0; i < ms.Len(); i++ {
-		service := ms.Get(i)
-		methods := service.Methods()
-		// svrName := string(service.Name())
+		methods := ms.Get(i).Methods()
 
 		for j := 0; j < methods.Len(); j++ {
 			rpcMethod := methods.Get(j)
@@ -29,48 +126,43 @@ func ExtractParseGRpcMethod(ms protoreflect.ServiceDescriptors, h interface{}) *
 			if !has {
 				continue
 			}
-			methodt := methodv.Type
 
-			if methodt.NumIn() != 2 || methodt.NumOut() != 2 {
-				continue
-			}
-			// if method.In(0) != ctxType {
-			// 	continue
-			// }
-			if !methodt.In(1).Implements(pbMsgType) {
+			m := newMethod(rpcMethodName, refv.Method(methodv.Index))
+			if m == nil {
 				continue
 			}
-			if !methodt.Out(0).Implements(pbMsgType) {
+			if rpcMethod.IsStreamingClient() {
 				continue
 			}
-			if methodt.Out(1) != errType {
-				continue
+			if rpcMethod.IsStreamingServer() {
+				if m.Style != StyleAsync {
+					continue
+				}
+				m.Style = StyleServerStream
+				m.ResponseType = messageType(rpcMethod.Output())
 			}
-			// epn := strings.Join([]string{svrName, rpcMethodName}, "/")
-			reqType := methodt.In(1).Elem()
-			respType := methodt.Out(0).Elem()
-
-			m := &Method{
-				FuncName:     rpcMethodName,
-				Func:         methodv.Func,
-				Style:        StyleGRpc,
-				RequestType:  reqType,
-				ResponseType: respType,
-			}
-			m.InitPool()
-
+			m.Meta = MethodMetaOf(rpcMethod)
 			ret.list[rpcMethodName] = m
 		}
 	}
 	return ret
 }
 
+// messageType returns the go type of the registered message, nil if it's not registered.
+func messageType(md protoreflect.MessageDescriptor) reflect.Type {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName())
+	if err != nil {
+		return nil
+	}
+	return reflect.TypeOf(mt.Zero().Interface()).Elem()
+}
+
 func ExtractAsyncMethod(ms protoreflect.MessageDescriptors, h interface{}) *CallTable[string] {
 	const MethodPrefix string = "On"
 	refh := reflect.TypeOf(h)
+	refv := reflect.ValueOf(h)
 
 	ret := NewCallTable[string]()
-	pbMsgType := reflect.TypeOf((*proto.Message)(nil)).Elem()
 
 	for i := 0; i < ms.Len(); i++ {
 		msg := ms.Get(i)
@@ -80,25 +172,11 @@ func ExtractAsyncMethod(ms protoreflect.MessageDescriptors, h interface{}) *Call
 			continue
 		}
 
-		if method.Type.NumIn() != 3 {
-			continue
-		}
-
-		reqMsgType := method.Type.In(2)
-		if reqMsgType.Kind() != reflect.Ptr {
+		m := newMethod(method.Name, refv.Method(method.Index))
+		if m == nil || m.Style != StyleAsync {
 			continue
 		}
-		if !reqMsgType.Implements(pbMsgType) {
-			continue
-		}
-
-		m := &Method{
-			FuncName:    method.Name,
-			Func:        method.Func,
-			Style:       StyleAsync,
-			RequestType: reqMsgType.Elem(),
-		}
-		m.InitPool()
+		m.Meta = MessageMetaOf(msg)
 		ret.list[msgName] = m
 	}
 	return ret
@@ -116,24 +194,17 @@ func ExtractProtoFile(fd protoreflect.FileDescriptor, handler interface{}) *Call
 	return ret
 }
 
+// GetMessageMsgID returns the msgid of the nested MSGID.ID enum of the message, 0 if it has none.
 func GetMessageMsgID(msg protoreflect.MessageDescriptor) uint32 {
-	MSGIDDesc := msg.Enums().ByName("MSGID")
-	if MSGIDDesc == nil {
-		return 0
-	}
-	IDDesc := MSGIDDesc.Values().ByName("ID")
-	if IDDesc == nil {
-		return 0
-	}
-	return uint32(IDDesc.Number())
+	return msgid.Of(msg)
 }
 
 func ExtractAsyncMethodByMsgID(ms protoreflect.MessageDescriptors, h interface{}) *CallTable[uint32] {
 	const MethodPrefix string = "On"
 	hvalue := reflect.TypeOf(h)
+	refv := reflect.ValueOf(h)
 
 	ret := NewCallTable[uint32]()
-	pbMsgType := reflect.TypeOf((*proto.Message)(nil)).Elem()
 
 	for i := 0; i < ms.Len(); i++ {
 		msg := ms.Get(i)
@@ -160,8 +231,10 @@ func ExtractAsyncMethodByMsgID(ms protoreflect.MessageDescriptors, h interface{}
 			continue
 		}
 		m := &Method{
-			Func:        method.Func,
+			FuncName:    method.Name,
+			Func:        refv.Method(method.Index),
 			Style:       StyleAsync,
+			Meta:        MessageMetaOf(msg),
 			RequestType: reqMsgType.Elem(),
 		}
 		m.InitPool()
@@ -0,0 +1,78 @@
+package calltable
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+// MethodMeta is declared in the .proto files by the (core.method) option of a rpc,
+// or the (core.handler) option of an async message, and enforced by the Dispatcher.
+type MethodMeta struct {
+	AuthRequired bool
+	// Role is the minimum role of the caller.
+	Role uint32
+	// Permission must be granted to the role of the caller by the Permissions of the Dispatcher.
+	Permission string
+
+	Timeout time.Duration
+
+	// RateLimit is the requests per second of each caller, 0 means no limit.
+	RateLimit uint32
+	RateBurst uint32
+
+	HttpPath string
+	HttpVerb string
+}
+
+// MethodMetaOf reads the (core.method) option of the rpc, returns nil if it is not declared.
+func MethodMetaOf(md protoreflect.MethodDescriptor) *MethodMeta {
+	if md == nil || !proto.HasExtension(md.Options(), msgcore.E_Method) {
+		return nil
+	}
+	return newMethodMeta(proto.GetExtension(md.Options(), msgcore.E_Method).(*msgcore.MethodOptions))
+}
+
+// MessageMetaOf reads the (core.handler) option of the message, returns nil if it is not declared.
+func MessageMetaOf(md protoreflect.MessageDescriptor) *MethodMeta {
+	if md == nil || !proto.HasExtension(md.Options(), msgcore.E_Handler) {
+		return nil
+	}
+	return newMethodMeta(proto.GetExtension(md.Options(), msgcore.E_Handler).(*msgcore.MethodOptions))
+}
+
+func newMethodMeta(opts *msgcore.MethodOptions) *MethodMeta {
+	if opts == nil {
+		return nil
+	}
+	ret := &MethodMeta{
+		AuthRequired: opts.AuthRequired || opts.Role != msgcore.Role_RoleAny || opts.Permission != "",
+		Role:         uint32(opts.Role),
+		Permission:   opts.Permission,
+		Timeout:      time.Duration(opts.TimeoutMs) * time.Millisecond,
+		RateLimit:    opts.GetRateLimit().GetRate(),
+		RateBurst:    opts.GetRateLimit().GetBurst(),
+		HttpPath:     opts.HttpPath,
+		HttpVerb:     opts.HttpVerb,
+	}
+	if ret.RateBurst == 0 {
+		ret.RateBurst = ret.RateLimit
+	}
+	return ret
+}
+
+// HttpRoute returns the declared http path and verb of the method,
+// the path is defPath if it is not declared, an empty verb accepts any.
+func (m *Method) HttpRoute(defPath string) (string, string) {
+	path, verb := defPath, ""
+	if m.Meta != nil {
+		verb = m.Meta.HttpVerb
+		if len(m.Meta.HttpPath) > 0 {
+			path = m.Meta.HttpPath
+		}
+	}
+	return path, verb
+}
@@ -1,8 +1,12 @@
 package calltable
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"sync"
+
+	"google.golang.org/protobuf/proto"
 )
 
 type MethodStyle int
@@ -12,14 +16,54 @@ const (
 	StyleRequest MethodStyle = iota // func (any, proto.Message) (proto.Message, error)
 	StyleMicro   MethodStyle = iota // func (context.Context, proto.Message, proto.Message) ( error)
 	StyleGRpc    MethodStyle = iota // func (context.Context, proto.Message) (proto.Message, error)
+	// StyleServerStream is declared by `returns (stream X)` in proto,
+	// func (any, proto.Message) error, which sends the responses through the Stream of its context.
+	StyleServerStream MethodStyle = iota
 )
 
+// Invoker is a typed dispatch stub generated by protoc-gen-surf.
+// ctx is the first argument passed to the handler, req is the request message.
+type Invoker func(ctx interface{}, req interface{}) (interface{}, error)
+
+var errType = reflect.TypeOf((*error)(nil)).Elem()
+
+var ErrInvalidContext = errors.New("invalid call context")
+var ErrInvalidRequest = errors.New("invalid request message")
+var ErrInvalidMethod = errors.New("invalid method")
+
+// Responder is implemented by the call contexts of async handlers,
+// which answer by ctx.Response instead of returning the response, such as core.Context.
+// Invoke takes what the handler responded through the context as its result.
+type Responder interface {
+	Responded() (resp interface{}, err error, ok bool)
+}
+
+// Stream sends the responses of a server streaming method.
+type Stream interface {
+	// Context is canceled when the stream is closed by either side, or timeouts.
+	Context() context.Context
+	// Send blocks until the peer has credits for the message.
+	Send(msg proto.Message) error
+}
+
+// Streamer is implemented by the call contexts which carry a Stream,
+// its Stream is nil for the unary calls.
+type Streamer interface {
+	Stream() Stream
+}
+
 type Method struct {
 	Func     reflect.Value
 	FuncName string
 
+	// Invoker is used instead of Func if it is set.
+	Invoker Invoker
+
 	Style MethodStyle
 
+	// Meta is declared by the proto options, nil means no restriction.
+	Meta *MethodMeta
+
 	RequestType  reflect.Type
 	ResponseType reflect.Type
 
@@ -38,6 +82,10 @@ func (m *Method) InitPool() {
 }
 
 func (m *Method) Call(args ...interface{}) []reflect.Value {
+	if m.Invoker != nil {
+		return m.callInvoker(args...)
+	}
+
 	argc := len(args)
 
 	values := make([]reflect.Value, argc)
@@ -47,6 +95,92 @@ func (m *Method) Call(args ...interface{}) []reflect.Value {
 	return m.Func.Call(values)
 }
 
+// Invoke calls the method in any MethodStyle, and returns the response and the error.
+// An async handler which answered through a Responder context
+// returns what it responded, otherwise the response of an async handler is nil.
+func (m *Method) Invoke(ctx interface{}, req interface{}) (interface{}, error) {
+	if m.Invoker != nil {
+		resp, err := m.Invoker(ctx, req)
+		if m.Style == StyleAsync {
+			return responded(ctx, resp, err)
+		}
+		return resp, err
+	}
+
+	if !m.Func.IsValid() {
+		return nil, ErrInvalidMethod
+	}
+
+	switch m.Style {
+	case StyleServerStream:
+		results := m.Func.Call([]reflect.Value{argValue(ctx, m.Func.Type().In(0)), argValue(req, m.Func.Type().In(1))})
+		if len(results) > 0 {
+			return nil, resultError(results[len(results)-1])
+		}
+		return nil, nil
+	case StyleRequest, StyleGRpc:
+		results := m.Func.Call([]reflect.Value{argValue(ctx, m.Func.Type().In(0)), argValue(req, m.Func.Type().In(1))})
+		return resultValue(results[0]), resultError(results[1])
+	case StyleMicro:
+		resp := m.NewResponse()
+		results := m.Func.Call([]reflect.Value{argValue(ctx, m.Func.Type().In(0)), argValue(req, m.Func.Type().In(1)), reflect.ValueOf(resp)})
+		if err := resultError(results[0]); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	default:
+		var err error
+		results := m.Func.Call([]reflect.Value{argValue(ctx, m.Func.Type().In(0)), argValue(req, m.Func.Type().In(1))})
+		if len(results) > 0 {
+			err = resultError(results[len(results)-1])
+		}
+		return responded(ctx, nil, err)
+	}
+}
+
+// takesContext tells the handler takes a plain context.Context, which can be replaced by a derived one.
+// The generated invokers take it except the async ones.
+func (m *Method) takesContext() bool {
+	if m.Invoker != nil {
+		return m.Style != StyleAsync
+	}
+	return m.Func.IsValid() && m.Func.Type().NumIn() > 0 && m.Func.Type().In(0) == ctxType
+}
+
+func responded(ctx interface{}, resp interface{}, err error) (interface{}, error) {
+	if r, ok := ctx.(Responder); ok {
+		if rresp, rerr, ok := r.Responded(); ok {
+			return rresp, rerr
+		}
+	}
+	return resp, err
+}
+
+// argValue converts the argument to the value of the parameter type, nil becomes zero value.
+func argValue(v interface{}, t reflect.Type) reflect.Value {
+	if v == nil {
+		return reflect.Zero(t)
+	}
+	return reflect.ValueOf(v)
+}
+
+func resultValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	return v.Interface()
+}
+
+func resultError(v reflect.Value) error {
+	if v.Type() != errType || v.IsNil() {
+		return nil
+	}
+	return v.Interface().(error)
+}
+
 func (m *Method) NewRequest() interface{} {
 	return reflect.New(m.RequestType).Interface()
 }
@@ -82,3 +216,45 @@ func (m *Method) PutResponse(resp interface{}) {
 	}
 	m.respPool.Put(resp)
 }
+
+// callInvoker calls the generated stub, and returns the results in the shape of Func.Call.
+func (m *Method) callInvoker(args ...interface{}) []reflect.Value {
+	var ctx, req interface{}
+	if len(args) > 0 {
+		ctx = args[0]
+	}
+	if len(args) > 1 {
+		req = args[1]
+	}
+
+	resp, err := m.Invoker(ctx, req)
+
+	errv := reflect.Zero(errType)
+	if err != nil {
+		errv = reflect.ValueOf(&err).Elem()
+	}
+
+	switch m.Style {
+	case StyleRequest, StyleGRpc:
+		var respv reflect.Value
+		if resp != nil {
+			respv = reflect.ValueOf(resp)
+		} else if m.ResponseType != nil {
+			respv = reflect.Zero(reflect.PointerTo(m.ResponseType))
+		} else {
+			respv = reflect.Zero(reflect.TypeOf((*proto.Message)(nil)).Elem())
+		}
+		return []reflect.Value{respv, errv}
+	case StyleMicro:
+		if len(args) > 2 && resp != nil {
+			dst, ok1 := args[2].(proto.Message)
+			src, ok2 := resp.(proto.Message)
+			if ok1 && ok2 {
+				proto.Merge(dst, src)
+			}
+		}
+		return []reflect.Value{errv}
+	default:
+		return []reflect.Value{errv}
+	}
+}
@@ -0,0 +1,93 @@
+package calltable
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type responder struct {
+	resp interface{}
+	err  error
+	done bool
+}
+
+func (r *responder) Response(resp interface{}, err error) {
+	r.resp, r.err, r.done = resp, err, true
+}
+
+func (r *responder) Responded() (interface{}, error, bool) {
+	return r.resp, r.err, r.done
+}
+
+type styleHandler struct{}
+
+func (styleHandler) Async(ctx *responder, in *wrapperspb.StringValue) {
+	ctx.Response(wrapperspb.String("async:"+in.Value), nil)
+}
+
+func (styleHandler) AsyncErr(ctx interface{}, in *wrapperspb.StringValue) error {
+	return errors.New("async failed")
+}
+
+func (styleHandler) Request(ctx interface{}, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String("request:" + in.Value), nil
+}
+
+func (styleHandler) Micro(ctx context.Context, in *wrapperspb.StringValue, out *wrapperspb.StringValue) error {
+	out.Value = "micro:" + in.Value
+	return nil
+}
+
+func (styleHandler) GRpc(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+	return wrapperspb.String("grpc:" + in.Value), nil
+}
+
+func TestMethodInvoke(t *testing.T) {
+	h := styleHandler{}
+	cases := []struct {
+		f     interface{}
+		ctx   interface{}
+		style MethodStyle
+		want  string
+		err   bool
+	}{
+		{h.Async, &responder{}, StyleAsync, "async:a", false},
+		{h.AsyncErr, nil, StyleAsync, "", true},
+		{h.Request, nil, StyleRequest, "request:a", false},
+		{h.Micro, context.Background(), StyleMicro, "micro:a", false},
+		{h.GRpc, context.Background(), StyleGRpc, "grpc:a", false},
+	}
+
+	for i, c := range cases {
+		m := NewMethod(c.f)
+		if m == nil {
+			t.Fatalf("case %d: style is not detected", i)
+		}
+		if m.Style != c.style {
+			t.Fatalf("case %d: style %d, want %d", i, m.Style, c.style)
+		}
+		resp, err := m.Invoke(c.ctx, wrapperspb.String("a"))
+		if (err != nil) != c.err {
+			t.Fatalf("case %d: err %v", i, err)
+		}
+		if c.err {
+			continue
+		}
+		if got := resp.(*wrapperspb.StringValue).Value; got != c.want {
+			t.Fatalf("case %d: resp %s, want %s", i, got, c.want)
+		}
+	}
+
+	if NewMethod(func(int) {}) != nil {
+		t.Fatal("invalid signature should not be a method")
+	}
+}
+
+func TestNewMethodName(t *testing.T) {
+	if m := NewMethod(styleHandler{}.GRpc); m.FuncName != "GRpc" {
+		t.Fatalf("func name: %s", m.FuncName)
+	}
+}
@@ -0,0 +1,100 @@
+package calltable
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+// Permissions maps the roles to the permissions granted to them, so the methods declare
+// the permissions they need instead of the roles. A role has the permissions of the smaller roles too.
+// A granted permission is exact like "mail.send", a prefix like "mail.*", or "*" for all.
+type Permissions struct {
+	roles  []uint32
+	grants map[uint32][]string
+}
+
+// DefaultPermissions grants all the permissions to the admins, when the dispatcher has no Permissions.
+var DefaultPermissions = NewPermissions(map[uint32][]string{
+	uint32(msgcore.Role_RoleAdmin): {"*"},
+})
+
+func NewPermissions(grants map[uint32][]string) *Permissions {
+	p := &Permissions{grants: make(map[uint32][]string, len(grants))}
+	for role, perms := range grants {
+		p.roles = append(p.roles, role)
+		p.grants[role] = append([]string(nil), perms...)
+	}
+	sort.Slice(p.roles, func(i, j int) bool { return p.roles[i] < p.roles[j] })
+	return p
+}
+
+// ParsePermissions reads the json object of the roles to their permissions, a role is
+// the name of core.Role like "RoleAdmin", or a number: {"RoleAdmin": ["*"], "50": ["mail.list"]}.
+func ParsePermissions(raw []byte) (*Permissions, error) {
+	named := map[string][]string{}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return nil, err
+	}
+	grants := make(map[uint32][]string, len(named))
+	for name, perms := range named {
+		role, err := parseRole(name)
+		if err != nil {
+			return nil, err
+		}
+		grants[role] = append(grants[role], perms...)
+	}
+	return NewPermissions(grants), nil
+}
+
+// LoadPermissions reads the file of ParsePermissions.
+func LoadPermissions(path string) (*Permissions, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := ParsePermissions(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse permissions %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func parseRole(name string) (uint32, error) {
+	if v, has := msgcore.Role_value[name]; has {
+		return uint32(v), nil
+	}
+	v, err := strconv.ParseUint(name, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("unknown role %q", name)
+	}
+	return uint32(v), nil
+}
+
+// Allowed tells the role is granted the permission.
+func (p *Permissions) Allowed(role uint32, permission string) bool {
+	for _, r := range p.roles {
+		if r > role {
+			break
+		}
+		for _, granted := range p.grants[r] {
+			if matchPermission(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchPermission(granted string, permission string) bool {
+	if granted == "*" || granted == permission {
+		return true
+	}
+	prefix, found := strings.CutSuffix(granted, "*")
+	return found && strings.HasPrefix(permission, prefix)
+}
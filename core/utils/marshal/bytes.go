@@ -2,8 +2,12 @@ package marshal
 
 import (
 	"errors"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
+// BytesMarshaler passes the raw bytes through,
+// a google.protobuf.BytesValue message is taken as its value.
 type BytesMarshaler struct{}
 
 var ErrInvalidMessage = errors.New("invalid message")
@@ -14,6 +18,8 @@ func (n BytesMarshaler) Marshal(v interface{}) ([]byte, error) {
 		return *ve, nil
 	case []byte:
 		return ve, nil
+	case *wrapperspb.BytesValue:
+		return ve.GetValue(), nil
 	}
 	return nil, ErrInvalidMessage
 }
@@ -22,6 +28,10 @@ func (n BytesMarshaler) Unmarshal(d []byte, v interface{}) error {
 	switch ve := v.(type) {
 	case *[]byte:
 		*ve = d
+		return nil
+	case *wrapperspb.BytesValue:
+		ve.Value = d
+		return nil
 	}
 	return ErrInvalidMessage
 }
@@ -29,3 +39,7 @@ func (n BytesMarshaler) Unmarshal(d []byte, v interface{}) error {
 func (n BytesMarshaler) String() string {
 	return "bytes"
 }
+
+func (BytesMarshaler) ContentType(_ interface{}) string {
+	return "application/octet-stream"
+}
@@ -0,0 +1,106 @@
+package marshal
+
+import (
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Codecs selects the marshalers by the media types of Content-Type and Accept.
+type Codecs struct {
+	Default Marshaler
+	byType  map[string]Marshaler
+}
+
+func NewCodecs(def Marshaler) *Codecs {
+	return &Codecs{
+		Default: def,
+		byType:  make(map[string]Marshaler),
+	}
+}
+
+// DefaultCodecs serves jsonpb by default, and protobuf and bytes on demand.
+func DefaultCodecs() *Codecs {
+	jsonpb := &JSONPb{}
+	pb := &ProtoMarshaler{}
+	c := NewCodecs(jsonpb)
+	c.Register("application/json", jsonpb)
+	c.Register("application/protobuf", pb)
+	c.Register("application/x-protobuf", pb)
+	c.Register("application/octet-stream", BytesMarshaler{})
+	return c
+}
+
+func (c *Codecs) Register(mediaType string, m Marshaler) {
+	c.byType[strings.ToLower(mediaType)] = m
+}
+
+// ForContentType returns the marshaler of the request body, an empty contentType is the default.
+func (c *Codecs) ForContentType(contentType string) (Marshaler, bool) {
+	if len(contentType) == 0 {
+		return c.Default, true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	m, has := c.byType[mt]
+	return m, has
+}
+
+// ForAccept returns the most preferred marshaler of the Accept header,
+// an empty header or a wildcard is the default.
+func (c *Codecs) ForAccept(accept string) (Marshaler, bool) {
+	if len(strings.TrimSpace(accept)) == 0 {
+		return c.Default, true
+	}
+
+	type candidate struct {
+		mediaType string
+		q         float64
+	}
+	var list []candidate
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, has := params["q"]; has {
+			if q, err = strconv.ParseFloat(qs, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			list = append(list, candidate{mt, q})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	for _, cand := range list {
+		if cand.mediaType == "*/*" {
+			return c.Default, true
+		}
+		if m, has := c.byType[cand.mediaType]; has {
+			return m, true
+		}
+		if strings.HasSuffix(cand.mediaType, "/*") {
+			prefix := strings.TrimSuffix(cand.mediaType, "*")
+			if strings.HasPrefix(c.Default.ContentType(nil), prefix) {
+				return c.Default, true
+			}
+			types := make([]string, 0, len(c.byType))
+			for mt := range c.byType {
+				types = append(types, mt)
+			}
+			sort.Strings(types)
+			for _, mt := range types {
+				if strings.HasPrefix(mt, prefix) {
+					return c.byType[mt], true
+				}
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,176 @@
+package marshal
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// UnmarshalQuery binds the query parameters to the fields of the message.
+// the parameters are named as the json or proto names of the fields,
+// a dotted name like "page.size" sets a field of a nested message,
+// and a repeated field takes all values of the parameter.
+func UnmarshalQuery(values url.Values, v interface{}) error {
+	pb, ok := v.(proto.Message)
+	if !ok {
+		return ErrInvalidProtobuf
+	}
+	msg := pb.ProtoReflect()
+	for key, vals := range values {
+		if err := bindQuery(msg, strings.Split(key, "."), vals); err != nil {
+			return fmt.Errorf("query %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// MarshalQuery is the reverse of UnmarshalQuery, the populated fields are named by their json names.
+func MarshalQuery(v interface{}) (url.Values, error) {
+	pb, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrInvalidProtobuf
+	}
+	values := url.Values{}
+	if err := encodeQuery(pb.ProtoReflect(), "", values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func encodeQuery(msg protoreflect.Message, prefix string, values url.Values) error {
+	var err error
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		key := prefix + fd.JSONName()
+		switch {
+		case fd.IsMap():
+			err = fmt.Errorf("query %s: map field is not supported", key)
+		case fd.Message() != nil:
+			if fd.IsList() {
+				err = fmt.Errorf("query %s: repeated message is not supported", key)
+			} else {
+				err = encodeQuery(v.Message(), key+".", values)
+			}
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				values.Add(key, formatScalar(fd, list.Get(i)))
+			}
+		default:
+			values.Set(key, formatScalar(fd, v))
+		}
+		return err == nil
+	})
+	return err
+}
+
+func formatScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BytesKind:
+		return base64.URLEncoding.EncodeToString(v.Bytes())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	}
+	return v.String()
+}
+
+func fieldByName(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	return fields.ByName(protoreflect.Name(name))
+}
+
+func bindQuery(msg protoreflect.Message, path []string, vals []string) error {
+	fd := fieldByName(msg.Descriptor(), path[0])
+	if fd == nil {
+		return fmt.Errorf("unknown field %s", path[0])
+	}
+	if fd.IsMap() {
+		return fmt.Errorf("map field is not supported")
+	}
+
+	if fd.Message() != nil {
+		if fd.IsList() || len(path) < 2 {
+			return fmt.Errorf("message field must be set by its sub fields")
+		}
+		return bindQuery(msg.Mutable(fd).Message(), path[1:], vals)
+	}
+	if len(path) > 1 {
+		return fmt.Errorf("%s is not a message", path[0])
+	}
+
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, s := range vals {
+			v, err := parseScalar(fd, s)
+			if err != nil {
+				return err
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+
+	if len(vals) == 0 {
+		return nil
+	}
+	v, err := parseScalar(fd, vals[len(vals)-1])
+	if err != nil {
+		return err
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(n)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(n), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(n)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(n), err
+	case protoreflect.FloatKind:
+		n, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(n)), err
+	case protoreflect.DoubleKind:
+		n, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(n), err
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			b, err = base64.URLEncoding.DecodeString(s)
+		}
+		return protoreflect.ValueOfBytes(b), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown enum value %s", s)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
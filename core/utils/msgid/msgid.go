@@ -0,0 +1,243 @@
+// Package msgid keeps the msgids of the messages, which are declared as the nested MSGID.ID enums,
+// and detects the ids or names claimed twice and the ids out of the ranges of their packages.
+package msgid
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+var (
+	ErrDuplicateID   = errors.New("duplicate msgid")
+	ErrDuplicateName = errors.New("duplicate msgid name")
+	ErrOutOfRange    = errors.New("msgid out of range")
+	ErrRangeOverlap  = errors.New("msgid range overlap")
+	ErrUnknownID     = errors.New("unknown msgid")
+)
+
+// Of returns the msgid of the message, 0 if it declares none.
+func Of(md protoreflect.MessageDescriptor) uint32 {
+	enum := md.Enums().ByName("MSGID")
+	if enum == nil {
+		return 0
+	}
+	id := enum.Values().ByName("ID")
+	if id == nil {
+		return 0
+	}
+	return uint32(id.Number())
+}
+
+// Range is the msgids owned by a proto package, both bounds are inclusive.
+type Range struct {
+	Owner string
+	Min   uint32
+	Max   uint32
+}
+
+func (r Range) Contains(id uint32) bool {
+	return id >= r.Min && id <= r.Max
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("%s[%d,%d]", r.Owner, r.Min, r.Max)
+}
+
+type Registry struct {
+	mu     sync.RWMutex
+	byID   map[uint32]protoreflect.MessageType
+	byName map[protoreflect.FullName]uint32
+	ranges []Range
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		byID:   make(map[uint32]protoreflect.MessageType),
+		byName: make(map[protoreflect.FullName]uint32),
+	}
+}
+
+// Default is the registry of the process, it is filled by Load.
+var Default = NewRegistry()
+
+// Load registers the messages of all the proto files linked into the process to Default.
+func Load() error {
+	return Default.LoadFiles(protoregistry.GlobalFiles, protoregistry.GlobalTypes)
+}
+
+// Reserve gives the msgids in [min, max] to the owner, which is the proto package of the messages.
+// A range overlapping the one of another owner is refused.
+func (r *Registry) Reserve(owner string, min, max uint32) error {
+	if min == 0 || min > max {
+		return fmt.Errorf("invalid msgid range %s[%d,%d]", owner, min, max)
+	}
+	nr := Range{Owner: owner, Min: min, Max: max}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rg := range r.ranges {
+		if rg == nr {
+			return nil
+		}
+		if rg.Owner != owner && rg.Min <= max && min <= rg.Max {
+			return fmt.Errorf("%w: %v and %v", ErrRangeOverlap, nr, rg)
+		}
+	}
+	for id, mt := range r.byID {
+		if nr.Contains(id) && ownerOf(mt.Descriptor()) != owner {
+			return fmt.Errorf("%w: %d of %s is in %v", ErrOutOfRange, id, mt.Descriptor().FullName(), nr)
+		}
+	}
+	r.ranges = append(r.ranges, nr)
+	sort.Slice(r.ranges, func(i, j int) bool { return r.ranges[i].Min < r.ranges[j].Min })
+	return nil
+}
+
+// Register adds the message type by its msgid, registering the same type again does nothing.
+func (r *Registry) Register(mt protoreflect.MessageType) error {
+	md := mt.Descriptor()
+	id := Of(md)
+	if id == 0 {
+		return fmt.Errorf("%s has no msgid", md.FullName())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if exist, has := r.byID[id]; has {
+		if exist.Descriptor().FullName() == md.FullName() {
+			return nil
+		}
+		return fmt.Errorf("%w: %d of %s and %s", ErrDuplicateID, id, md.FullName(), exist.Descriptor().FullName())
+	}
+	if exist, has := r.byName[md.FullName()]; has {
+		return fmt.Errorf("%w: %s of %d and %d", ErrDuplicateName, md.FullName(), id, exist)
+	}
+
+	owner := ownerOf(md)
+	reserved, inRange := false, false
+	for _, rg := range r.ranges {
+		if rg.Owner != owner {
+			if rg.Contains(id) {
+				return fmt.Errorf("%w: %d of %s is in %v", ErrOutOfRange, id, md.FullName(), rg)
+			}
+			continue
+		}
+		reserved = true
+		inRange = inRange || rg.Contains(id)
+	}
+	if reserved && !inRange {
+		return fmt.Errorf("%w: %d of %s is not in the ranges of %s", ErrOutOfRange, id, md.FullName(), owner)
+	}
+
+	r.byID[id] = mt
+	r.byName[md.FullName()] = id
+	return nil
+}
+
+// LoadFiles reserves the (core.msgid_range) options of the files, then registers their messages which have msgids.
+// All the collisions are reported in the joined error, the messages without collision are registered still.
+func (r *Registry) LoadFiles(files *protoregistry.Files, types *protoregistry.Types) error {
+	var errs []error
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		ranges, _ := proto.GetExtension(fd.Options(), msgcore.E_MsgidRange).([]*msgcore.MsgIDRange)
+		for _, rg := range ranges {
+			if err := r.Reserve(string(fd.Package()), rg.GetMin(), rg.GetMax()); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", fd.Path(), err))
+			}
+		}
+		return true
+	})
+
+	var mds []protoreflect.MessageDescriptor
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		mds = appendMsgIDMessages(mds, fd.Messages())
+		return true
+	})
+	// the order of RangeFiles is random, sort to report the same collisions every time.
+	sort.Slice(mds, func(i, j int) bool { return mds[i].FullName() < mds[j].FullName() })
+
+	for _, md := range mds {
+		mt, err := types.FindMessageByName(md.FullName())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", md.FullName(), err))
+			continue
+		}
+		if err := r.Register(mt); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func appendMsgIDMessages(dst []protoreflect.MessageDescriptor, ms protoreflect.MessageDescriptors) []protoreflect.MessageDescriptor {
+	for i := 0; i < ms.Len(); i++ {
+		md := ms.Get(i)
+		if Of(md) != 0 {
+			dst = append(dst, md)
+		}
+		dst = appendMsgIDMessages(dst, md.Messages())
+	}
+	return dst
+}
+
+func ownerOf(md protoreflect.MessageDescriptor) string {
+	return string(md.ParentFile().Package())
+}
+
+// Type returns the message type of the msgid.
+func (r *Registry) Type(id uint32) (protoreflect.MessageType, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	mt, has := r.byID[id]
+	return mt, has
+}
+
+// Name returns the full name of the message of the msgid, or "" if it is unknown.
+func (r *Registry) Name(id uint32) string {
+	if mt, has := r.Type(id); has {
+		return string(mt.Descriptor().FullName())
+	}
+	return ""
+}
+
+// ID returns the msgid of the registered message.
+func (r *Registry) ID(m proto.Message) (uint32, bool) {
+	return r.IDByName(m.ProtoReflect().Descriptor().FullName())
+}
+
+// IDByName returns the msgid of the registered message by its full name.
+func (r *Registry) IDByName(name protoreflect.FullName) (uint32, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, has := r.byName[name]
+	return id, has
+}
+
+// Unmarshal decodes the body into a new message of the msgid.
+func (r *Registry) Unmarshal(id uint32, body []byte) (proto.Message, error) {
+	mt, has := r.Type(id)
+	if !has {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownID, id)
+	}
+	m := mt.New().Interface()
+	if err := proto.Unmarshal(body, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Ranges returns the reserved ranges ordered by their min.
+func (r *Registry) Ranges() []Range {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Range(nil), r.ranges...)
+}
@@ -0,0 +1,152 @@
+package msgid_test
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/ajenpan/surf/core/utils/msgid"
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+type message struct {
+	name string
+	id   int32
+}
+
+// newFile builds a proto file of the package with the messages, the ranges are its (core.msgid_range) options.
+func newFile(t *testing.T, pkg string, ranges [][2]uint32, msgs ...message) protoreflect.FileDescriptor {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(pkg + ".proto"),
+		Package: proto.String(pkg),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{},
+	}
+	var rgs []*msgcore.MsgIDRange
+	for _, rg := range ranges {
+		rgs = append(rgs, &msgcore.MsgIDRange{Min: rg[0], Max: rg[1]})
+	}
+	proto.SetExtension(fdp.Options, msgcore.E_MsgidRange, rgs)
+
+	for _, m := range msgs {
+		fdp.MessageType = append(fdp.MessageType, &descriptorpb.DescriptorProto{
+			Name: proto.String(m.name),
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("MSGID"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("INVALID_MSGID"), Number: proto.Int32(0)},
+					{Name: proto.String("ID"), Number: proto.Int32(m.id)},
+				},
+			}},
+		})
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fd
+}
+
+func load(t *testing.T, r *msgid.Registry, fds ...protoreflect.FileDescriptor) error {
+	files := &protoregistry.Files{}
+	types := &protoregistry.Types{}
+	for _, fd := range fds {
+		if err := files.RegisterFile(fd); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < fd.Messages().Len(); i++ {
+			if err := types.RegisterMessage(dynamicpb.NewMessageType(fd.Messages().Get(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return r.LoadFiles(files, types)
+}
+
+func TestLoadFiles(t *testing.T) {
+	r := msgid.NewRegistry()
+	err := load(t, r,
+		newFile(t, "game", [][2]uint32{{100, 199}}, message{"JoinRequest", 100}, message{"JoinResponse", 101}),
+		newFile(t, "chat", nil, message{"SayRequest", 300}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Name(101) != "game.JoinResponse" {
+		t.Fatal("wrong name of 101:", r.Name(101))
+	}
+	if id, ok := r.IDByName("chat.SayRequest"); !ok || id != 300 {
+		t.Fatal("wrong id of chat.SayRequest:", id)
+	}
+
+	mt, _ := r.Type(100)
+	body, _ := proto.Marshal(mt.New().Interface())
+	m, err := r.Unmarshal(100, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := r.ID(m); !ok || id != 100 {
+		t.Fatal("wrong id of the unmarshaled message:", id)
+	}
+	if _, err := r.Unmarshal(999, body); !errors.Is(err, msgid.ErrUnknownID) {
+		t.Fatal("expected ErrUnknownID, got", err)
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	r := msgid.NewRegistry()
+	// the messages are registered in the order of their full names, the ones of arena go first.
+	err := load(t, r,
+		newFile(t, "arena", [][2]uint32{{100, 199}}, message{"JoinRequest", 100}, message{"LeaveRequest", 200}),
+		newFile(t, "chat", nil, message{"SayRequest", 100}, message{"HearRequest", 150}, message{"JoinRequest", 300}),
+	)
+	for _, target := range []error{msgid.ErrDuplicateID, msgid.ErrOutOfRange} {
+		if !errors.Is(err, target) {
+			t.Fatalf("expected %v in %v", target, err)
+		}
+	}
+	// the messages without collision are registered still, the same short name in another package is no collision.
+	if r.Name(100) != "arena.JoinRequest" || r.Name(300) != "chat.JoinRequest" {
+		t.Fatal("wrong names of 100 and 300:", r.Name(100), r.Name(300))
+	}
+	for _, id := range []uint32{150, 200} {
+		if _, has := r.Type(id); has {
+			t.Fatal("unexpected registered msgid:", id)
+		}
+	}
+
+	// a message claiming another msgid is refused by its full name.
+	err = load(t, r, newFile(t, "arena", [][2]uint32{{100, 199}}, message{"JoinRequest", 101}))
+	if !errors.Is(err, msgid.ErrDuplicateName) {
+		t.Fatal("expected ErrDuplicateName, got", err)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	r := msgid.NewRegistry()
+	if err := r.Reserve("game", 100, 199); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Reserve("game", 100, 199); err != nil {
+		t.Fatal("reserving the same range again:", err)
+	}
+	if err := r.Reserve("chat", 150, 250); !errors.Is(err, msgid.ErrRangeOverlap) {
+		t.Fatal("expected ErrRangeOverlap, got", err)
+	}
+	if err := r.Reserve("chat", 200, 100); err == nil {
+		t.Fatal("expected an invalid range error")
+	}
+	if err := r.Reserve("chat", 200, 299); err != nil {
+		t.Fatal(err)
+	}
+	if rgs := r.Ranges(); len(rgs) != 2 || rgs[1].Owner != "chat" {
+		t.Fatal("wrong ranges:", rgs)
+	}
+}
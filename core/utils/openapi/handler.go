@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// Handler serves the document as json.
+func Handler(doc *Document) http.HandlerFunc {
+	raw, err := json.MarshalIndent(doc, "", "  ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(raw)
+	}
+}
+
+// DocsHandler serves a page which renders the document fetched from specURL.
+// the page is self-contained, it doesn't load anything except the document.
+func DocsHandler(title string, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		docsTemplate.Execute(w, map[string]string{
+			"Title":   title,
+			"SpecURL": specURL,
+		})
+	}
+}
+
+// Serve registers the document at "/openapi.json", and the docs page at "/docs" if withDocs.
+func Serve(mux *http.ServeMux, doc *Document, withDocs bool) {
+	mux.HandleFunc("/openapi.json", Handler(doc))
+	if withDocs {
+		mux.HandleFunc("/docs", DocsHandler(doc.Info.Title, "/openapi.json"))
+	}
+}
@@ -0,0 +1,435 @@
+// Package openapi describes the methods of a calltable as an OpenAPI 3 document.
+package openapi
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/utils/calltable"
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem maps the lower case http verbs to the operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+
+	// the method options which are not covered by OpenAPI
+	Role        uint32 `json:"x-surf-role,omitempty"`
+	Permission  string `json:"x-surf-permission,omitempty"`
+	TimeoutMs   int64  `json:"x-surf-timeout-ms,omitempty"`
+	RateLimit   uint32 `json:"x-surf-rate-limit,omitempty"`
+	RateBurst   uint32 `json:"x-surf-rate-burst,omitempty"`
+	MethodStyle string `json:"x-surf-style,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *uint32            `json:"minLength,omitempty"`
+	MaxLength            *uint32            `json:"maxLength,omitempty"`
+	MinItems             *uint32            `json:"minItems,omitempty"`
+	MaxItems             *uint32            `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+const bearerAuth = "bearerAuth"
+
+type Options struct {
+	Title   string
+	Version string
+
+	// PathPrefix is prepended to the name of the methods which don't declare a http path,
+	// like "/MailBox/".
+	PathPrefix string
+
+	// UseProtoNames names the properties as the proto fields instead of lowerCamelCase.
+	UseProtoNames bool
+
+	// ContentType of the request and response bodies, default to "application/json".
+	ContentType string
+
+	// Envelope wraps the response schema, the default is the {"err","data"} envelope of core.
+	Envelope func(g *Generator, data *Schema) *Schema
+}
+
+// Generator converts the proto descriptors into the schemas of a Document.
+type Generator struct {
+	opts Options
+	doc  *Document
+}
+
+// Generate describes all methods of the calltable, the paths are the same as the
+// http servers register: the declared http path, or PathPrefix + method name.
+func Generate(ct *calltable.CallTable[string], opts Options) *Document {
+	if len(opts.ContentType) == 0 {
+		opts.ContentType = "application/json"
+	}
+	if opts.Envelope == nil {
+		opts.Envelope = DefaultEnvelope
+	}
+	if len(opts.Version) == 0 {
+		opts.Version = "1.0.0"
+	}
+
+	g := &Generator{
+		opts: opts,
+		doc: &Document{
+			OpenAPI: Version,
+			Info:    Info{Title: opts.Title, Version: opts.Version},
+			Paths:   make(map[string]*PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]*SecurityScheme{
+					bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+	}
+
+	var names []string
+	ct.Range(func(name string, m *calltable.Method) bool {
+		// the server streams are not served over http.
+		if m.Style != calltable.StyleServerStream {
+			names = append(names, name)
+		}
+		return true
+	})
+	sort.Strings(names)
+
+	for _, name := range names {
+		if m := ct.Get(name); m != nil {
+			g.addMethod(name, m)
+		}
+	}
+	return g.doc
+}
+
+// DefaultEnvelope is the json response of the http bridge of network:
+// {"err": errors.Error, "errors": [errors.Error], "data": response}.
+func DefaultEnvelope(g *Generator, data *Schema) *Schema {
+	errRef := g.MessageRef((&errors.Error{}).ProtoReflect().Descriptor())
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"err":    errRef,
+			"errors": {Type: "array", Items: errRef, Description: "the invalid fields of the request"},
+			"data":   data,
+		},
+	}
+}
+
+func (g *Generator) addMethod(name string, m *calltable.Method) {
+	path, verb := m.HttpRoute(g.opts.PathPrefix + name)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	verb = strings.ToLower(verb)
+	if len(verb) == 0 {
+		verb = "post"
+	}
+
+	op := &Operation{
+		OperationID: strings.Trim(strings.ReplaceAll(path, "/", "_"), "_"),
+		Summary:     name,
+		MethodStyle: styleName(m.Style),
+		Responses:   make(map[string]*Response),
+	}
+
+	if md := messageOf(m.RequestType); md != nil {
+		if verb == "get" || verb == "delete" {
+			op.Parameters = g.queryParameters(md)
+		} else {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]*MediaType{g.opts.ContentType: {Schema: g.MessageRef(md)}},
+			}
+		}
+	}
+
+	var data *Schema
+	if md := messageOf(m.ResponseType); md != nil {
+		data = g.MessageRef(md)
+	} else {
+		data = &Schema{Type: "object"}
+	}
+	op.Responses["200"] = &Response{
+		Description: "OK",
+		Content:     map[string]*MediaType{g.opts.ContentType: {Schema: g.opts.Envelope(g, data)}},
+	}
+
+	if meta := m.Meta; meta != nil {
+		if meta.AuthRequired || meta.Role != 0 || meta.Permission != "" {
+			op.Security = []map[string][]string{{bearerAuth: {}}}
+		}
+		op.Role = meta.Role
+		op.Permission = meta.Permission
+		op.RateLimit, op.RateBurst = meta.RateLimit, meta.RateBurst
+		op.TimeoutMs = meta.Timeout.Milliseconds()
+	}
+
+	item, has := g.doc.Paths[path]
+	if !has {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+	(*item)[verb] = op
+}
+
+func styleName(s calltable.MethodStyle) string {
+	switch s {
+	case calltable.StyleAsync:
+		return "async"
+	case calltable.StyleRequest:
+		return "request"
+	case calltable.StyleMicro:
+		return "micro"
+	case calltable.StyleGRpc:
+		return "grpc"
+	}
+	return ""
+}
+
+func messageOf(t reflect.Type) protoreflect.MessageDescriptor {
+	if t == nil {
+		return nil
+	}
+	msg, ok := reflect.New(t).Interface().(proto.Message)
+	if !ok {
+		return nil
+	}
+	return msg.ProtoReflect().Descriptor()
+}
+
+func (g *Generator) propName(fd protoreflect.FieldDescriptor) string {
+	if g.opts.UseProtoNames {
+		return string(fd.Name())
+	}
+	return fd.JSONName()
+}
+
+// queryParameters lists the singular scalar fields, the others can't be carried by a query.
+func (g *Generator) queryParameters(md protoreflect.MessageDescriptor) []*Parameter {
+	var ret []*Parameter
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsList() || fd.IsMap() || fd.Message() != nil {
+			continue
+		}
+		schema := g.fieldSchema(fd)
+		rules := fieldRules(fd)
+		ret = append(ret, &Parameter{
+			Name:     g.propName(fd),
+			In:       "query",
+			Required: rules.GetRequired(),
+			Schema:   schema,
+		})
+	}
+	return ret
+}
+
+// MessageRef returns a reference to the schema of the message, and adds the schema into the components.
+func (g *Generator) MessageRef(md protoreflect.MessageDescriptor) *Schema {
+	if s := wellKnownSchema(md); s != nil {
+		return s
+	}
+	name := string(md.FullName())
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, has := g.doc.Components.Schemas[name]; has {
+		return ref
+	}
+
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	// registered before the fields, so that the recursive messages refer to it.
+	g.doc.Components.Schemas[name] = schema
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		prop := g.propName(fd)
+		schema.Properties[prop] = g.fieldSchema(fd)
+		if fieldRules(fd).GetRequired() {
+			schema.Required = append(schema.Required, prop)
+		}
+	}
+	return ref
+}
+
+func (g *Generator) fieldSchema(fd protoreflect.FieldDescriptor) *Schema {
+	rules := fieldRules(fd)
+
+	if fd.IsMap() {
+		return &Schema{Type: "object", AdditionalProperties: g.singularSchema(fd.MapValue(), nil)}
+	}
+	if fd.IsList() {
+		s := &Schema{Type: "array", Items: g.singularSchema(fd, nil)}
+		if rules.GetMinLen() > 0 {
+			s.MinItems = proto.Uint32(rules.GetMinLen())
+		}
+		if rules.GetMaxLen() > 0 {
+			s.MaxItems = proto.Uint32(rules.GetMaxLen())
+		}
+		return s
+	}
+	return g.singularSchema(fd, rules)
+}
+
+// singularSchema is the schema of a single value in the protojson encoding.
+func (g *Generator) singularSchema(fd protoreflect.FieldDescriptor, rules *msgcore.FieldRules) *Schema {
+	var s *Schema
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		s = &Schema{Type: "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		s = &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		s = &Schema{Type: "integer", Format: "uint32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		// protojson encodes the 64-bit integers as strings
+		s = &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		s = &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		s = &Schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		s = &Schema{Type: "number", Format: "double"}
+	case protoreflect.StringKind:
+		s = &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		s = &Schema{Type: "string", Format: "byte"}
+	case protoreflect.EnumKind:
+		s = &Schema{Type: "string"}
+		values := fd.Enum().Values()
+		for i := 0; i < values.Len(); i++ {
+			s.Enum = append(s.Enum, string(values.Get(i).Name()))
+		}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return g.MessageRef(fd.Message())
+	default:
+		s = &Schema{}
+	}
+
+	if rules == nil {
+		return s
+	}
+	switch fd.Kind() {
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		if rules.MinLen > 0 {
+			s.MinLength = proto.Uint32(rules.MinLen)
+		}
+		if rules.MaxLen > 0 {
+			s.MaxLength = proto.Uint32(rules.MaxLen)
+		}
+		s.Pattern = rules.Pattern
+		if len(rules.TimeLayout) > 0 {
+			s.Description = "time in layout " + rules.TimeLayout
+		}
+	default:
+		s.Minimum = rules.Min
+		s.Maximum = rules.Max
+	}
+	return s
+}
+
+func fieldRules(fd protoreflect.FieldDescriptor) *msgcore.FieldRules {
+	if !proto.HasExtension(fd.Options(), msgcore.E_Rules) {
+		return nil
+	}
+	return proto.GetExtension(fd.Options(), msgcore.E_Rules).(*msgcore.FieldRules)
+}
+
+// wellKnownSchema describes the well known types by their protojson encoding.
+func wellKnownSchema(md protoreflect.MessageDescriptor) *Schema {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "google.protobuf.Duration":
+		return &Schema{Type: "string"}
+	case "google.protobuf.Struct", "google.protobuf.Any":
+		return &Schema{Type: "object"}
+	case "google.protobuf.Value":
+		return &Schema{}
+	case "google.protobuf.ListValue":
+		return &Schema{Type: "array", Items: &Schema{}}
+	case "google.protobuf.Empty":
+		return &Schema{Type: "object"}
+	case "google.protobuf.StringValue", "google.protobuf.BytesValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return &Schema{Type: "string"}
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value":
+		return &Schema{Type: "integer"}
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return &Schema{Type: "number"}
+	case "google.protobuf.BoolValue":
+		return &Schema{Type: "boolean"}
+	}
+	return nil
+}
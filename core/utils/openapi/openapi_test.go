@@ -0,0 +1,43 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ajenpan/surf/core/utils/calltable"
+	"github.com/ajenpan/surf/core/utils/openapi"
+	"github.com/ajenpan/surf/msg/mailbox"
+)
+
+func TestGenerate(t *testing.T) {
+	ct := calltable.NewCallTable[string]()
+	mailbox.RegisterMailBoxServer(ct, nil)
+
+	doc := openapi.Generate(ct, openapi.Options{Title: "MailBox", PathPrefix: "/MailBox/", UseProtoNames: true})
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	item, has := doc.Paths["/MailBox/SendMail"]
+	if !has {
+		t.Fatal("missing /MailBox/SendMail")
+	}
+	op := (*item)["post"]
+	if op == nil || len(op.Security) == 0 || op.Permission != "mail.send" {
+		t.Fatalf("SendMail should require the mail.send permission: %+v", op)
+	}
+	if op := (*doc.Paths["/MailBox/Announcement"])["post"]; len(op.Security) != 0 {
+		t.Fatal("Announcement should be public")
+	}
+
+	req := doc.Components.Schemas["SendMailRequest"]
+	if req == nil {
+		t.Fatal("missing SendMailRequest schema")
+	}
+	if len(req.Required) == 0 || req.Properties["title"].MaxLength == nil {
+		t.Fatalf("field rules are not described: %+v", req)
+	}
+	if _, has := doc.Components.Schemas["errors.Error"]; !has {
+		t.Fatal("missing the error schema of the envelope")
+	}
+}
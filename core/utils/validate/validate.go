@@ -0,0 +1,210 @@
+// Package validate checks the messages against the (core.rules) options of their fields.
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/ajenpan/surf/core/errors"
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+type fieldRule struct {
+	*msgcore.FieldRules
+	pattern *regexp.Regexp
+}
+
+var (
+	rules    sync.Map // protoreflect.FullName of a field -> *fieldRule, nil if it has no rules
+	hasRules sync.Map // protoreflect.FullName of a message -> bool
+)
+
+// Validate returns a *errors.MultiError which lists all the invalid fields, or nil.
+func Validate(m proto.Message) error {
+	if m == nil {
+		return nil
+	}
+	msg := m.ProtoReflect()
+	if !needCheck(msg.Descriptor(), map[protoreflect.FullName]bool{}) {
+		return nil
+	}
+	errs := errors.NewMultiError()
+	checkMessage(msg, "", errs)
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// needCheck tells whether the message or any message inside it declares rules.
+func needCheck(md protoreflect.MessageDescriptor, visiting map[protoreflect.FullName]bool) bool {
+	if v, has := hasRules.Load(md.FullName()); has {
+		return v.(bool)
+	}
+	if visiting[md.FullName()] {
+		return false
+	}
+	visiting[md.FullName()] = true
+
+	ret := false
+	fields := md.Fields()
+	for i := 0; i < fields.Len() && !ret; i++ {
+		fd := fields.Get(i)
+		if ruleOf(fd) != nil {
+			ret = true
+		} else if fd.IsMap() {
+			if vd := fd.MapValue(); vd.Message() != nil {
+				ret = needCheck(vd.Message(), visiting)
+			}
+		} else if fd.Message() != nil {
+			ret = needCheck(fd.Message(), visiting)
+		}
+	}
+	hasRules.Store(md.FullName(), ret)
+	return ret
+}
+
+func ruleOf(fd protoreflect.FieldDescriptor) *fieldRule {
+	if v, has := rules.Load(fd.FullName()); has {
+		return v.(*fieldRule)
+	}
+	var ret *fieldRule
+	if proto.HasExtension(fd.Options(), msgcore.E_Rules) {
+		r := proto.GetExtension(fd.Options(), msgcore.E_Rules).(*msgcore.FieldRules)
+		ret = &fieldRule{FieldRules: r}
+		if len(r.Pattern) > 0 {
+			ret.pattern = regexp.MustCompile(r.Pattern)
+		}
+	}
+	rules.Store(fd.FullName(), ret)
+	return ret
+}
+
+func fieldPath(parent string, fd protoreflect.FieldDescriptor) string {
+	if len(parent) == 0 {
+		return string(fd.Name())
+	}
+	return parent + "." + string(fd.Name())
+}
+
+func report(errs *errors.MultiError, path string, format string, args ...interface{}) {
+	errs.Append(&errors.Error{
+		Code:   errors.CodeInvalidArgument,
+		Detail: path + ": " + fmt.Sprintf(format, args...),
+		Field:  path,
+	})
+}
+
+func checkMessage(msg protoreflect.Message, parent string, errs *errors.MultiError) {
+	fields := msg.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := fieldPath(parent, fd)
+
+		if r := ruleOf(fd); r != nil {
+			checkField(msg, fd, r, path, errs)
+		}
+		if !msg.Has(fd) {
+			continue
+		}
+
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				continue
+			}
+			msg.Get(fd).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				checkMessage(v.Message(), path+"["+k.String()+"]", errs)
+				return true
+			})
+		case fd.IsList():
+			if fd.Message() == nil {
+				continue
+			}
+			list := msg.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				checkMessage(list.Get(j).Message(), path+"["+strconv.Itoa(j)+"]", errs)
+			}
+		case fd.Message() != nil:
+			checkMessage(msg.Get(fd).Message(), path, errs)
+		}
+	}
+}
+
+func checkField(msg protoreflect.Message, fd protoreflect.FieldDescriptor, r *fieldRule, path string, errs *errors.MultiError) {
+	if !msg.Has(fd) {
+		if r.Required {
+			report(errs, path, "is required")
+			return
+		}
+		if fd.Message() != nil && !fd.IsList() && !fd.IsMap() {
+			return
+		}
+	}
+
+	v := msg.Get(fd)
+	switch {
+	case fd.IsList():
+		checkLen(errs, path, r, v.List().Len())
+		return
+	case fd.IsMap():
+		checkLen(errs, path, r, v.Map().Len())
+		return
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		s := v.String()
+		checkLen(errs, path, r, utf8.RuneCountInString(s))
+		// an empty string which is not required is only checked by the length
+		if len(s) == 0 {
+			return
+		}
+		if r.pattern != nil && !r.pattern.MatchString(s) {
+			report(errs, path, "must match %q", r.Pattern)
+		}
+		if len(r.TimeLayout) > 0 {
+			if _, err := time.Parse(r.TimeLayout, s); err != nil {
+				report(errs, path, "must be a time in layout %q", r.TimeLayout)
+			}
+		}
+	case protoreflect.BytesKind:
+		checkLen(errs, path, r, len(v.Bytes()))
+	case protoreflect.EnumKind:
+		if r.DefinedOnly && fd.Enum().Values().ByNumber(v.Enum()) == nil {
+			report(errs, path, "%d is not a defined value of %s", v.Enum(), fd.Enum().FullName())
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		checkRange(errs, path, r, float64(v.Int()))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		checkRange(errs, path, r, float64(v.Uint()))
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		checkRange(errs, path, r, v.Float())
+	}
+}
+
+func checkLen(errs *errors.MultiError, path string, r *fieldRule, n int) {
+	if r.MinLen > 0 && n < int(r.MinLen) {
+		report(errs, path, "length must be at least %d", r.MinLen)
+	}
+	if r.MaxLen > 0 && n > int(r.MaxLen) {
+		report(errs, path, "length must be at most %d", r.MaxLen)
+	}
+}
+
+func checkRange(errs *errors.MultiError, path string, r *fieldRule, n float64) {
+	if r.Min != nil && n < r.GetMin() {
+		report(errs, path, "must be at least %v", r.GetMin())
+	}
+	if r.Max != nil && n > r.GetMax() {
+		report(errs, path, "must be at most %v", r.GetMax())
+	}
+}
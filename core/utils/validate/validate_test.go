@@ -0,0 +1,49 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/utils/validate"
+	"github.com/ajenpan/surf/msg/mailbox"
+	"github.com/ajenpan/surf/msg/uauth"
+)
+
+func fieldsOf(t *testing.T, err error) map[string]bool {
+	merr, ok := err.(*errors.MultiError)
+	if !ok {
+		t.Fatalf("expected *errors.MultiError, got %v", err)
+	}
+	ret := make(map[string]bool)
+	for _, e := range merr.Errors {
+		ret[e.Field] = true
+	}
+	return ret
+}
+
+func TestValidate(t *testing.T) {
+	if err := validate.Validate(&uauth.RegisterRequest{Uname: "surf_user", Passwd: "123456"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := fieldsOf(t, validate.Validate(&uauth.RegisterRequest{Uname: "a!", Passwd: "123"}))
+	if !fields["uname"] || !fields["passwd"] || len(fields) != 2 {
+		t.Fatalf("unexpected invalid fields: %v", fields)
+	}
+
+	fields = fieldsOf(t, validate.Validate(&mailbox.SendMailRequest{
+		Title:     "title",
+		RecvConds: &mailbox.MailRecvCond{},
+		ExpireAt:  "tomorrow",
+	}))
+	for _, f := range []string{"content", "recv_conds.items", "expire_at"} {
+		if !fields[f] {
+			t.Fatalf("%s should be invalid: %v", f, fields)
+		}
+	}
+
+	// no rules are declared in the message
+	if err := validate.Validate(&uauth.UserInfo{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,788 @@
+// Code generated by protoc-gen-surf. DO NOT EDIT.
+// source: uauth.proto
+
+package uauth
+
+import (
+	context "context"
+	calltable "github.com/ajenpan/surf/core/utils/calltable"
+	reflect "reflect"
+)
+
+// UAuthServer is the handler of the UAuth service.
+type UAuthServer interface {
+	Captcha(context.Context, *CaptchaRequest) (*CaptchaResponse, error)
+	Login(context.Context, *LoginRequest) (*LoginResponse, error)
+	LoginSecondFactor(context.Context, *LoginSecondFactorRequest) (*LoginResponse, error)
+	AnonymousLogin(context.Context, *AnonymousLoginRequest) (*AnonymousLoginResponse, error)
+	UpgradeGuest(context.Context, *UpgradeGuestRequest) (*UpgradeGuestResponse, error)
+	RefreshToken(context.Context, *RefreshTokenRequest) (*RefreshTokenResponse, error)
+	UserInfo(context.Context, *UserInfoRequest) (*UserInfoResponse, error)
+	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	ModifyPasswd(context.Context, *ModifyPasswdRequest) (*ModifyPasswdResponse, error)
+	ResetPasswd(context.Context, *ResetPasswdRequest) (*ResetPasswdResponse, error)
+	PublicKeys(context.Context, *PublicKeysRequest) (*PublicKeysResponse, error)
+	Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error)
+	Logout(context.Context, *LogoutRequest) (*LogoutResponse, error)
+	RevokeUser(context.Context, *RevokeUserRequest) (*RevokeUserResponse, error)
+	UnlockUser(context.Context, *UnlockUserRequest) (*UnlockUserResponse, error)
+	TotpSetup(context.Context, *TotpSetupRequest) (*TotpSetupResponse, error)
+	TotpEnable(context.Context, *TotpEnableRequest) (*TotpEnableResponse, error)
+	TotpDisable(context.Context, *TotpDisableRequest) (*TotpDisableResponse, error)
+	TotpRecoveryCodes(context.Context, *TotpRecoveryCodesRequest) (*TotpRecoveryCodesResponse, error)
+	ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error)
+	RevokeSessions(context.Context, *RevokeSessionsRequest) (*RevokeSessionsResponse, error)
+	ListUserSessions(context.Context, *ListUserSessionsRequest) (*ListSessionsResponse, error)
+	RevokeUserSessions(context.Context, *RevokeUserSessionsRequest) (*RevokeSessionsResponse, error)
+	OAuthProviders(context.Context, *OAuthProvidersRequest) (*OAuthProvidersResponse, error)
+	OAuthStart(context.Context, *OAuthStartRequest) (*OAuthStartResponse, error)
+	OAuthLogin(context.Context, *OAuthLoginRequest) (*LoginResponse, error)
+	OAuthLink(context.Context, *OAuthLinkRequest) (*OAuthLinkResponse, error)
+	ListIdentities(context.Context, *ListIdentitiesRequest) (*ListIdentitiesResponse, error)
+	UnlinkIdentity(context.Context, *UnlinkIdentityRequest) (*UnlinkIdentityResponse, error)
+}
+
+// RegisterUAuthServer adds the methods of UAuth into the call table, keyed by method name.
+func RegisterUAuthServer(ct *calltable.CallTable[string], srv UAuthServer) {
+	ct.Add("Captcha", &calltable.Method{
+		FuncName:     "Captcha",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*CaptchaRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*CaptchaResponse)(nil)).Elem(),
+		Invoker:      _UAuth_Captcha_Invoker(srv),
+	})
+	ct.Add("Login", &calltable.Method{
+		FuncName:     "Login",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*LoginRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*LoginResponse)(nil)).Elem(),
+		Invoker:      _UAuth_Login_Invoker(srv),
+	})
+	ct.Add("LoginSecondFactor", &calltable.Method{
+		FuncName:     "LoginSecondFactor",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*LoginSecondFactorRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*LoginResponse)(nil)).Elem(),
+		Invoker:      _UAuth_LoginSecondFactor_Invoker(srv),
+	})
+	ct.Add("AnonymousLogin", &calltable.Method{
+		FuncName:     "AnonymousLogin",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*AnonymousLoginRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*AnonymousLoginResponse)(nil)).Elem(),
+		Invoker:      _UAuth_AnonymousLogin_Invoker(srv),
+	})
+	ct.Add("UpgradeGuest", &calltable.Method{
+		FuncName:     "UpgradeGuest",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*UpgradeGuestRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*UpgradeGuestResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("UpgradeGuest")),
+		Invoker:      _UAuth_UpgradeGuest_Invoker(srv),
+	})
+	ct.Add("RefreshToken", &calltable.Method{
+		FuncName:     "RefreshToken",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*RefreshTokenRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*RefreshTokenResponse)(nil)).Elem(),
+		Invoker:      _UAuth_RefreshToken_Invoker(srv),
+	})
+	ct.Add("UserInfo", &calltable.Method{
+		FuncName:     "UserInfo",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*UserInfoRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*UserInfoResponse)(nil)).Elem(),
+		Invoker:      _UAuth_UserInfo_Invoker(srv),
+	})
+	ct.Add("Register", &calltable.Method{
+		FuncName:     "Register",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*RegisterRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*RegisterResponse)(nil)).Elem(),
+		Invoker:      _UAuth_Register_Invoker(srv),
+	})
+	ct.Add("ModifyPasswd", &calltable.Method{
+		FuncName:     "ModifyPasswd",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*ModifyPasswdRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*ModifyPasswdResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("ModifyPasswd")),
+		Invoker:      _UAuth_ModifyPasswd_Invoker(srv),
+	})
+	ct.Add("ResetPasswd", &calltable.Method{
+		FuncName:     "ResetPasswd",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*ResetPasswdRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*ResetPasswdResponse)(nil)).Elem(),
+		Invoker:      _UAuth_ResetPasswd_Invoker(srv),
+	})
+	ct.Add("PublicKeys", &calltable.Method{
+		FuncName:     "PublicKeys",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*PublicKeysRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*PublicKeysResponse)(nil)).Elem(),
+		Invoker:      _UAuth_PublicKeys_Invoker(srv),
+	})
+	ct.Add("Introspect", &calltable.Method{
+		FuncName:     "Introspect",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*IntrospectRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*IntrospectResponse)(nil)).Elem(),
+		Invoker:      _UAuth_Introspect_Invoker(srv),
+	})
+	ct.Add("Logout", &calltable.Method{
+		FuncName:     "Logout",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*LogoutRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*LogoutResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("Logout")),
+		Invoker:      _UAuth_Logout_Invoker(srv),
+	})
+	ct.Add("RevokeUser", &calltable.Method{
+		FuncName:     "RevokeUser",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*RevokeUserRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*RevokeUserResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("RevokeUser")),
+		Invoker:      _UAuth_RevokeUser_Invoker(srv),
+	})
+	ct.Add("UnlockUser", &calltable.Method{
+		FuncName:     "UnlockUser",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*UnlockUserRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*UnlockUserResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("UnlockUser")),
+		Invoker:      _UAuth_UnlockUser_Invoker(srv),
+	})
+	ct.Add("TotpSetup", &calltable.Method{
+		FuncName:     "TotpSetup",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*TotpSetupRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*TotpSetupResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("TotpSetup")),
+		Invoker:      _UAuth_TotpSetup_Invoker(srv),
+	})
+	ct.Add("TotpEnable", &calltable.Method{
+		FuncName:     "TotpEnable",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*TotpEnableRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*TotpEnableResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("TotpEnable")),
+		Invoker:      _UAuth_TotpEnable_Invoker(srv),
+	})
+	ct.Add("TotpDisable", &calltable.Method{
+		FuncName:     "TotpDisable",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*TotpDisableRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*TotpDisableResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("TotpDisable")),
+		Invoker:      _UAuth_TotpDisable_Invoker(srv),
+	})
+	ct.Add("TotpRecoveryCodes", &calltable.Method{
+		FuncName:     "TotpRecoveryCodes",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*TotpRecoveryCodesRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*TotpRecoveryCodesResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("TotpRecoveryCodes")),
+		Invoker:      _UAuth_TotpRecoveryCodes_Invoker(srv),
+	})
+	ct.Add("ListSessions", &calltable.Method{
+		FuncName:     "ListSessions",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*ListSessionsRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*ListSessionsResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("ListSessions")),
+		Invoker:      _UAuth_ListSessions_Invoker(srv),
+	})
+	ct.Add("RevokeSessions", &calltable.Method{
+		FuncName:     "RevokeSessions",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*RevokeSessionsRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*RevokeSessionsResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("RevokeSessions")),
+		Invoker:      _UAuth_RevokeSessions_Invoker(srv),
+	})
+	ct.Add("ListUserSessions", &calltable.Method{
+		FuncName:     "ListUserSessions",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*ListUserSessionsRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*ListSessionsResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("ListUserSessions")),
+		Invoker:      _UAuth_ListUserSessions_Invoker(srv),
+	})
+	ct.Add("RevokeUserSessions", &calltable.Method{
+		FuncName:     "RevokeUserSessions",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*RevokeUserSessionsRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*RevokeSessionsResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("RevokeUserSessions")),
+		Invoker:      _UAuth_RevokeUserSessions_Invoker(srv),
+	})
+	ct.Add("OAuthProviders", &calltable.Method{
+		FuncName:     "OAuthProviders",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*OAuthProvidersRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*OAuthProvidersResponse)(nil)).Elem(),
+		Invoker:      _UAuth_OAuthProviders_Invoker(srv),
+	})
+	ct.Add("OAuthStart", &calltable.Method{
+		FuncName:     "OAuthStart",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*OAuthStartRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*OAuthStartResponse)(nil)).Elem(),
+		Invoker:      _UAuth_OAuthStart_Invoker(srv),
+	})
+	ct.Add("OAuthLogin", &calltable.Method{
+		FuncName:     "OAuthLogin",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*OAuthLoginRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*LoginResponse)(nil)).Elem(),
+		Invoker:      _UAuth_OAuthLogin_Invoker(srv),
+	})
+	ct.Add("OAuthLink", &calltable.Method{
+		FuncName:     "OAuthLink",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*OAuthLinkRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*OAuthLinkResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("OAuthLink")),
+		Invoker:      _UAuth_OAuthLink_Invoker(srv),
+	})
+	ct.Add("ListIdentities", &calltable.Method{
+		FuncName:     "ListIdentities",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*ListIdentitiesRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*ListIdentitiesResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("ListIdentities")),
+		Invoker:      _UAuth_ListIdentities_Invoker(srv),
+	})
+	ct.Add("UnlinkIdentity", &calltable.Method{
+		FuncName:     "UnlinkIdentity",
+		Style:        calltable.StyleGRpc,
+		RequestType:  reflect.TypeOf((*UnlinkIdentityRequest)(nil)).Elem(),
+		ResponseType: reflect.TypeOf((*UnlinkIdentityResponse)(nil)).Elem(),
+		Meta:         calltable.MethodMetaOf(File_uauth_proto.Services().ByName("UAuth").Methods().ByName("UnlinkIdentity")),
+		Invoker:      _UAuth_UnlinkIdentity_Invoker(srv),
+	})
+}
+
+func _UAuth_Captcha_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*CaptchaRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.Captcha(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_Login_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*LoginRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.Login(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_LoginSecondFactor_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*LoginSecondFactorRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.LoginSecondFactor(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_AnonymousLogin_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*AnonymousLoginRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.AnonymousLogin(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_UpgradeGuest_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*UpgradeGuestRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.UpgradeGuest(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_RefreshToken_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*RefreshTokenRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.RefreshToken(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_UserInfo_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*UserInfoRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.UserInfo(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_Register_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*RegisterRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.Register(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_ModifyPasswd_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*ModifyPasswdRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.ModifyPasswd(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_ResetPasswd_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*ResetPasswdRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.ResetPasswd(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_PublicKeys_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*PublicKeysRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.PublicKeys(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_Introspect_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*IntrospectRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.Introspect(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_Logout_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*LogoutRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.Logout(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_RevokeUser_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*RevokeUserRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.RevokeUser(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_UnlockUser_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*UnlockUserRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.UnlockUser(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_TotpSetup_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*TotpSetupRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.TotpSetup(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_TotpEnable_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*TotpEnableRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.TotpEnable(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_TotpDisable_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*TotpDisableRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.TotpDisable(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_TotpRecoveryCodes_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*TotpRecoveryCodesRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.TotpRecoveryCodes(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_ListSessions_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*ListSessionsRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.ListSessions(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_RevokeSessions_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*RevokeSessionsRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.RevokeSessions(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_ListUserSessions_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*ListUserSessionsRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.ListUserSessions(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_RevokeUserSessions_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*RevokeUserSessionsRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.RevokeUserSessions(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_OAuthProviders_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*OAuthProvidersRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.OAuthProviders(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_OAuthStart_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*OAuthStartRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.OAuthStart(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_OAuthLogin_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*OAuthLoginRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.OAuthLogin(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_OAuthLink_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*OAuthLinkRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.OAuthLink(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_ListIdentities_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*ListIdentitiesRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.ListIdentities(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
+
+func _UAuth_UnlinkIdentity_Invoker(srv UAuthServer) calltable.Invoker {
+	return func(ctx interface{}, req interface{}) (interface{}, error) {
+		c, ok := ctx.(context.Context)
+		if !ok {
+			return nil, calltable.ErrInvalidContext
+		}
+		in, ok := req.(*UnlinkIdentityRequest)
+		if !ok {
+			return nil, calltable.ErrInvalidRequest
+		}
+		out, err := srv.UnlinkIdentity(c, in)
+		if out == nil {
+			return nil, err
+		}
+		return out, err
+	}
+}
@@ -4,8 +4,29 @@ $protocbin="protoc.exe"
 Write-Output $protocbin
 & $protocbin --version
 
+# the clients of the game clients are generated if their output dirs are set:
+#   $env:TS_OUT="../client/ts"; $env:CS_OUT="../client/cs"; ./gen-proto.ps1
+# the ts messages are generated by ts-proto: npm install ts-proto
+
+# the method options are imported as "core/options.proto"
+$coreopt = "Mcore/options.proto=github.com/ajenpan/surf/msg/core"
+
 Get-ChildItem -Path . -Recurse -Filter *.proto | ForEach-Object {    
     $outputPath = $_.DirectoryName    
     Write-Output  $_.FullName
-    & $protocbin --proto_path=$outputPath --go_out=../msg $_.FullName
+    $surfopt = "style=grpc"
+    if ($_.Name -eq "mailbox.proto") {
+        $surfopt = "style=micro"
+    }
+    # options.proto is generated by its import path "core/options.proto"
+    if ($_.Name -eq "options.proto") {
+        $outputPath = "."
+    }
+    & $protocbin --proto_path=$outputPath --proto_path=. --go_out=../msg --go_opt=$coreopt --surf_out=../msg --surf_opt="$surfopt,$coreopt" $_.FullName
+    if ($env:TS_OUT) {
+        & $protocbin --proto_path=$outputPath --proto_path=. --ts_proto_out=$env:TS_OUT --surf_out=$env:TS_OUT --surf_opt="lang=ts,$coreopt" $_.FullName
+    }
+    if ($env:CS_OUT) {
+        & $protocbin --proto_path=$outputPath --proto_path=. --csharp_out=$env:CS_OUT --surf_out=$env:CS_OUT --surf_opt="lang=csharp,$coreopt" $_.FullName
+    }
 }
@@ -2,11 +2,37 @@
 
 protoc --version
 
+# protoc-gen-surf generates the typed calltable registrations
+# go install github.com/ajenpan/surf/tools/protoc-gen-surf
+
+# the clients of the game clients are generated if their output dirs are set:
+#   TS_OUT=../client/ts CS_OUT=../client/cs ./gen-proto.sh
+# the ts messages are generated by ts-proto: npm install ts-proto
+
+# the method options are imported as "core/options.proto"
+coreopt="Mcore/options.proto=github.com/ajenpan/surf/msg/core"
+
 pbfiles=$(find . -name "*.proto" -type f)
 
 for file in $pbfiles; do
     filename=$(basename -- "$file")
     dir=$(dirname "$file")
     echo $dir $filename $file
-    protoc -I=${dir} -I=. --go_out=${dir} $file
+    surfopt="style=grpc"
+    case $filename in
+    # the mailbox handlers are written in micro style: func(ctx, in, out) error
+    mailbox.proto) surfopt="style=micro" ;;
+    esac
+    incdir=${dir}
+    # options.proto is generated by its import path "core/options.proto"
+    if [ "$filename" = "options.proto" ]; then
+        incdir=.
+    fi
+    protoc -I=${incdir} -I=. --go_out=${dir} --go_opt=${coreopt} --surf_out=${dir} --surf_opt=${surfopt},${coreopt} $file
+    if [ -n "$TS_OUT" ]; then
+        protoc -I=${incdir} -I=. --ts_proto_out=${TS_OUT} --surf_out=${TS_OUT} --surf_opt=lang=ts,${coreopt} $file
+    fi
+    if [ -n "$CS_OUT" ]; then
+        protoc -I=${incdir} -I=. --csharp_out=${CS_OUT} --surf_out=${CS_OUT} --surf_opt=lang=csharp,${coreopt} $file
+    fi
 done
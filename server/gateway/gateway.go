@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	"github.com/ajenpan/surf/core/event"
+	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/network"
+	"github.com/ajenpan/surf/core/utils"
+	msg "github.com/ajenpan/surf/msg/core"
+)
+
+type Options struct {
+	// TokenVerifier authenticates the client conns, a key set loaded from the public key file
+	// or fetched from uauth.
+	TokenVerifier auth.Verifier
+
+	// Routes maps a service name to the http address of its backend,
+	// a request named "uauth/UserInfo" is posted to Routes["uauth"] + "/UserInfo".
+	Routes map[string]string
+
+	ContentType string
+	Timeout     time.Duration
+}
+
+func New(opts Options) *Gateway {
+	if opts.ContentType == "" {
+		opts.ContentType = "application/json"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Gateway{
+		Options: opts,
+		client:  &http.Client{Timeout: opts.Timeout},
+		conns:   network.NewConnTable(),
+		revoked: auth.NewDenyList(),
+	}
+}
+
+// Gateway accepts the client connections of every transport,
+// and forwards the authenticated client requests to the backend services.
+type Gateway struct {
+	Options
+	client *http.Client
+
+	// conns of all the transports, to close the ones of the revoked tokens.
+	conns   *network.ConnTable
+	revoked *auth.DenyList
+}
+
+type clientUser struct {
+	*auth.UserInfo
+	token string
+}
+
+func (g *Gateway) OnConnAuth(data []byte) (auth.User, error) {
+	uinfo, err := g.TokenVerifier.Verify(data)
+	if err != nil {
+		return nil, err
+	}
+	if g.revoked.IsRevoked(uinfo) {
+		return nil, auth.ErrTokenRevoked
+	}
+	return &clientUser{UserInfo: uinfo, token: string(data)}, nil
+}
+
+func (g *Gateway) OnConnEnable(c network.Conn, enable bool) {
+	log.Infof("gateway conn:%s, uid:%d, enable:%v", c.ConnID(), c.UserID(), enable)
+	if enable {
+		g.conns.Store(c)
+	} else {
+		g.conns.RemoveIfSame(c)
+	}
+}
+
+// OnEvent receives the revocation events, and closes the conns of the revoked tokens.
+func (g *Gateway) OnEvent(e *event.Event) {
+	r, ok := auth.ParseRevocationEvent(e)
+	if !ok {
+		return
+	}
+	g.revoked.Add(r)
+	kicked := g.conns.CloseIf(func(c network.Conn) bool {
+		u := network.ConnUser(c)
+		return u != nil && g.revoked.IsRevoked(u)
+	})
+	if kicked > 0 {
+		log.Infof("gateway closed %d conns of the revoked tokens, uid: %d", kicked, r.Uid)
+	}
+}
+
+func (g *Gateway) OnConnPacket(c network.Conn, pk *network.HVPacket) {
+	req := &msg.RequestMsgWrap{}
+	if err := proto.Unmarshal(pk.GetBody(), req); err != nil {
+		log.Warnf("gateway conn:%s, unmarshal request failed: %v", c.ConnID(), err)
+		return
+	}
+	go g.forward(c, req)
+}
+
+func (g *Gateway) forward(c network.Conn, req *msg.RequestMsgWrap) {
+	resp := &msg.ResponseMsgWrap{
+		Name:  req.Name,
+		Seqid: req.Seqid,
+	}
+
+	body, err := g.call(c, req)
+	if err != nil {
+		log.Warnf("gateway forward %s failed: %v", req.Name, err)
+		network.SetResponseError(resp, err)
+	} else {
+		resp.Body = body
+	}
+
+	raw, err := proto.Marshal(resp)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	pk := network.NewHVPacket()
+	pk.SetFlag(network.HVPacketFlagPacket)
+	pk.SetSubFlag(uint8(msg.MsgType_Response))
+	pk.SetBody(raw)
+	if err := c.Send(pk); err != nil {
+		log.Warnf("gateway conn:%s, send response failed: %v", c.ConnID(), err)
+	}
+}
+
+func (g *Gateway) call(c network.Conn, req *msg.RequestMsgWrap) ([]byte, error) {
+	svrName, method, found := strings.Cut(req.Name, "/")
+	if !found || method == "" {
+		return nil, errors.InvalidArgument("invalid request name: %s", req.Name)
+	}
+
+	addr, has := g.Routes[svrName]
+	if !has {
+		return nil, errors.NotFound("service %s not found", svrName)
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, utils.JoinURL(addr, method), bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", g.ContentType)
+	httpReq.Header.Set("Accept", g.ContentType)
+	if u, ok := network.ConnUser(c).(*clientUser); ok {
+		httpReq.Header.Set("Authorization", "Bearer "+u.token)
+	}
+
+	httpResp, err := g.client.Do(httpReq)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeUnavailable, "service "+svrName+" unavailable")
+	}
+	defer httpResp.Body.Close()
+
+	raw, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return network.ReadHttpResponse(httpResp.Header.Get("Content-Type"), httpResp.StatusCode, raw)
+}
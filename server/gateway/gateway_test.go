@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
+	"github.com/ajenpan/surf/core/network"
+)
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for i := 0; !cond(); i++ {
+		if i > 500 {
+			t.Fatal("timeout waiting for", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestRevokedConnClosed revokes a token at the event hub of uauth, the conn of the token is closed by the gateway.
+func TestRevokedConnClosed(t *testing.T) {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := New(Options{TokenVerifier: auth.NewKeySet(&pk.PublicKey)})
+
+	svr, err := network.NewTcpServer(network.TcpServerOptions{
+		ListenAddr:   "127.0.0.1:0",
+		OnConnPacket: gw.OnConnPacket,
+		OnConnEnable: gw.OnConnEnable,
+		OnConnAuth:   gw.OnConnAuth,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.Start()
+	defer svr.Stop()
+
+	hub := event.NewHub(event.HubOptions{Retain: time.Minute})
+	hubsvr := httptest.NewServer(hub)
+	defer hubsvr.Close()
+	sub := event.NewSubscriber(event.SubscriberOptions{URL: hubsvr.URL, Topics: []string{auth.RevokedTopic}}, gw)
+	sub.Start()
+	defer sub.Stop()
+
+	dial := func(uid uint32) (*network.ClientConn, string) {
+		uinfo := &auth.UserInfo{UId: uid, UName: "player"}
+		token, err := auth.GenerateToken(pk, uinfo, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := network.DialTcp(svr.Address().String(), network.ClientOptions{Token: []byte(token)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c, token
+	}
+	revokedConn, revokedToken := dial(1001)
+	defer revokedConn.Close()
+	liveConn, _ := dial(1002)
+	defer liveConn.Close()
+	waitFor(t, "the conns", func() bool { return gw.conns.Len() == 2 })
+	waitFor(t, "the subscriber", func() bool { return hub.Subscribers() == 1 })
+
+	revokedUser, err := gw.TokenVerifier.Verify([]byte(revokedToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := auth.RevocationEvent(&auth.Revocation{
+		TokenId:  revokedUser.TokenID,
+		Uid:      revokedUser.UId,
+		ExpireAt: revokedUser.ExpireAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.Publish(e)
+
+	select {
+	case <-revokedConn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the conn of the revoked token is not closed")
+	}
+	waitFor(t, "the revoked conn removed", func() bool { return gw.conns.Len() == 1 })
+	select {
+	case <-liveConn.Done():
+		t.Fatal("the conn of another token is closed")
+	default:
+	}
+
+	if _, err := gw.OnConnAuth([]byte(revokedToken)); err != auth.ErrTokenRevoked {
+		t.Fatal("the revoked token is accepted again:", err)
+	}
+}
@@ -55,7 +55,8 @@ func (an *announcement) write(in *proto.PublishAnnouncementRequest) error {
 	return os.WriteFile(announcementFilePath, []byte(raw), 0644)
 }
 
-func (an *announcement) read(out *proto.AnnouncementResponse, caller string) {
+// read returns the content if it is effective, or the preview is allowed.
+func (an *announcement) read(out *proto.AnnouncementResponse, preview bool) {
 	an.rwlock.RLock()
 	defer an.rwlock.RUnlock()
 
@@ -64,7 +65,7 @@ func (an *announcement) read(out *proto.AnnouncementResponse, caller string) {
 	out.ExpectValid = an.resp.ExpectValid
 	out.CurrentVaild = an.resp.ExpectValid && n.After(an.effectAt) && n.Before(an.expireAt)
 
-	if out.CurrentVaild || caller == "admin" {
+	if out.CurrentVaild || preview {
 		out.Title = an.resp.Title
 		out.Content = an.resp.Content
 		out.EffectAt = an.resp.EffectAt
@@ -87,7 +88,8 @@ func (h *Handler) PublishAnnouncement(ctx context.Context, in *proto.PublishAnno
 }
 
 func (h *Handler) Announcement(ctx context.Context, in *proto.AnnouncementRequest, out *proto.AnnouncementResponse) error {
-	role, _ := GetCallerRoleFromCtx(ctx)
-	h.ann.read(out, role)
+	// the publishers see the announcement before it is effective.
+	caller, err := GetCallerFromCtx(ctx)
+	h.ann.read(out, err == nil && h.permissions().Allowed(caller.UserRole(), "announcement.publish"))
 	return nil
 }
@@ -4,90 +4,43 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"strconv"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
-
+	"github.com/ajenpan/surf/core/auth"
 	pb "github.com/ajenpan/surf/msg/mailbox"
 )
 
-type (
-	CtxClaimsKey   struct{}
-	CtxXRealIpKey  struct{}
-	CtxAdminUIDKey struct{}
-	CtxUserKey     struct{}
-	CtxCallerKey   struct{}
-)
+type CtxXRealIpKey struct{}
 
-var (
-	CtxAdminUID   = CtxAdminUIDKey{}
-	CtxXRealIp    = CtxXRealIpKey{}
-	CtxClaims     = CtxClaimsKey{}
-	CtxUser       = CtxUserKey{}
-	CtxCallerRole = CtxCallerKey{}
-)
+var CtxXRealIp = CtxXRealIpKey{}
 
 const TimeLayout = "2006-01-02 15:04:05"
 
-var AdminAuthsigned = []byte("49370808e359957960ae9058f3860c9b")
-
-func VerifyAdminTokenWithClaims(tokenRaw string) (map[string]interface{}, error) {
-	claims := make(jwt.MapClaims)
-
-	_, err := jwt.ParseWithClaims(tokenRaw, claims, func(t *jwt.Token) (interface{}, error) {
-		return AdminAuthsigned, nil
-	})
-
-	return claims, err
-}
-
-func GetAdminUIDFromCtx(ctx context.Context) (string, error) {
-	raw := ctx.Value(CtxAdminUID)
-	if raw == nil {
-		return "", fmt.Errorf("ctx without uid")
-	}
-	ret, ok := raw.(string)
+// GetCallerFromCtx is the uauth user of the request, authenticated by the token verified with the shared keys.
+func GetCallerFromCtx(ctx context.Context) (auth.User, error) {
+	caller, ok := auth.UserFromContext(ctx)
 	if !ok {
-		return "", fmt.Errorf("GetUIDFromCtx failed")
+		return nil, fmt.Errorf("ctx without caller")
 	}
-	return ret, nil
+	return caller, nil
 }
 
-func GetCallerRoleFromCtx(ctx context.Context) (string, error) {
-	raw := ctx.Value(CtxCallerRole)
-	if raw == nil {
-		return "", fmt.Errorf("ctx without caller")
-	}
-	ret, ok := raw.(string)
-	if !ok {
-		return "", fmt.Errorf("GetCallerFromCtx failed")
+// GetAdminUIDFromCtx is the uid of the admin who calls, the dispatcher checked the permission of the method.
+func GetAdminUIDFromCtx(ctx context.Context) (string, error) {
+	caller, err := GetCallerFromCtx(ctx)
+	if err != nil {
+		return "", err
 	}
-	return ret, nil
+	return strconv.FormatUint(uint64(caller.UserID()), 10), nil
 }
 
 func GetUserFromCtx(ctx context.Context) (*User, error) {
-	raw := ctx.Value(CtxUser)
-	if raw == nil {
-		return nil, fmt.Errorf("ctx without uid")
+	caller, err := GetCallerFromCtx(ctx)
+	if err != nil {
+		return nil, err
 	}
-	ret, ok := raw.(*User)
-	if !ok {
-		return nil, fmt.Errorf("GetUIDFromCtx failed")
-	}
-	return ret, nil
-}
-
-func GetClaimsFromCtx(ctx context.Context) (jwt.MapClaims, error) {
-	raw := ctx.Value(CtxClaims)
-	if raw == nil {
-		return nil, fmt.Errorf("ctx without claims")
-	}
-
-	ret, ok := raw.(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("GetClaimsFromCtx failed")
-	}
-	return ret, nil
+	return &User{UID: caller.UserID()}, nil
 }
 
 func GetXRealIpFromCtx(ctx context.Context) (string, error) {
@@ -26,6 +26,20 @@ type Config struct {
 
 	DBPDeliverUrl  string
 	ChecksumVerify bool
+
+	// KeysURL is the PublicKeys of uauth which verifies the tokens of the users and the admins,
+	// the key of PublicKeyFile is used if it is empty.
+	KeysURL       string
+	PublicKeyFile string
+	// AuthURL is the http server of uauth like "http://uauth:9999", the tokens are introspected
+	// by uauth instead of verified by the keys if it is set, so mailbox holds no key.
+	AuthURL string
+	// EventsURL is the event stream of uauth like "http://uauth:9998/events", the tokens cached for AuthURL
+	// are dropped by the revocations of it. A revoked token is accepted until its cache expires if it is empty.
+	EventsURL string
+	// PermissionsFile maps the roles to the permissions of the methods, see calltable.ParsePermissions.
+	// Only the admins have all the permissions if it is empty.
+	PermissionsFile string
 }
 
 type DebugConf struct {
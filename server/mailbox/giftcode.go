@@ -16,10 +16,6 @@ var RegGiftCode = regexp.MustCompile(`^[a-zA-Z0-9]{8}$`)
 
 func (h *Handler) GenerateGiftCode(ctx context.Context, in *proto.GenerateGiftCodeRequest, out *proto.GenerateGiftCodeResponse) error {
 
-	if _, err := GetAdminUIDFromCtx(ctx); err != nil {
-		return fmt.Errorf("you have no permission")
-	}
-
 	checkGiftType := func(gt string) bool {
 		if gt == "SR" {
 			return true
@@ -69,10 +65,6 @@ func (h *Handler) GenerateGiftCode(ctx context.Context, in *proto.GenerateGiftCo
 }
 
 func (h *Handler) GiftCodeList(ctx context.Context, in *proto.GiftCodeListRequest, out *proto.GiftCodeListResponse) error {
-	if _, err := GetAdminUIDFromCtx(ctx); err != nil {
-		return fmt.Errorf("you have no permission")
-	}
-
 	in.Page = CheckListPage(in.Page)
 
 	total := int64(0)
@@ -184,10 +176,6 @@ func (h *Handler) ExchangeGiftCode(ctx context.Context, in *proto.ExchangeGiftCo
 }
 
 func (h *Handler) UpdateGiftCode(ctx context.Context, in *proto.UpdateGiftCodeRequest, out *proto.UpdateGiftCodeResponse) error {
-	_, err := GetAdminUIDFromCtx(ctx)
-	if err != nil {
-		return fmt.Errorf("you have no permission")
-	}
 	record := &gamedbMod.GiftCode{
 		ID:     int(in.Id),
 		Status: int8(in.Status),
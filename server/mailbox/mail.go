@@ -12,6 +12,7 @@ import (
 	"gorm.io/gorm/schema"
 
 	"github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/core/utils/calltable"
 	proto "github.com/ajenpan/surf/msg/mailbox"
 	"github.com/ajenpan/surf/server/mailbox/database"
 	gamedbMod "github.com/ajenpan/surf/server/mailbox/database/models"
@@ -64,6 +65,9 @@ func NewHandler(c *Config) *Handler {
 	return ret
 }
 
+// a missing handler of the MailBox service is a compile error.
+var _ proto.MailBoxServer = (*Handler)(nil)
+
 type User struct {
 	UID uint32
 }
@@ -77,9 +81,19 @@ type Handler struct {
 	WLogDB   *gorm.DB
 	Rds      *redis.Client
 
+	// Permissions is the one of the dispatcher, the handlers check the permissions of the callers by it too.
+	Permissions *calltable.Permissions
+
 	ann *announcement
 }
 
+func (h *Handler) permissions() *calltable.Permissions {
+	if h.Permissions == nil {
+		return calltable.DefaultPermissions
+	}
+	return h.Permissions
+}
+
 func (h *Handler) Init() error {
 	h.cache = &MailCache{
 		infos: make(map[uint32]*MailDetail),
@@ -233,20 +247,8 @@ func (h *Handler) SendMail(ctx context.Context, in *proto.SendMailRequest, out *
 		return err
 	}
 
-	if in.RecvConds == nil || len(in.RecvConds.Items) == 0 {
-		return fmt.Errorf("recv condition is required")
-	}
-
-	if len(in.Title) == 0 || len(in.Content) == 0 {
-		return fmt.Errorf("title or content is required")
-	}
-
 	in.EffectAt = time.Now().Format(TimeLayout)
 
-	if len(in.EffectAt) == 0 || len(in.ExpireAt) == 0 {
-		return fmt.Errorf("effect time is required")
-	}
-
 	expireAt, err := time.ParseInLocation(TimeLayout, in.ExpireAt, time.Local)
 	if err != nil {
 		return fmt.Errorf("expire time format error,%v", err)
@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/big"
+	mrand "math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ajenpan/surf/core/errors"
+	msg "github.com/ajenpan/surf/msg/uauth"
+)
+
+const (
+	CaptchaDigit = "digit"
+	CaptchaMath  = "math"
+)
+
+const (
+	captchaTTL    = 5 * time.Minute
+	captchaDigits = 5
+)
+
+// Captcha issues a captcha, its answer is kept in the cache until it is verified once or expired.
+func (h *Auth) Captcha(ctx context.Context, in *msg.CaptchaRequest) (*msg.CaptchaResponse, error) {
+	var question, answer string
+	var err error
+	switch in.CaptchaType {
+	case "", CaptchaDigit:
+		question, err = randDigits(captchaDigits)
+		answer = question
+	case CaptchaMath:
+		question, answer, err = randArithmetic()
+	default:
+		return nil, errors.InvalidArgument("unknown captcha type %q", in.CaptchaType)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "generate captcha failed")
+	}
+
+	out := &msg.CaptchaResponse{
+		CaptchaId: uuid.NewString(),
+		ExpiresIn: int64(captchaTTL / time.Second),
+	}
+	raw, err := renderCaptcha(question)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "render captcha failed")
+	}
+	out.Base64Captcha = base64.StdEncoding.EncodeToString(raw)
+
+	if err := h.Cache.StoreOnce(context.Background(), captchaKey(out.CaptchaId), answer, captchaTTL); err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "store captcha failed")
+	}
+	return out, nil
+}
+
+// verifyCaptcha takes the answer of the captcha, so a captcha can't be tried twice.
+func (h *Auth) verifyCaptcha(v *msg.CaptchaVerify) bool {
+	if v == nil || v.CaptchaId == "" {
+		return false
+	}
+	answer, has := h.Cache.TakeOnce(context.Background(), captchaKey(v.CaptchaId))
+	if !has {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(answer), []byte(strings.TrimSpace(v.Answer))) == 1
+}
+
+func captchaKey(id string) string {
+	return "captcha:" + id
+}
+
+func randInt(n int64) (int64, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(n))
+	if err != nil {
+		return 0, err
+	}
+	return v.Int64(), nil
+}
+
+func randDigits(n int) (string, error) {
+	buf := make([]byte, n)
+	for i := range buf {
+		d, err := randInt(10)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = byte('0' + d)
+	}
+	return string(buf), nil
+}
+
+// randArithmetic is a question like "12 + 7 = ?", the answer is never negative.
+func randArithmetic() (question string, answer string, err error) {
+	op, err := randInt(3)
+	if err != nil {
+		return "", "", err
+	}
+	max := int64(20)
+	if op == 2 {
+		max = 9
+	}
+	a, err := randInt(max)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := randInt(max)
+	if err != nil {
+		return "", "", err
+	}
+	a, b = a+1, b+1
+	switch op {
+	case 0:
+		return fmt.Sprintf("%d + %d = ?", a, b), fmt.Sprint(a + b), nil
+	case 1:
+		if a < b {
+			a, b = b, a
+		}
+		return fmt.Sprintf("%d - %d = ?", a, b), fmt.Sprint(a - b), nil
+	default:
+		return fmt.Sprintf("%d x %d = ?", a, b), fmt.Sprint(a * b), nil
+	}
+}
+
+// captchaGlyphs is a 5x7 bitmap font, a row is the low 5 bits from left to right.
+var captchaGlyphs = map[rune][7]uint8{
+	'0': {0x0E, 0x11, 0x13, 0x15, 0x19, 0x11, 0x0E},
+	'1': {0x04, 0x0C, 0x04, 0x04, 0x04, 0x04, 0x0E},
+	'2': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x08, 0x1F},
+	'3': {0x1F, 0x02, 0x04, 0x02, 0x01, 0x11, 0x0E},
+	'4': {0x02, 0x06, 0x0A, 0x12, 0x1F, 0x02, 0x02},
+	'5': {0x1F, 0x10, 0x1E, 0x01, 0x01, 0x11, 0x0E},
+	'6': {0x06, 0x08, 0x10, 0x1E, 0x11, 0x11, 0x0E},
+	'7': {0x1F, 0x01, 0x02, 0x04, 0x08, 0x08, 0x08},
+	'8': {0x0E, 0x11, 0x11, 0x0E, 0x11, 0x11, 0x0E},
+	'9': {0x0E, 0x11, 0x11, 0x0F, 0x01, 0x02, 0x0C},
+	'+': {0x00, 0x04, 0x04, 0x1F, 0x04, 0x04, 0x00},
+	'-': {0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00},
+	'x': {0x00, 0x11, 0x0A, 0x04, 0x0A, 0x11, 0x00},
+	'=': {0x00, 0x00, 0x1F, 0x00, 0x1F, 0x00, 0x00},
+	'?': {0x0E, 0x11, 0x01, 0x02, 0x04, 0x00, 0x04},
+}
+
+const (
+	captchaScale   = 4
+	captchaAdvance = 6 * captchaScale
+	captchaPadding = 8
+	captchaHeight  = 7*captchaScale + 4*captchaPadding
+)
+
+// renderCaptcha draws the text with the jittered glyphs, the noise lines and dots, and encodes it as png.
+func renderCaptcha(text string) ([]byte, error) {
+	text = strings.ReplaceAll(text, " ", "")
+	width := len(text)*captchaAdvance + 2*captchaPadding
+	img := image.NewRGBA(image.Rect(0, 0, width, captchaHeight))
+	rnd := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+
+	background := color.RGBA{uint8(220 + rnd.Intn(36)), uint8(220 + rnd.Intn(36)), uint8(220 + rnd.Intn(36)), 255}
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = background.R, background.G, background.B, background.A
+	}
+	randInk := func() color.RGBA {
+		return color.RGBA{uint8(rnd.Intn(120)), uint8(rnd.Intn(120)), uint8(rnd.Intn(120)), 255}
+	}
+
+	for i, r := range text {
+		glyph, has := captchaGlyphs[r]
+		if !has {
+			return nil, fmt.Errorf("no glyph of %q", r)
+		}
+		ink := randInk()
+		x0 := captchaPadding + i*captchaAdvance + rnd.Intn(5) - 2
+		y0 := 2*captchaPadding + rnd.Intn(2*captchaPadding+1) - captchaPadding
+		for row, bits := range glyph {
+			// shear the rows a little, like the italic.
+			shift := (3 - row) * (rnd.Intn(3) - 1)
+			for col := 0; col < 5; col++ {
+				if bits&(1<<(4-col)) == 0 {
+					continue
+				}
+				fillRect(img, x0+col*captchaScale+shift, y0+row*captchaScale, captchaScale, captchaScale, ink)
+			}
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		drawLine(img, rnd.Intn(width), rnd.Intn(captchaHeight), rnd.Intn(width), rnd.Intn(captchaHeight), randInk())
+	}
+	for i := 0; i < width*captchaHeight/40; i++ {
+		img.Set(rnd.Intn(width), rnd.Intn(captchaHeight), randInk())
+	}
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func fillRect(img *image.RGBA, x, y, w, h int, c color.RGBA) {
+	for i := x; i < x+w; i++ {
+		for j := y; j < y+h; j++ {
+			img.SetRGBA(i, j, c)
+		}
+	}
+}
+
+// drawLine is the Bresenham's line.
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		img.SetRGBA(x0, y0, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image/png"
+	"testing"
+	"time"
+
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+)
+
+func TestCaptcha(t *testing.T) {
+	h := &Auth{AuthOptions: AuthOptions{Cache: cache.NewMemory()}}
+
+	out, err := h.Captcha(nil, &msg.CaptchaRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(out.Base64Captcha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != captchaDigits*captchaAdvance+2*captchaPadding || img.Bounds().Dy() != captchaHeight {
+		t.Fatal("wrong image size:", img.Bounds())
+	}
+
+	// peek the answer, then the wrong verify takes it, the right one fails after that.
+	answer, _ := h.Cache.TakeOnce(context.Background(), captchaKey(out.CaptchaId))
+	h.Cache.StoreOnce(context.Background(), captchaKey(out.CaptchaId), answer, time.Minute)
+	if h.verifyCaptcha(&msg.CaptchaVerify{CaptchaId: out.CaptchaId, Answer: "wrong"}) {
+		t.Fatal("a wrong answer is verified")
+	}
+	if h.verifyCaptcha(&msg.CaptchaVerify{CaptchaId: out.CaptchaId, Answer: answer}) {
+		t.Fatal("the captcha is verified twice")
+	}
+
+	math, err := h.Captcha(nil, &msg.CaptchaRequest{CaptchaType: CaptchaMath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := base64.StdEncoding.DecodeString(math.Base64Captcha); err != nil || math.Base64Captcha == "" {
+		t.Fatal("the math captcha is not an image:", err)
+	}
+
+	// the question is never sent as text, a bot would read it.
+	for _, typ := range []string{"text", "unknown"} {
+		if _, err := h.Captcha(nil, &msg.CaptchaRequest{CaptchaType: typ}); err == nil {
+			t.Fatalf("the captcha type %q is issued", typ)
+		}
+	}
+}
+
+func TestRandArithmetic(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		question, answer, err := randArithmetic()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var a, b int
+		var op string
+		if _, err := fmt.Sscanf(question, "%d %s %d = ?", &a, &op, &b); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]int{"+": a + b, "-": a - b, "x": a * b}[op]
+		if answer != fmt.Sprint(want) || want < 0 {
+			t.Fatal("wrong answer:", question, answer)
+		}
+		if _, err := renderCaptcha(question); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoginFailures(t *testing.T) {
+	ctx := context.Background()
+	f := &loginFailures{cache: cache.NewMemory(), window: time.Minute, captchaAfter: 2}
+
+	f.fail(ctx, "surf_user", "1.2.3.4")
+	if f.captchaRequired(ctx, "surf_user", "1.2.3.4") {
+		t.Fatal("the captcha is required before the limit")
+	}
+	f.fail(ctx, "surf_user", "1.2.3.4")
+	if !f.captchaRequired(ctx, "surf_user", "5.6.7.8") {
+		t.Fatal("the captcha is not required by the account failures")
+	}
+	if !f.captchaRequired(ctx, "other_user", "1.2.3.4") {
+		t.Fatal("the captcha is not required by the ip failures")
+	}
+
+	// the successful login clears the account, but not the ip.
+	f.succeed(ctx, "surf_user")
+	if f.captchaRequired(ctx, "surf_user", "5.6.7.8") {
+		t.Fatal("the account failures are not cleared")
+	}
+	if !f.captchaRequired(ctx, "surf_user", "1.2.3.4") {
+		t.Fatal("the ip failures are cleared")
+	}
+
+	if remoteIP("1.2.3.4:5678") != "1.2.3.4" || remoteIP("[::1]:80") != "::1" || remoteIP("") != "" {
+		t.Fatal("wrong remote ip")
+	}
+}
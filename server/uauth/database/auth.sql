@@ -5,17 +5,75 @@ use auth;
 CREATE TABLE IF NOT EXISTS `users` (
   `uid` bigint(20) NOT NULL AUTO_INCREMENT COMMENT 'user unique id',
   `uname` varchar(64) CHARACTER SET utf8mb4 NOT NULL COMMENT 'user name',
-  `passwd` varchar(64) NOT NULL DEFAULT '' COMMENT 'password',
+  `passwd` varchar(128) NOT NULL DEFAULT '' COMMENT 'pbkdf2 hash of the password, plaintext rows are hashed at login',
   `nickname` varchar(64) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
   `avatar` varchar(1024) NOT NULL DEFAULT '',
   `gender` tinyint(4) NOT NULL DEFAULT 0,
   `phone` varchar(32) NOT NULL DEFAULT '',
   `email` varchar(64) NOT NULL DEFAULT '',
   `stat` tinyint(4) NOT NULL DEFAULT 0 COMMENT 'user status code',
+  `guest` tinyint(4) NOT NULL DEFAULT 0 COMMENT 'a guest of a device until it is upgraded',
+  `role` int(10) unsigned NOT NULL DEFAULT 1 COMMENT 'core.Role, the admin role is granted only after the second factor',
   `create_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
   `update_at` datetime NOT NULL ON UPDATE CURRENT_TIMESTAMP DEFAULT CURRENT_TIMESTAMP COMMENT '',
   PRIMARY KEY (`uid`),
   UNIQUE KEY `UQE_uname` (`uname`)
 ) ENGINE = InnoDB AUTO_INCREMENT = 100000 DEFAULT CHARSET = utf8mb4;
 
+CREATE TABLE IF NOT EXISTS `guest_devices` (
+  `device_id` varchar(64) NOT NULL COMMENT 'the device of the guest',
+  `uid` bigint(20) NOT NULL COMMENT 'the guest user',
+  `secret` varchar(64) NOT NULL DEFAULT '' COMMENT 'sha256 of the device secret',
+  `create_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+  PRIMARY KEY (`device_id`),
+  KEY `IDX_uid` (`uid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_totps` (
+  `uid` bigint(20) NOT NULL COMMENT 'the user',
+  `secret` varchar(64) NOT NULL COMMENT 'base32 totp secret',
+  `enabled` tinyint(4) NOT NULL DEFAULT 0 COMMENT 'pending until a code of the secret is verified',
+  `last_step` bigint(20) NOT NULL DEFAULT 0 COMMENT 'the time step of the last used code, refuses the replays',
+  `create_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+  PRIMARY KEY (`uid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_recovery_codes` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `uid` bigint(20) NOT NULL COMMENT 'the user',
+  `code_hash` varchar(64) NOT NULL COMMENT 'sha256 of the recovery code, deleted when used',
+  `create_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+  PRIMARY KEY (`id`),
+  KEY `IDX_uid` (`uid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_sessions` (
+  `id` varchar(64) NOT NULL COMMENT 'the refresh token family of the login',
+  `uid` bigint(20) NOT NULL COMMENT 'the user',
+  `device_id` varchar(64) NOT NULL DEFAULT '',
+  `ip` varchar(64) NOT NULL DEFAULT '' COMMENT 'the ip of the last login or refresh',
+  `user_agent` varchar(256) NOT NULL DEFAULT '',
+  `token_id` varchar(64) NOT NULL DEFAULT '' COMMENT 'jti of the latest access token, revoked with the session',
+  `token_expire` bigint(20) NOT NULL DEFAULT 0 COMMENT 'expiry of the latest access token in unix seconds',
+  `create_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+  `last_seen_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'the last login or refresh',
+  `expire_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'the session can not be refreshed after it',
+  PRIMARY KEY (`id`),
+  KEY `IDX_uid` (`uid`),
+  KEY `IDX_expire_at` (`expire_at`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `user_identities` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `provider` varchar(32) NOT NULL COMMENT 'the name of the identity provider',
+  `subject` varchar(255) NOT NULL COMMENT 'the user in the provider',
+  `uid` bigint(20) NOT NULL COMMENT 'the user',
+  `email` varchar(64) NOT NULL DEFAULT '' COMMENT 'the email of the provider when linked',
+  `email_verified` tinyint(4) NOT NULL DEFAULT 0 COMMENT 'the email is verified by a trusted provider, the identities of other providers are linked by it',
+  `create_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `UK_provider_subject` (`provider`, `subject`),
+  UNIQUE KEY `UK_uid_provider` (`uid`, `provider`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4;
+
 insert into  users (uname, passwd, nickname) values ('test', '123456', 'test');
\ No newline at end of file
@@ -8,17 +8,65 @@ import (
 )
 
 type AuthCacheInfo struct {
-	User         *models.Users
-	AssessToken  string
+	User        *models.Users
+	AssessToken string
+	// RefreshToken is the family id of the refresh tokens of the login.
 	RefreshToken string
 	LoginAt      string
 	LoginIP      string
 }
 
+// RefreshFamily is the refresh tokens issued by a login, each use of the current token rotates it.
+type RefreshFamily struct {
+	ID       string
+	UID      int64
+	DeviceID string
+	// Role is granted by the login, the admin role only after the second factor.
+	Role uint32
+	// TokenHash is the sha256 of the current token, the rotated tokens are reuses.
+	TokenHash string
+	CreateAt  time.Time
+	ExpireAt  time.Time
+}
+
 type AuthCache interface {
-	StoreUser(ctx context.Context, user *AuthCacheInfo, expireAt time.Duration) error
+	// StoreUser replaces the cached user and its indexes, a zero expire never expires.
+	StoreUser(ctx context.Context, user *AuthCacheInfo, expire time.Duration) error
 	DeleteUser(ctx context.Context, uid int64)
 	FetchUser(ctx context.Context, uid int64) *AuthCacheInfo
 	FetchUserByName(ctx context.Context, uname string) *AuthCacheInfo
 	FetchUserByToken(ctx context.Context, token string) *AuthCacheInfo
+
+	StoreRefreshFamily(ctx context.Context, f *RefreshFamily) error
+	FetchRefreshFamily(ctx context.Context, id string) *RefreshFamily
+	// SwapRefreshToken replaces the token hash of the family if it is still the old one.
+	SwapRefreshToken(ctx context.Context, id string, oldHash string, newHash string) (bool, error)
+	DeleteRefreshFamily(ctx context.Context, id string)
+
+	// StoreOnce keeps the value, like a verification code, until it is taken or expired.
+	// The zero durations and times of the methods never expire.
+	StoreOnce(ctx context.Context, key string, value string, expire time.Duration) error
+	// TakeOnce returns and drops the value, a value can be taken only once.
+	TakeOnce(ctx context.Context, key string) (string, bool)
+
+	// RevokeToken denies the token id until the token expires.
+	RevokeToken(ctx context.Context, tokenID string, expireAt time.Time) error
+	IsTokenRevoked(ctx context.Context, tokenID string) bool
+	// RevokeUser denies the tokens and the refresh token families of the uid created before validAfter.
+	RevokeUser(ctx context.Context, uid int64, validAfter time.Time, expire time.Duration) error
+	// UserValidAfter is zero if the uid is not revoked.
+	UserValidAfter(ctx context.Context, uid int64) time.Time
+
+	// IncrCounter increases the counter, like the failed logins of an account, and returns the new count.
+	// The counter expires after the expire from its first increase.
+	IncrCounter(ctx context.Context, key string, expire time.Duration) (int64, error)
+	// FetchCounter is zero if the counter is not present or expired.
+	FetchCounter(ctx context.Context, key string) int64
+	DeleteCounter(ctx context.Context, key string)
+
+	// Lock locks the key, like an account, until the time.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil is zero if the key is not locked.
+	LockedUntil(ctx context.Context, key string) time.Time
+	Unlock(ctx context.Context, key string)
 }
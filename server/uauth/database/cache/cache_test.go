@@ -0,0 +1,322 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+func TestMemory(t *testing.T) {
+	testAuthCache(t, NewMemory(), false)
+}
+
+// TestMemorySweep checks a write sweeps a bounded number of entries, and the expired ones are swept by the writes.
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory().(*Memory)
+	past := time.Now().Add(-time.Minute)
+	for i := 0; i < 1000; i++ {
+		m.counters[strconv.Itoa(i)] = counter{n: 1, expireAt: past}
+	}
+
+	ctx := context.Background()
+	m.IncrCounter(ctx, "live", time.Minute)
+	if len(m.counters) < 1000-sweepLimit+1 {
+		t.Fatalf("a write swept %d entries", 1001-len(m.counters))
+	}
+	for i := 0; len(m.counters) > 1; i++ {
+		if i > 10000 {
+			t.Fatal("the expired entries are not swept:", len(m.counters))
+		}
+		m.IncrCounter(ctx, "live", time.Minute)
+	}
+	if m.FetchCounter(ctx, "live") == 0 {
+		t.Fatal("the live counter is swept")
+	}
+}
+
+func TestNoop(t *testing.T) {
+	testAuthCache(t, Noop{}, true)
+}
+
+// TestRedis runs against the stand-in, or the redis of SURF_TEST_REDIS like "127.0.0.1:6379".
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("SURF_TEST_REDIS")
+	if addr == "" {
+		addr = newStandin(t).Addr()
+	}
+	rds := redis.NewClient(&redis.Options{Addr: addr, DisableIndentity: true})
+	defer rds.Close()
+	prefix := "surf_test:" + time.Now().Format("150405.000000") + ":"
+	testAuthCache(t, NewRedis(rds, prefix), false)
+}
+
+// testAuthCache is the conformance suite of the AuthCache implementations.
+// A forgetful cache, like Noop, misses everything, but never returns a wrong value.
+func testAuthCache(t *testing.T, c AuthCache, forgetful bool) {
+	ctx := context.Background()
+
+	expectUser := func(t *testing.T, got *AuthCacheInfo, uid int64, token string) {
+		t.Helper()
+		if forgetful {
+			if got != nil {
+				t.Fatal("a forgetful cache returns a user:", got.User)
+			}
+			return
+		}
+		if got == nil || got.User == nil || got.User.UID != uid || got.AssessToken != token {
+			t.Fatalf("expected uid %d with token %s, got %+v", uid, token, got)
+		}
+	}
+	expectMiss := func(t *testing.T, got *AuthCacheInfo) {
+		t.Helper()
+		if got != nil {
+			t.Fatal("expected a miss, got", got.User)
+		}
+	}
+
+	t.Run("User", func(t *testing.T) {
+		user := &models.Users{UID: 10001, Uname: "surf_user", Nickname: "surf"}
+		if err := c.StoreUser(ctx, &AuthCacheInfo{User: user, AssessToken: "token-1"}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		expectUser(t, c.FetchUser(ctx, 10001), 10001, "token-1")
+		expectUser(t, c.FetchUserByName(ctx, "surf_user"), 10001, "token-1")
+		expectUser(t, c.FetchUserByToken(ctx, "token-1"), 10001, "token-1")
+		expectMiss(t, c.FetchUser(ctx, 10002))
+		expectMiss(t, c.FetchUserByName(ctx, "unknown"))
+		expectMiss(t, c.FetchUserByToken(ctx, "unknown"))
+
+		// the indexes of the replaced login are dropped.
+		renamed := &models.Users{UID: 10001, Uname: "surf_user2"}
+		if err := c.StoreUser(ctx, &AuthCacheInfo{User: renamed, AssessToken: "token-2"}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		expectMiss(t, c.FetchUserByName(ctx, "surf_user"))
+		expectMiss(t, c.FetchUserByToken(ctx, "token-1"))
+		expectUser(t, c.FetchUserByName(ctx, "surf_user2"), 10001, "token-2")
+		expectUser(t, c.FetchUserByToken(ctx, "token-2"), 10001, "token-2")
+
+		c.DeleteUser(ctx, 10001)
+		expectMiss(t, c.FetchUser(ctx, 10001))
+		expectMiss(t, c.FetchUserByName(ctx, "surf_user2"))
+		expectMiss(t, c.FetchUserByToken(ctx, "token-2"))
+	})
+
+	t.Run("UserExpire", func(t *testing.T) {
+		user := &models.Users{UID: 10003, Uname: "surf_expire"}
+		if err := c.StoreUser(ctx, &AuthCacheInfo{User: user, AssessToken: "token-3"}, 50*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		expectUser(t, c.FetchUser(ctx, 10003), 10003, "token-3")
+		time.Sleep(100 * time.Millisecond)
+		expectMiss(t, c.FetchUser(ctx, 10003))
+		expectMiss(t, c.FetchUserByName(ctx, "surf_expire"))
+		expectMiss(t, c.FetchUserByToken(ctx, "token-3"))
+	})
+
+	t.Run("RefreshFamily", func(t *testing.T) {
+		now := time.Now()
+		f := &RefreshFamily{ID: "family-1", UID: 10001, TokenHash: "hash-1", CreateAt: now, ExpireAt: now.Add(time.Minute)}
+		if err := c.StoreRefreshFamily(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+		if forgetful {
+			if c.FetchRefreshFamily(ctx, f.ID) != nil {
+				t.Fatal("a forgetful cache returns a family")
+			}
+			if swapped, err := c.SwapRefreshToken(ctx, f.ID, "hash-1", "hash-2"); swapped || err != nil {
+				t.Fatal("a forgetful cache swaps:", swapped, err)
+			}
+			return
+		}
+		if got := c.FetchRefreshFamily(ctx, f.ID); got == nil || got.TokenHash != "hash-1" || got.UID != 10001 {
+			t.Fatalf("wrong family: %+v", got)
+		}
+
+		if swapped, err := c.SwapRefreshToken(ctx, f.ID, "wrong", "hash-2"); swapped || err != nil {
+			t.Fatal("swap the wrong hash:", swapped, err)
+		}
+		if swapped, err := c.SwapRefreshToken(ctx, f.ID, "hash-1", "hash-2"); !swapped || err != nil {
+			t.Fatal("swap the current hash:", swapped, err)
+		}
+		if got := c.FetchRefreshFamily(ctx, f.ID); got == nil || got.TokenHash != "hash-2" {
+			t.Fatalf("the hash is not swapped: %+v", got)
+		}
+
+		// only one of the concurrent swaps of the same hash wins.
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		wins := 0
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				swapped, err := c.SwapRefreshToken(ctx, f.ID, "hash-2", "hash-3")
+				if err != nil {
+					t.Error(err)
+				}
+				if swapped {
+					mu.Lock()
+					wins++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		if wins != 1 {
+			t.Fatal("concurrent swaps win:", wins)
+		}
+
+		c.DeleteRefreshFamily(ctx, f.ID)
+		if c.FetchRefreshFamily(ctx, f.ID) != nil {
+			t.Fatal("the family is not deleted")
+		}
+
+		short := &RefreshFamily{ID: "family-2", UID: 10001, TokenHash: "hash-1", CreateAt: now, ExpireAt: time.Now().Add(50 * time.Millisecond)}
+		if err := c.StoreRefreshFamily(ctx, short); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if c.FetchRefreshFamily(ctx, short.ID) != nil {
+			t.Fatal("the expired family is fetched")
+		}
+		if swapped, _ := c.SwapRefreshToken(ctx, short.ID, "hash-1", "hash-2"); swapped {
+			t.Fatal("the expired family is swapped")
+		}
+	})
+
+	t.Run("Once", func(t *testing.T) {
+		if err := c.StoreOnce(ctx, "code", "123456", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		value, has := c.TakeOnce(ctx, "code")
+		if forgetful {
+			if has {
+				t.Fatal("a forgetful cache takes a value")
+			}
+			return
+		}
+		if !has || value != "123456" {
+			t.Fatal("wrong value:", value, has)
+		}
+		if _, has := c.TakeOnce(ctx, "code"); has {
+			t.Fatal("the value is taken twice")
+		}
+
+		if err := c.StoreOnce(ctx, "short", "123456", 50*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if _, has := c.TakeOnce(ctx, "short"); has {
+			t.Fatal("the expired value is taken")
+		}
+	})
+
+	t.Run("Revocation", func(t *testing.T) {
+		if err := c.RevokeToken(ctx, "jti-1", time.Now().Add(time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.RevokeToken(ctx, "jti-2", time.Now().Add(50*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+		validAfter := time.Now()
+		if err := c.RevokeUser(ctx, 10001, validAfter, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		// a later revocation never moves valid after back.
+		if err := c.RevokeUser(ctx, 10001, validAfter.Add(-time.Hour), time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if c.IsTokenRevoked(ctx, "jti-3") || !c.UserValidAfter(ctx, 10002).IsZero() {
+			t.Fatal("an unknown token or user is revoked")
+		}
+		if forgetful {
+			if c.IsTokenRevoked(ctx, "jti-1") || !c.UserValidAfter(ctx, 10001).IsZero() {
+				t.Fatal("a forgetful cache keeps revocations")
+			}
+			return
+		}
+		if !c.IsTokenRevoked(ctx, "jti-1") || !c.IsTokenRevoked(ctx, "jti-2") {
+			t.Fatal("the token is not revoked")
+		}
+		if got := c.UserValidAfter(ctx, 10001); !got.Equal(validAfter) {
+			t.Fatal("wrong valid after:", got, validAfter)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if c.IsTokenRevoked(ctx, "jti-2") {
+			t.Fatal("the expired revocation is kept")
+		}
+	})
+
+	t.Run("Counter", func(t *testing.T) {
+		for i := int64(1); i <= 3; i++ {
+			n, err := c.IncrCounter(ctx, "fails", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !forgetful && n != i {
+				t.Fatal("wrong count:", n, i)
+			}
+		}
+		if got := c.FetchCounter(ctx, "fails"); forgetful && got != 0 || !forgetful && got != 3 {
+			t.Fatal("wrong count:", got)
+		}
+		c.DeleteCounter(ctx, "fails")
+		if c.FetchCounter(ctx, "fails") != 0 || c.FetchCounter(ctx, "unknown") != 0 {
+			t.Fatal("the deleted counter is counted")
+		}
+
+		// the window starts at the first increase, the later ones don't extend it.
+		c.IncrCounter(ctx, "short", 100*time.Millisecond)
+		time.Sleep(60 * time.Millisecond)
+		c.IncrCounter(ctx, "short", 100*time.Millisecond)
+		time.Sleep(60 * time.Millisecond)
+		if c.FetchCounter(ctx, "short") != 0 {
+			t.Fatal("the expired counter is counted")
+		}
+		// an increase after the expiry starts a new window.
+		n, err := c.IncrCounter(ctx, "short", time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !forgetful && (n != 1 || c.FetchCounter(ctx, "short") != 1) {
+			t.Fatal("the expired counter is increased:", n)
+		}
+	})
+	t.Run("Lock", func(t *testing.T) {
+		until := time.Now().Add(time.Minute)
+		if err := c.Lock(ctx, "uname:surf_user", until); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Lock(ctx, "uname:short", time.Now().Add(50*time.Millisecond)); err != nil {
+			t.Fatal(err)
+		}
+		if !c.LockedUntil(ctx, "uname:unknown").IsZero() {
+			t.Fatal("an unknown key is locked")
+		}
+		if forgetful {
+			if !c.LockedUntil(ctx, "uname:surf_user").IsZero() {
+				t.Fatal("a forgetful cache keeps locks")
+			}
+			return
+		}
+		if got := c.LockedUntil(ctx, "uname:surf_user"); !got.Equal(until) {
+			t.Fatal("wrong locked until:", got, until)
+		}
+		c.Unlock(ctx, "uname:surf_user")
+		if !c.LockedUntil(ctx, "uname:surf_user").IsZero() {
+			t.Fatal("the key is not unlocked")
+		}
+		time.Sleep(100 * time.Millisecond)
+		if !c.LockedUntil(ctx, "uname:short").IsZero() {
+			t.Fatal("the expired lock is kept")
+		}
+	})
+}
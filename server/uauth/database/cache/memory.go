@@ -8,61 +8,311 @@ import (
 
 func NewMemory() AuthCache {
 	return &Memory{
-		cache:    make(map[int64]*AuthCacheInfo),
+		cache:    make(map[int64]*cachedUser),
 		name2id:  make(map[string]int64),
 		token2id: make(map[string]int64),
+		families: make(map[string]*RefreshFamily),
+		once:     make(map[string]onceValue),
+		revoked:  make(map[string]time.Time),
+		validAt:  make(map[int64]revokedUser),
+		counters: make(map[string]counter),
+		locks:    make(map[string]time.Time),
 	}
 }
 
+type cachedUser struct {
+	info *AuthCacheInfo
+	// expireAt is zero if the user never expires.
+	expireAt time.Time
+}
+
+func (u *cachedUser) expired(now time.Time) bool {
+	return expired(u.expireAt, now)
+}
+
+// expired tells whether the time is passed, a zero time never expires.
+func expired(expireAt time.Time, now time.Time) bool {
+	return !expireAt.IsZero() && now.After(expireAt)
+}
+
+// expireAfter is zero if the duration is zero, which never expires.
+func expireAfter(now time.Time, expire time.Duration) time.Time {
+	if expire <= 0 {
+		return time.Time{}
+	}
+	return now.Add(expire)
+}
+
+// sweepLimit is the most entries a write checks for expiry, so a write costs the same however many entries are kept.
+// The random order of the map iteration spreads the sweeps over all the entries.
+const sweepLimit = 32
+
+// sweep deletes the expired ones of at most sweepLimit entries of the map, it must be called with the write lock.
+func sweep[K comparable, V any](m map[K]V, expired func(V) bool) {
+	n := 0
+	for k, v := range m {
+		if n++; n > sweepLimit {
+			return
+		}
+		if expired(v) {
+			delete(m, k)
+		}
+	}
+}
+
+type onceValue struct {
+	value    string
+	expireAt time.Time
+}
+
 type Memory struct {
 	rwLock   sync.RWMutex
-	cache    map[int64]*AuthCacheInfo
+	cache    map[int64]*cachedUser
 	name2id  map[string]int64
 	token2id map[string]int64
+	families map[string]*RefreshFamily
+	once     map[string]onceValue
+	revoked  map[string]time.Time // token id -> expire at
+	validAt  map[int64]revokedUser
+	counters map[string]counter
+	locks    map[string]time.Time
+}
+
+type counter struct {
+	n        int64
+	expireAt time.Time
 }
 
-func (m *Memory) StoreUser(ctx context.Context, user *AuthCacheInfo, exprieAt time.Duration) error {
-	m.DeleteUser(ctx, user.User.UID)
+type revokedUser struct {
+	validAfter time.Time
+	expireAt   time.Time
+}
 
+func (m *Memory) StoreUser(ctx context.Context, user *AuthCacheInfo, expire time.Duration) error {
 	m.rwLock.Lock()
 	defer m.rwLock.Unlock()
 
-	m.cache[user.User.UID] = user
+	now := time.Now()
+	n := 0
+	for uid, u := range m.cache {
+		if n++; n > sweepLimit {
+			break
+		}
+		if u.expired(now) {
+			m.deleteUser(uid)
+		}
+	}
+	m.deleteUser(user.User.UID)
+
+	m.cache[user.User.UID] = &cachedUser{info: user, expireAt: expireAfter(now, expire)}
 	m.name2id[user.User.Uname] = user.User.UID
-	m.token2id[user.AssessToken] = user.User.UID
+	if user.AssessToken != "" {
+		m.token2id[user.AssessToken] = user.User.UID
+	}
 	return nil
 }
 
-func (m *Memory) DeleteUser(ctx context.Context, uid int64) {
-	m.rwLock.RLock()
-	defer m.rwLock.RUnlock()
-
+// deleteUser must be called with the write lock.
+func (m *Memory) deleteUser(uid int64) {
 	if u, has := m.cache[uid]; has {
 		delete(m.cache, uid)
-		delete(m.name2id, u.User.Uname)
-		delete(m.token2id, u.AssessToken)
+		delete(m.name2id, u.info.User.Uname)
+		delete(m.token2id, u.info.AssessToken)
+	}
+}
+
+func (m *Memory) DeleteUser(ctx context.Context, uid int64) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	m.deleteUser(uid)
+}
+
+// fetchUser must be called with the lock.
+func (m *Memory) fetchUser(uid int64) *AuthCacheInfo {
+	u, has := m.cache[uid]
+	if !has || u.expired(time.Now()) {
+		return nil
 	}
+	return u.info
 }
 
 func (m *Memory) FetchUser(ctx context.Context, uid int64) *AuthCacheInfo {
 	m.rwLock.RLock()
 	defer m.rwLock.RUnlock()
-	return m.cache[uid]
+	return m.fetchUser(uid)
 }
 
 func (m *Memory) FetchUserByName(ctx context.Context, uname string) *AuthCacheInfo {
 	m.rwLock.RLock()
 	defer m.rwLock.RUnlock()
-	uid := m.name2id[uname]
-	return m.cache[uid]
+	uid, has := m.name2id[uname]
+	if !has {
+		return nil
+	}
+	return m.fetchUser(uid)
 }
 
 func (m *Memory) FetchUserByToken(ctx context.Context, AccessToken string) *AuthCacheInfo {
 	m.rwLock.RLock()
 	defer m.rwLock.RUnlock()
 	uid, has := m.token2id[AccessToken]
-	if has {
-		return m.cache[uid]
+	if !has {
+		return nil
 	}
+	return m.fetchUser(uid)
+}
+
+func (m *Memory) StoreRefreshFamily(ctx context.Context, f *RefreshFamily) error {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	now := time.Now()
+	sweep(m.families, func(f *RefreshFamily) bool { return now.After(f.ExpireAt) })
+	cp := *f
+	m.families[f.ID] = &cp
 	return nil
 }
+
+func (m *Memory) FetchRefreshFamily(ctx context.Context, id string) *RefreshFamily {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	f, has := m.families[id]
+	if !has || time.Now().After(f.ExpireAt) {
+		return nil
+	}
+	cp := *f
+	return &cp
+}
+
+func (m *Memory) SwapRefreshToken(ctx context.Context, id string, oldHash string, newHash string) (bool, error) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	f, has := m.families[id]
+	if !has || time.Now().After(f.ExpireAt) || f.TokenHash != oldHash {
+		return false, nil
+	}
+	f.TokenHash = newHash
+	return true, nil
+}
+
+func (m *Memory) DeleteRefreshFamily(ctx context.Context, id string) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	delete(m.families, id)
+}
+
+func (m *Memory) StoreOnce(ctx context.Context, key string, value string, expire time.Duration) error {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	now := time.Now()
+	sweep(m.once, func(v onceValue) bool { return expired(v.expireAt, now) })
+	m.once[key] = onceValue{value: value, expireAt: expireAfter(now, expire)}
+	return nil
+}
+
+func (m *Memory) TakeOnce(ctx context.Context, key string) (string, bool) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	v, has := m.once[key]
+	if !has {
+		return "", false
+	}
+	delete(m.once, key)
+	if expired(v.expireAt, time.Now()) {
+		return "", false
+	}
+	return v.value, true
+}
+
+func (m *Memory) RevokeToken(ctx context.Context, tokenID string, expireAt time.Time) error {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	now := time.Now()
+	sweep(m.revoked, func(exp time.Time) bool { return expired(exp, now) })
+	m.revoked[tokenID] = expireAt
+	return nil
+}
+
+func (m *Memory) IsTokenRevoked(ctx context.Context, tokenID string) bool {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	exp, has := m.revoked[tokenID]
+	return has && !expired(exp, time.Now())
+}
+
+func (m *Memory) RevokeUser(ctx context.Context, uid int64, validAfter time.Time, expire time.Duration) error {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	now := time.Now()
+	sweep(m.validAt, func(u revokedUser) bool { return expired(u.expireAt, now) })
+	if old, has := m.validAt[uid]; has && old.validAfter.After(validAfter) {
+		validAfter = old.validAfter
+	}
+	m.validAt[uid] = revokedUser{validAfter: validAfter, expireAt: expireAfter(now, expire)}
+	return nil
+}
+
+func (m *Memory) UserValidAfter(ctx context.Context, uid int64) time.Time {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	u, has := m.validAt[uid]
+	if !has || expired(u.expireAt, time.Now()) {
+		return time.Time{}
+	}
+	return u.validAfter
+}
+
+func (m *Memory) IncrCounter(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	now := time.Now()
+	sweep(m.counters, func(c counter) bool { return expired(c.expireAt, now) })
+	// the sweep is bounded, an expired counter may be still here, it starts over like a new one.
+	c, has := m.counters[key]
+	if !has || expired(c.expireAt, now) {
+		c = counter{expireAt: expireAfter(now, expire)}
+	}
+	c.n++
+	m.counters[key] = c
+	return c.n, nil
+}
+
+func (m *Memory) FetchCounter(ctx context.Context, key string) int64 {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	c, has := m.counters[key]
+	if !has || expired(c.expireAt, time.Now()) {
+		return 0
+	}
+	return c.n
+}
+
+func (m *Memory) DeleteCounter(ctx context.Context, key string) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	delete(m.counters, key)
+}
+
+func (m *Memory) Lock(ctx context.Context, key string, until time.Time) error {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	now := time.Now()
+	sweep(m.locks, func(at time.Time) bool { return !now.Before(at) })
+	m.locks[key] = until
+	return nil
+}
+
+func (m *Memory) LockedUntil(ctx context.Context, key string) time.Time {
+	m.rwLock.RLock()
+	defer m.rwLock.RUnlock()
+	at, has := m.locks[key]
+	if !has || !time.Now().Before(at) {
+		return time.Time{}
+	}
+	return at
+}
+
+func (m *Memory) Unlock(ctx context.Context, key string) {
+	m.rwLock.Lock()
+	defer m.rwLock.Unlock()
+	delete(m.locks, key)
+}
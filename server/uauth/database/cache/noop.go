@@ -2,23 +2,64 @@ package cache
 
 import (
 	"context"
-
-	"github.com/ajenpan/surf/server/uauth/database/models"
+	"time"
 )
 
+// Noop keeps nothing, every fetch is a miss.
+// The refresh tokens, the reset codes and the revocations do not work with it.
 type Noop struct {
 }
 
-func (Noop) StoreUser(ctx context.Context, user *AuthCacheInfo) error { return nil }
+var _ AuthCache = Noop{}
+
+func (Noop) StoreUser(ctx context.Context, user *AuthCacheInfo, expire time.Duration) error {
+	return nil
+}
+
+func (Noop) DeleteUser(ctx context.Context, uid int64) {}
+
+func (Noop) FetchUser(ctx context.Context, uid int64) *AuthCacheInfo { return nil }
+
+func (Noop) FetchUserByName(ctx context.Context, uname string) *AuthCacheInfo { return nil }
 
-func (Noop) FetchUser(ctx context.Context, uid int64) *AuthCacheInfo {
-	return &AuthCacheInfo{
-		User: &models.Users{UID: uid},
-	}
+func (Noop) FetchUserByToken(ctx context.Context, token string) *AuthCacheInfo { return nil }
+
+func (Noop) StoreRefreshFamily(ctx context.Context, f *RefreshFamily) error { return nil }
+
+func (Noop) FetchRefreshFamily(ctx context.Context, id string) *RefreshFamily { return nil }
+
+func (Noop) SwapRefreshToken(ctx context.Context, id string, oldHash string, newHash string) (bool, error) {
+	return false, nil
 }
 
-func (Noop) FetchUserByName(ctx context.Context, uname string) *AuthCacheInfo {
-	return &AuthCacheInfo{
-		User: &models.Users{Uname: uname},
-	}
+func (Noop) DeleteRefreshFamily(ctx context.Context, id string) {}
+
+func (Noop) StoreOnce(ctx context.Context, key string, value string, expire time.Duration) error {
+	return nil
 }
+
+func (Noop) TakeOnce(ctx context.Context, key string) (string, bool) { return "", false }
+
+func (Noop) RevokeToken(ctx context.Context, tokenID string, expireAt time.Time) error { return nil }
+
+func (Noop) IsTokenRevoked(ctx context.Context, tokenID string) bool { return false }
+
+func (Noop) RevokeUser(ctx context.Context, uid int64, validAfter time.Time, expire time.Duration) error {
+	return nil
+}
+
+func (Noop) UserValidAfter(ctx context.Context, uid int64) time.Time { return time.Time{} }
+
+func (Noop) IncrCounter(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	return 0, nil
+}
+
+func (Noop) FetchCounter(ctx context.Context, key string) int64 { return 0 }
+
+func (Noop) DeleteCounter(ctx context.Context, key string) {}
+
+func (Noop) Lock(ctx context.Context, key string, until time.Time) error { return nil }
+
+func (Noop) LockedUntil(ctx context.Context, key string) time.Time { return time.Time{} }
+
+func (Noop) Unlock(ctx context.Context, key string) {}
@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultRedisPrefix is the prefix of the keys of NewRedis if the prefix is empty.
+const DefaultRedisPrefix = "uauth:"
+
+// watchRetries is how many times a transaction is retried when its watched keys are changed.
+const watchRetries = 8
+
+// NewRedis keeps the cache in redis, so that the instances of uauth share the logins and revocations.
+// A user is stored as "<prefix>user:<uid>", and indexed by "<prefix>uname:<uname>" and "<prefix>token:<sha256 of token>",
+// the indexes expire with the user.
+func NewRedis(rds redis.UniversalClient, prefix string) AuthCache {
+	if prefix == "" {
+		prefix = DefaultRedisPrefix
+	}
+	return &Redis{rds: rds, prefix: prefix}
+}
+
+type Redis struct {
+	rds    redis.UniversalClient
+	prefix string
+}
+
+func (r *Redis) userKey(uid int64) string {
+	return r.prefix + "user:" + strconv.FormatInt(uid, 10)
+}
+
+func (r *Redis) unameKey(uname string) string {
+	return r.prefix + "uname:" + uname
+}
+
+func (r *Redis) tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return r.prefix + "token:" + hex.EncodeToString(sum[:])
+}
+
+func (r *Redis) familyKey(id string) string {
+	return r.prefix + "refresh:" + id
+}
+
+func (r *Redis) onceKey(key string) string {
+	return r.prefix + "once:" + key
+}
+
+func (r *Redis) revokedTokenKey(tokenID string) string {
+	return r.prefix + "revoked:" + tokenID
+}
+
+func (r *Redis) validAfterKey(uid int64) string {
+	return r.prefix + "validafter:" + strconv.FormatInt(uid, 10)
+}
+
+func (r *Redis) counterKey(key string) string {
+	return r.prefix + "counter:" + key
+}
+
+func (r *Redis) lockKey(key string) string {
+	return r.prefix + "lock:" + key
+}
+
+// ttlUntil is the ttl of a key which expires at the time, a zero time never expires.
+// It is at least 1ms, since a zero ttl means no expiry for redis.
+func ttlUntil(at time.Time) time.Duration {
+	if at.IsZero() {
+		return 0
+	}
+	if ttl := time.Until(at); ttl > time.Millisecond {
+		return ttl
+	}
+	return time.Millisecond
+}
+
+// watch runs the optimistic transaction, and retries it if the keys are changed by others.
+func (r *Redis) watch(ctx context.Context, fn func(*redis.Tx) error, keys ...string) error {
+	var err error
+	for i := 0; i < watchRetries; i++ {
+		err = r.rds.Watch(ctx, fn, keys...)
+		if err != redis.TxFailedErr {
+			return err
+		}
+	}
+	return err
+}
+
+func getUser(ctx context.Context, c redis.Cmdable, key string) (*AuthCacheInfo, error) {
+	raw, err := c.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := &AuthCacheInfo{}
+	if err := json.Unmarshal(raw, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (r *Redis) delUserIndexes(ctx context.Context, pipe redis.Pipeliner, old *AuthCacheInfo) {
+	if old == nil || old.User == nil {
+		return
+	}
+	pipe.Del(ctx, r.unameKey(old.User.Uname))
+	if old.AssessToken != "" {
+		pipe.Del(ctx, r.tokenKey(old.AssessToken))
+	}
+}
+
+func (r *Redis) StoreUser(ctx context.Context, user *AuthCacheInfo, expire time.Duration) error {
+	raw, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+	uid := strconv.FormatInt(user.User.UID, 10)
+	key := r.userKey(user.User.UID)
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		old, err := getUser(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.delUserIndexes(ctx, pipe, old)
+			pipe.Set(ctx, key, raw, expire)
+			pipe.Set(ctx, r.unameKey(user.User.Uname), uid, expire)
+			if user.AssessToken != "" {
+				pipe.Set(ctx, r.tokenKey(user.AssessToken), uid, expire)
+			}
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (r *Redis) DeleteUser(ctx context.Context, uid int64) {
+	key := r.userKey(uid)
+	r.watch(ctx, func(tx *redis.Tx) error {
+		old, err := getUser(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			r.delUserIndexes(ctx, pipe, old)
+			pipe.Del(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (r *Redis) FetchUser(ctx context.Context, uid int64) *AuthCacheInfo {
+	info, _ := getUser(ctx, r.rds, r.userKey(uid))
+	return info
+}
+
+// fetchByIndex follows the index to the user, an index left by an old login is a miss.
+func (r *Redis) fetchByIndex(ctx context.Context, index string, match func(*AuthCacheInfo) bool) *AuthCacheInfo {
+	uid, err := r.rds.Get(ctx, index).Int64()
+	if err != nil {
+		return nil
+	}
+	info, _ := getUser(ctx, r.rds, r.userKey(uid))
+	if info == nil || info.User == nil || !match(info) {
+		return nil
+	}
+	return info
+}
+
+func (r *Redis) FetchUserByName(ctx context.Context, uname string) *AuthCacheInfo {
+	return r.fetchByIndex(ctx, r.unameKey(uname), func(info *AuthCacheInfo) bool {
+		return info.User.Uname == uname
+	})
+}
+
+func (r *Redis) FetchUserByToken(ctx context.Context, token string) *AuthCacheInfo {
+	return r.fetchByIndex(ctx, r.tokenKey(token), func(info *AuthCacheInfo) bool {
+		return info.AssessToken == token
+	})
+}
+
+func (r *Redis) StoreRefreshFamily(ctx context.Context, f *RefreshFamily) error {
+	raw, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return r.rds.Set(ctx, r.familyKey(f.ID), raw, ttlUntil(f.ExpireAt)).Err()
+}
+
+func getFamily(ctx context.Context, c redis.Cmdable, key string) (*RefreshFamily, error) {
+	raw, err := c.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := &RefreshFamily{}
+	if err := json.Unmarshal(raw, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r *Redis) FetchRefreshFamily(ctx context.Context, id string) *RefreshFamily {
+	f, _ := getFamily(ctx, r.rds, r.familyKey(id))
+	if f == nil || time.Now().After(f.ExpireAt) {
+		return nil
+	}
+	return f
+}
+
+func (r *Redis) SwapRefreshToken(ctx context.Context, id string, oldHash string, newHash string) (bool, error) {
+	key := r.familyKey(id)
+	swapped := false
+	err := r.rds.Watch(ctx, func(tx *redis.Tx) error {
+		f, err := getFamily(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+		if f == nil || time.Now().After(f.ExpireAt) || f.TokenHash != oldHash {
+			return nil
+		}
+		f.TokenHash = newHash
+		raw, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SetArgs(ctx, key, raw, redis.SetArgs{KeepTTL: true})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		swapped = true
+		return nil
+	}, key)
+	// a concurrent change is not a swap, not an error.
+	if errors.Is(err, redis.TxFailedErr) {
+		return false, nil
+	}
+	return swapped, err
+}
+
+func (r *Redis) DeleteRefreshFamily(ctx context.Context, id string) {
+	r.rds.Del(ctx, r.familyKey(id))
+}
+
+func (r *Redis) StoreOnce(ctx context.Context, key string, value string, expire time.Duration) error {
+	return r.rds.Set(ctx, r.onceKey(key), value, expire).Err()
+}
+
+func (r *Redis) TakeOnce(ctx context.Context, key string) (string, bool) {
+	value, err := r.rds.GetDel(ctx, r.onceKey(key)).Result()
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+func (r *Redis) RevokeToken(ctx context.Context, tokenID string, expireAt time.Time) error {
+	return r.rds.Set(ctx, r.revokedTokenKey(tokenID), 1, ttlUntil(expireAt)).Err()
+}
+
+func (r *Redis) IsTokenRevoked(ctx context.Context, tokenID string) bool {
+	n, err := r.rds.Exists(ctx, r.revokedTokenKey(tokenID)).Result()
+	return err == nil && n > 0
+}
+
+func (r *Redis) RevokeUser(ctx context.Context, uid int64, validAfter time.Time, expire time.Duration) error {
+	key := r.validAfterKey(uid)
+	return r.watch(ctx, func(tx *redis.Tx) error {
+		old, err := tx.Get(ctx, key).Int64()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		at := validAfter.UnixNano()
+		if old > at {
+			at = old
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, at, expire)
+			return nil
+		})
+		return err
+	}, key)
+}
+
+func (r *Redis) UserValidAfter(ctx context.Context, uid int64) time.Time {
+	at, err := r.rds.Get(ctx, r.validAfterKey(uid)).Int64()
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+func (r *Redis) IncrCounter(ctx context.Context, key string, expire time.Duration) (int64, error) {
+	key = r.counterKey(key)
+	var incr *redis.IntCmd
+	_, err := r.rds.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// only the first increase sets the expire, INCR keeps it.
+		pipe.SetNX(ctx, key, 0, expire)
+		incr = pipe.Incr(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+func (r *Redis) FetchCounter(ctx context.Context, key string) int64 {
+	n, _ := r.rds.Get(ctx, r.counterKey(key)).Int64()
+	return n
+}
+
+func (r *Redis) DeleteCounter(ctx context.Context, key string) {
+	r.rds.Del(ctx, r.counterKey(key))
+}
+
+func (r *Redis) Lock(ctx context.Context, key string, until time.Time) error {
+	if !time.Now().Before(until) {
+		return nil
+	}
+	return r.rds.Set(ctx, r.lockKey(key), until.UnixNano(), ttlUntil(until)).Err()
+}
+
+func (r *Redis) LockedUntil(ctx context.Context, key string) time.Time {
+	at, err := r.rds.Get(ctx, r.lockKey(key)).Int64()
+	if err != nil || time.Now().UnixNano() >= at {
+		return time.Time{}
+	}
+	return time.Unix(0, at)
+}
+
+func (r *Redis) Unlock(ctx context.Context, key string) {
+	r.rds.Del(ctx, r.lockKey(key))
+}
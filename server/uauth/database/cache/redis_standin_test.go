@@ -0,0 +1,301 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standin is an in-process redis which speaks RESP2,
+// it supports only the commands used by Redis, and the transactions of WATCH/MULTI/EXEC.
+type standin struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	data     map[string]standinValue
+	versions map[string]uint64
+}
+
+type standinValue struct {
+	value    string
+	expireAt time.Time
+}
+
+func newStandin(t *testing.T) *standin {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &standin{
+		ln:       ln,
+		data:     make(map[string]standinValue),
+		versions: make(map[string]uint64),
+	}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *standin) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *standin) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+type standinConn struct {
+	watched map[string]uint64
+	queued  [][]string
+	multi   bool
+}
+
+func (s *standin) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	c := &standinConn{}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if _, err := conn.Write(s.handle(c, args)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("unexpected line %q", line)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func simpleReply(s string) []byte { return []byte("+" + s + "\r\n") }
+func errorReply(s string) []byte  { return []byte("-ERR " + s + "\r\n") }
+func intReply(n int) []byte       { return []byte(":" + strconv.Itoa(n) + "\r\n") }
+func nilReply() []byte            { return []byte("$-1\r\n") }
+func bulkReply(s string) []byte {
+	return []byte("$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n")
+}
+
+func (s *standin) handle(c *standinConn, args []string) []byte {
+	cmd := strings.ToUpper(args[0])
+	switch cmd {
+	case "MULTI":
+		c.multi = true
+		c.queued = nil
+		return simpleReply("OK")
+	case "DISCARD":
+		c.multi = false
+		c.queued = nil
+		c.watched = nil
+		return simpleReply("OK")
+	case "EXEC":
+		return s.exec(c)
+	case "WATCH":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if c.watched == nil {
+			c.watched = make(map[string]uint64)
+		}
+		for _, key := range args[1:] {
+			s.expire(key)
+			c.watched[key] = s.versions[key]
+		}
+		return simpleReply("OK")
+	case "UNWATCH":
+		c.watched = nil
+		return simpleReply("OK")
+	}
+	if c.multi {
+		c.queued = append(c.queued, args)
+		return simpleReply("QUEUED")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.run(args)
+}
+
+func (s *standin) exec(c *standinConn) []byte {
+	queued, watched := c.queued, c.watched
+	c.multi, c.queued, c.watched = false, nil, nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, version := range watched {
+		s.expire(key)
+		if s.versions[key] != version {
+			return []byte("*-1\r\n")
+		}
+	}
+	ret := []byte("*" + strconv.Itoa(len(queued)) + "\r\n")
+	for _, args := range queued {
+		ret = append(ret, s.run(args)...)
+	}
+	return ret
+}
+
+// expire drops the key if it is expired, must be called with the lock.
+func (s *standin) expire(key string) {
+	v, has := s.data[key]
+	if has && !v.expireAt.IsZero() && !time.Now().Before(v.expireAt) {
+		delete(s.data, key)
+		s.versions[key]++
+	}
+}
+
+func (s *standin) get(key string) (standinValue, bool) {
+	s.expire(key)
+	v, has := s.data[key]
+	return v, has
+}
+
+func (s *standin) del(key string) bool {
+	s.expire(key)
+	if _, has := s.data[key]; !has {
+		return false
+	}
+	delete(s.data, key)
+	s.versions[key]++
+	return true
+}
+
+// run executes a command, must be called with the lock.
+func (s *standin) run(args []string) []byte {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return simpleReply("PONG")
+	case "GET":
+		if len(args) != 2 {
+			return errorReply("wrong number of arguments")
+		}
+		if v, has := s.get(args[1]); has {
+			return bulkReply(v.value)
+		}
+		return nilReply()
+	case "GETDEL":
+		if len(args) != 2 {
+			return errorReply("wrong number of arguments")
+		}
+		v, has := s.get(args[1])
+		if !has {
+			return nilReply()
+		}
+		s.del(args[1])
+		return bulkReply(v.value)
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if s.del(key) {
+				n++
+			}
+		}
+		return intReply(n)
+	case "EXISTS":
+		n := 0
+		for _, key := range args[1:] {
+			if _, has := s.get(key); has {
+				n++
+			}
+		}
+		return intReply(n)
+	case "SET":
+		return s.set(args)
+	case "INCR":
+		if len(args) != 2 {
+			return errorReply("wrong number of arguments")
+		}
+		v, _ := s.get(args[1])
+		n := 0
+		if v.value != "" {
+			var err error
+			if n, err = strconv.Atoi(v.value); err != nil {
+				return errorReply("value is not an integer or out of range")
+			}
+		}
+		v.value = strconv.Itoa(n + 1)
+		s.data[args[1]] = v
+		s.versions[args[1]]++
+		return intReply(n + 1)
+	}
+	return errorReply("unknown command '" + args[0] + "'")
+}
+
+func (s *standin) set(args []string) []byte {
+	if len(args) < 3 {
+		return errorReply("wrong number of arguments")
+	}
+	key := args[1]
+	old, has := s.get(key)
+	v := standinValue{value: args[2]}
+	nx := false
+	for i := 3; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch opt {
+		case "NX":
+			nx = true
+		case "KEEPTTL":
+			v.expireAt = old.expireAt
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				return errorReply("syntax error")
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n <= 0 {
+				return errorReply("invalid expire time in 'set' command")
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			v.expireAt = time.Now().Add(time.Duration(n) * unit)
+			i++
+		default:
+			return errorReply("syntax error")
+		}
+	}
+	if nx && has {
+		return nilReply()
+	}
+	s.data[key] = v
+	s.versions[key]++
+	return simpleReply("OK")
+}
@@ -8,13 +8,15 @@ import (
 type Users struct {
 	UID      int64     `gorm:"autoIncrement:true;primaryKey;column:uid;type:bigint;not null;comment:'用户唯一id'" json:"uid"`         // 用户唯一id
 	Uname    string    `gorm:"unique;column:uname;type:varchar(64);not null;comment:'用户名'" json:"uname"`                          // 用户名
-	Passwd   string    `gorm:"column:passwd;type:varchar(64);not null;default:'';comment:'密码'" json:"passwd"`                     // 密码
+	Passwd   string    `gorm:"column:passwd;type:varchar(128);not null;default:'';comment:'密码'" json:"passwd"`                    // 密码
 	Nickname string    `gorm:"column:nickname;type:varchar(64);not null;default:'';comment:'昵称'" json:"nickname"`                 // 昵称
 	Avatar   string    `gorm:"column:avatar;type:varchar(1024);not null;default:'';comment:'头像'" json:"avatar"`                   // 头像
 	Gender   int8      `gorm:"column:gender;type:tinyint;not null;default:0;comment:'性别'" json:"gender"`                          // 性别
 	Phone    string    `gorm:"column:phone;type:varchar(32);not null;default:'';comment:'电话号码'" json:"phone"`                     // 电话号码
 	Email    string    `gorm:"column:email;type:varchar(64);not null;default:'';comment:'电子邮箱'" json:"email"`                     // 电子邮箱
 	Stat     int8      `gorm:"column:stat;type:tinyint;not null;default:0;comment:'状态码'" json:"stat"`                             // 状态码
+	Guest    int8      `gorm:"column:guest;type:tinyint;not null;default:0;comment:'游客'" json:"guest"`                            // 游客
+	Role     uint32    `gorm:"column:role;type:int unsigned;not null;default:1;comment:'角色'" json:"role"`                         // 角色
 	CreateAt time.Time `gorm:"column:create_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'创建时间'" json:"create_at"` // 创建时间
 	UpdateAt time.Time `gorm:"column:update_at;type:datetime;default:null;comment:'修改时间'" json:"update_at"`                       // 修改时间
 }
@@ -35,6 +37,8 @@ var UsersColumns = struct {
 	Phone    string
 	Email    string
 	Stat     string
+	Guest    string
+	Role     string
 	CreateAt string
 	UpdateAt string
 }{
@@ -47,6 +51,168 @@ var UsersColumns = struct {
 	Phone:    "phone",
 	Email:    "email",
 	Stat:     "stat",
+	Guest:    "guest",
+	Role:     "role",
 	CreateAt: "create_at",
 	UpdateAt: "update_at",
 }
+
+// GuestDevices binds the guest users to their devices.
+type GuestDevices struct {
+	DeviceID string    `gorm:"primaryKey;column:device_id;type:varchar(64);not null;comment:'设备id'" json:"device_id"`             // 设备id
+	UID      int64     `gorm:"index;column:uid;type:bigint;not null;comment:'游客的用户id'" json:"uid"`                                // 游客的用户id
+	Secret   string    `gorm:"column:secret;type:varchar(64);not null;default:'';comment:'设备密钥的sha256'" json:"secret"`            // 设备密钥的sha256
+	CreateAt time.Time `gorm:"column:create_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'创建时间'" json:"create_at"` // 创建时间
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *GuestDevices) TableName() string {
+	return "guest_devices"
+}
+
+// GuestDevicesColumns get sql column name.获取数据库列名
+var GuestDevicesColumns = struct {
+	DeviceID string
+	UID      string
+	Secret   string
+	CreateAt string
+}{
+	DeviceID: "device_id",
+	UID:      "uid",
+	Secret:   "secret",
+	CreateAt: "create_at",
+}
+
+// UserTotps is the totp second factor of the users.
+type UserTotps struct {
+	UID      int64     `gorm:"primaryKey;column:uid;type:bigint;not null;comment:'用户id'" json:"uid"`                              // 用户id
+	Secret   string    `gorm:"column:secret;type:varchar(64);not null;comment:'base32的密钥'" json:"secret"`                         // base32的密钥
+	Enabled  int8      `gorm:"column:enabled;type:tinyint;not null;default:0;comment:'已启用'" json:"enabled"`                       // 已启用
+	LastStep int64     `gorm:"column:last_step;type:bigint;not null;default:0;comment:'最后使用的时间步'" json:"last_step"`               // 最后使用的时间步
+	CreateAt time.Time `gorm:"column:create_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'创建时间'" json:"create_at"` // 创建时间
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *UserTotps) TableName() string {
+	return "user_totps"
+}
+
+// UserTotpsColumns get sql column name.获取数据库列名
+var UserTotpsColumns = struct {
+	UID      string
+	Secret   string
+	Enabled  string
+	LastStep string
+	CreateAt string
+}{
+	UID:      "uid",
+	Secret:   "secret",
+	Enabled:  "enabled",
+	LastStep: "last_step",
+	CreateAt: "create_at",
+}
+
+// UserRecoveryCodes is the recovery codes of the second factor, each one is deleted when it is used.
+type UserRecoveryCodes struct {
+	ID       int64     `gorm:"autoIncrement:true;primaryKey;column:id;type:bigint;not null" json:"id"`
+	UID      int64     `gorm:"index;column:uid;type:bigint;not null;comment:'用户id'" json:"uid"`                                   // 用户id
+	CodeHash string    `gorm:"column:code_hash;type:varchar(64);not null;comment:'恢复码的sha256'" json:"code_hash"`                  // 恢复码的sha256
+	CreateAt time.Time `gorm:"column:create_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'创建时间'" json:"create_at"` // 创建时间
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *UserRecoveryCodes) TableName() string {
+	return "user_recovery_codes"
+}
+
+// UserRecoveryCodesColumns get sql column name.获取数据库列名
+var UserRecoveryCodesColumns = struct {
+	ID       string
+	UID      string
+	CodeHash string
+	CreateAt string
+}{
+	ID:       "id",
+	UID:      "uid",
+	CodeHash: "code_hash",
+	CreateAt: "create_at",
+}
+
+// UserSessions is the logins of the users, a session is the refresh token family of a login.
+type UserSessions struct {
+	ID          string    `gorm:"primaryKey;column:id;type:varchar(64);not null;comment:'刷新令牌族id'" json:"id"`                                // 刷新令牌族id
+	UID         int64     `gorm:"index;column:uid;type:bigint;not null;comment:'用户id'" json:"uid"`                                           // 用户id
+	DeviceID    string    `gorm:"column:device_id;type:varchar(64);not null;default:'';comment:'设备id'" json:"device_id"`                     // 设备id
+	IP          string    `gorm:"column:ip;type:varchar(64);not null;default:'';comment:'最后的ip'" json:"ip"`                                  // 最后的ip
+	UserAgent   string    `gorm:"column:user_agent;type:varchar(256);not null;default:'';comment:'客户端'" json:"user_agent"`                   // 客户端
+	TokenID     string    `gorm:"column:token_id;type:varchar(64);not null;default:'';comment:'最新访问令牌的jti'" json:"token_id"`                 // 最新访问令牌的jti
+	TokenExpire int64     `gorm:"column:token_expire;type:bigint;not null;default:0;comment:'最新访问令牌的过期时间'" json:"token_expire"`              // 最新访问令牌的过期时间
+	CreateAt    time.Time `gorm:"column:create_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'创建时间'" json:"create_at"`         // 创建时间
+	LastSeenAt  time.Time `gorm:"column:last_seen_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'最后活跃时间'" json:"last_seen_at"` // 最后活跃时间
+	ExpireAt    time.Time `gorm:"index;column:expire_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'过期时间'" json:"expire_at"`   // 过期时间
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *UserSessions) TableName() string {
+	return "user_sessions"
+}
+
+// UserSessionsColumns get sql column name.获取数据库列名
+var UserSessionsColumns = struct {
+	ID          string
+	UID         string
+	DeviceID    string
+	IP          string
+	UserAgent   string
+	TokenID     string
+	TokenExpire string
+	CreateAt    string
+	LastSeenAt  string
+	ExpireAt    string
+}{
+	ID:          "id",
+	UID:         "uid",
+	DeviceID:    "device_id",
+	IP:          "ip",
+	UserAgent:   "user_agent",
+	TokenID:     "token_id",
+	TokenExpire: "token_expire",
+	CreateAt:    "create_at",
+	LastSeenAt:  "last_seen_at",
+	ExpireAt:    "expire_at",
+}
+
+// UserIdentities links the users to their identities of the identity providers.
+type UserIdentities struct {
+	ID            int64     `gorm:"autoIncrement:true;primaryKey;column:id;type:bigint;not null" json:"id"`
+	Provider      string    `gorm:"uniqueIndex:uk_provider_subject,priority:1;uniqueIndex:uk_uid_provider,priority:2;column:provider;type:varchar(32);not null;comment:'身份提供方'" json:"provider"` // 身份提供方
+	Subject       string    `gorm:"uniqueIndex:uk_provider_subject,priority:2;column:subject;type:varchar(255);not null;comment:'提供方的用户id'" json:"subject"`                                      // 提供方的用户id
+	UID           int64     `gorm:"uniqueIndex:uk_uid_provider,priority:1;column:uid;type:bigint;not null;comment:'用户id'" json:"uid"`                                                            // 用户id
+	Email         string    `gorm:"column:email;type:varchar(64);not null;default:'';comment:'提供方的邮箱'" json:"email"`                                                                             // 提供方的邮箱
+	EmailVerified int8      `gorm:"column:email_verified;type:tinyint;not null;default:0;comment:'邮箱已被信任的提供方验证'" json:"email_verified"`                                                          // 邮箱已被信任的提供方验证
+	CreateAt      time.Time `gorm:"column:create_at;type:datetime;not null;default:CURRENT_TIMESTAMP;comment:'创建时间'" json:"create_at"`                                                           // 创建时间
+}
+
+// TableName get sql table name.获取数据库表名
+func (m *UserIdentities) TableName() string {
+	return "user_identities"
+}
+
+// UserIdentitiesColumns get sql column name.获取数据库列名
+var UserIdentitiesColumns = struct {
+	ID            string
+	Provider      string
+	Subject       string
+	UID           string
+	Email         string
+	EmailVerified string
+	CreateAt      string
+}{
+	ID:            "id",
+	Provider:      "provider",
+	Subject:       "subject",
+	UID:           "uid",
+	Email:         "email",
+	EmailVerified: "email_verified",
+	CreateAt:      "create_at",
+}
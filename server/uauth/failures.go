@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/ajenpan/surf/core"
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+)
+
+const (
+	// DefaultCaptchaAfterFailures is how many failed logins of an account or an ip require the captcha.
+	DefaultCaptchaAfterFailures = 3
+	// DefaultFailureWindow is how long the failed logins are counted.
+	DefaultFailureWindow = 15 * time.Minute
+
+	// DefaultLockAfterFailures is how many failed logins of an account lock it.
+	DefaultLockAfterFailures = 10
+	// DefaultLockIPAfterFailures is how many failed logins of an ip lock it, an ip may be shared by many users.
+	DefaultLockIPAfterFailures = 100
+	// DefaultLockDuration is the first lockout, each later one in lockoutMemory doubles it.
+	DefaultLockDuration    = 5 * time.Minute
+	DefaultMaxLockDuration = 24 * time.Hour
+)
+
+const (
+	// lockoutMemory is how long the lockouts are remembered to double the next one.
+	lockoutMemory = 24 * time.Hour
+
+	// the wait before the next login grows from failureBaseDelay after the first failure, up to failureMaxDelay.
+	failureBaseDelay = 250 * time.Millisecond
+	failureMaxDelay  = 4 * time.Second
+)
+
+// loginFailures counts the failed logins of the accounts and the ips in the cache, so the counts are
+// shared by the instances of uauth. The counts are of a sliding window, too many failures lock
+// the account or the ip for a while. The counts of an account are cleared by its successful login.
+type loginFailures struct {
+	cache  cache.AuthCache
+	window time.Duration
+	// captchaAfter is the count which requires the captcha, never if it is not positive.
+	captchaAfter int64
+	// lockAfter and lockIPAfter are the counts which lock, never if they are not positive.
+	lockAfter   int64
+	lockIPAfter int64
+	lockFor     time.Duration
+	maxLockFor  time.Duration
+}
+
+func accountFailureKey(uname string) string {
+	return "loginfail:uname:" + uname
+}
+
+func ipFailureKey(ip string) string {
+	return "loginfail:ip:" + ip
+}
+
+// throttleKey is the lock of the key which lasts the delay after a failure.
+func throttleKey(key string) string {
+	return "throttle:" + key
+}
+
+// remoteIPOf is the ip of the caller, empty if ctx is not of a call.
+func remoteIPOf(ctx context.Context) string {
+	if c := core.ContextOf(ctx); c != nil {
+		return remoteIP(c.RemoteAddr())
+	}
+	return ""
+}
+
+// remoteIP strips the port of the remote address.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+func (f *loginFailures) bucket(key string, idx int64) string {
+	return key + ":" + strconv.FormatInt(idx, 10)
+}
+
+// slidingCount estimates the count of the last window by the current and the previous fixed windows,
+// the previous one is weighted by its part still in the last window, rounded up.
+func (f *loginFailures) slidingCount(now time.Time, cur, prev int64) int64 {
+	elapsed := now.UnixNano() % int64(f.window)
+	weight := 1 - float64(elapsed)/float64(f.window)
+	return cur + int64(math.Ceil(float64(prev)*weight))
+}
+
+func (f *loginFailures) countOf(ctx context.Context, key string) int64 {
+	now := time.Now()
+	idx := now.UnixNano() / int64(f.window)
+	cur := f.cache.FetchCounter(ctx, f.bucket(key, idx))
+	prev := f.cache.FetchCounter(ctx, f.bucket(key, idx-1))
+	return f.slidingCount(now, cur, prev)
+}
+
+func (f *loginFailures) incr(ctx context.Context, key string) int64 {
+	now := time.Now()
+	idx := now.UnixNano() / int64(f.window)
+	cur, _ := f.cache.IncrCounter(ctx, f.bucket(key, idx), 2*f.window)
+	prev := f.cache.FetchCounter(ctx, f.bucket(key, idx-1))
+	return f.slidingCount(now, cur, prev)
+}
+
+func (f *loginFailures) clear(ctx context.Context, key string) {
+	idx := time.Now().UnixNano() / int64(f.window)
+	f.cache.DeleteCounter(ctx, f.bucket(key, idx))
+	f.cache.DeleteCounter(ctx, f.bucket(key, idx-1))
+}
+
+// count is the larger one of the failures of the account and the ip.
+func (f *loginFailures) count(ctx context.Context, uname string, ip string) int64 {
+	n := f.countOf(ctx, accountFailureKey(uname))
+	if ip != "" {
+		if byIP := f.countOf(ctx, ipFailureKey(ip)); byIP > n {
+			n = byIP
+		}
+	}
+	return n
+}
+
+func (f *loginFailures) captchaRequired(ctx context.Context, uname string, ip string) bool {
+	return f.captchaAfter > 0 && f.count(ctx, uname, ip) >= f.captchaAfter
+}
+
+// delay is the wait before the next login after the failures, it doubles with each failure.
+func (f *loginFailures) delay(ctx context.Context, uname string, ip string) time.Duration {
+	n := f.count(ctx, uname, ip)
+	if n <= 0 {
+		return 0
+	}
+	d := failureBaseDelay
+	for i := int64(1); i < n && d < failureMaxDelay; i++ {
+		d *= 2
+	}
+	if d > failureMaxDelay {
+		d = failureMaxDelay
+	}
+	return d
+}
+
+// lockedUntil is the later one of the lockouts of the account and the ip, zero if neither is locked.
+func (f *loginFailures) lockedUntil(ctx context.Context, uname string, ip string) time.Time {
+	until := f.cache.LockedUntil(ctx, accountFailureKey(uname))
+	if ip != "" {
+		if byIP := f.cache.LockedUntil(ctx, ipFailureKey(ip)); byIP.After(until) {
+			until = byIP
+		}
+	}
+	return until
+}
+
+// throttledUntil is the later one of the throttles of the account and the ip, zero if neither is throttled.
+func (f *loginFailures) throttledUntil(ctx context.Context, uname string, ip string) time.Time {
+	until := f.cache.LockedUntil(ctx, throttleKey(accountFailureKey(uname)))
+	if ip != "" {
+		if byIP := f.cache.LockedUntil(ctx, throttleKey(ipFailureKey(ip))); byIP.After(until) {
+			until = byIP
+		}
+	}
+	return until
+}
+
+// fail counts the failure, and throttles the account and the ip for the delay of their failures,
+// so the logins are refused with a retry-after instead of holding the serving goroutines.
+func (f *loginFailures) fail(ctx context.Context, uname string, ip string) {
+	key := accountFailureKey(uname)
+	if n := f.incr(ctx, key); f.lockAfter > 0 && n >= f.lockAfter {
+		f.lock(ctx, key)
+	}
+	if ip != "" {
+		key = ipFailureKey(ip)
+		if n := f.incr(ctx, key); f.lockIPAfter > 0 && n >= f.lockIPAfter {
+			f.lock(ctx, key)
+		}
+	}
+
+	d := f.delay(ctx, uname, ip)
+	if d <= 0 {
+		return
+	}
+	until := time.Now().Add(d)
+	f.cache.Lock(ctx, throttleKey(accountFailureKey(uname)), until)
+	if ip != "" {
+		f.cache.Lock(ctx, throttleKey(ipFailureKey(ip)), until)
+	}
+}
+
+// lock locks the key, the lockout doubles if the key is locked recently.
+// The failures are cleared, so the key has all the tries after the lockout.
+func (f *loginFailures) lock(ctx context.Context, key string) {
+	n, _ := f.cache.IncrCounter(ctx, "lockouts:"+key, lockoutMemory)
+	d := f.lockFor
+	for i := int64(1); i < n && d < f.maxLockFor; i++ {
+		d *= 2
+	}
+	if d > f.maxLockFor {
+		d = f.maxLockFor
+	}
+	f.cache.Lock(ctx, key, time.Now().Add(d))
+	f.clear(ctx, key)
+}
+
+// unlock clears the lockout, the failures and the lockout history of the key.
+func (f *loginFailures) unlock(ctx context.Context, key string) {
+	f.cache.Unlock(ctx, key)
+	f.cache.Unlock(ctx, throttleKey(key))
+	f.cache.DeleteCounter(ctx, "lockouts:"+key)
+	f.clear(ctx, key)
+}
+
+// succeed clears the failures of the account, the ones of the ip are kept since it may be shared.
+func (f *loginFailures) succeed(ctx context.Context, uname string) {
+	f.clear(ctx, accountFailureKey(uname))
+}
+
+// retryAfter is the AccountLocked error of a lockout or a throttle,
+// the "retry_after" metadata is the seconds the client waits.
+func retryAfter(detail string, until time.Time) error {
+	secs := int64(math.Ceil(time.Until(until).Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	err := errors.New(int32(msg.ResponseFlag_AccountLocked), fmt.Sprintf("%s, retry after %ds", detail, secs))
+	return errors.WithMeta(err, "retry_after", strconv.FormatInt(secs, 10))
+}
+
+// UnlockUser unlocks the account or the ip locked by the failed logins, and clears their failures.
+func (h *Auth) UnlockUser(ctx context.Context, in *msg.UnlockUserRequest) (*msg.UnlockUserResponse, error) {
+	if in.Uname == "" && in.Ip == "" {
+		return nil, errors.InvalidArgument("uname or ip is required")
+	}
+	if in.Uname != "" {
+		h.failures.unlock(context.Background(), accountFailureKey(in.Uname))
+	}
+	if in.Ip != "" {
+		h.failures.unlock(context.Background(), ipFailureKey(in.Ip))
+	}
+	log.Infof("unlock the logins of uname: %q, ip: %q", in.Uname, in.Ip)
+	return &msg.UnlockUserResponse{}, nil
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ajenpan/surf/core/errors"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+)
+
+func TestSlidingCount(t *testing.T) {
+	f := &loginFailures{window: 10 * time.Second}
+	// a quarter of the current window is elapsed, so three quarters of the previous one count.
+	now := time.Unix(1000, int64(2500*time.Millisecond))
+	if got := f.slidingCount(now, 1, 4); got != 4 {
+		t.Fatal("wrong sliding count:", got)
+	}
+	if got := f.slidingCount(now, 2, 1); got != 3 {
+		t.Fatal("the previous window is not rounded up:", got)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	f := &loginFailures{
+		cache:      cache.NewMemory(),
+		window:     time.Minute,
+		lockAfter:  3,
+		lockFor:    time.Minute,
+		maxLockFor: 3 * time.Minute,
+	}
+
+	if d := f.delay(ctx, "surf_user", ""); d != 0 {
+		t.Fatal("the first login is delayed:", d)
+	}
+	f.fail(ctx, "surf_user", "")
+	if d := f.delay(ctx, "surf_user", ""); d != failureBaseDelay {
+		t.Fatal("wrong delay:", d)
+	}
+	// the next login is refused until the delay passes, instead of sleeping.
+	if left := time.Until(f.throttledUntil(ctx, "surf_user", "")); left <= 0 || left > failureBaseDelay {
+		t.Fatal("wrong throttle:", left)
+	}
+	if !f.throttledUntil(ctx, "other_user", "").IsZero() {
+		t.Fatal("another account is throttled")
+	}
+	f.fail(ctx, "surf_user", "")
+	if d := f.delay(ctx, "surf_user", ""); d != 2*failureBaseDelay {
+		t.Fatal("the delay is not doubled:", d)
+	}
+	if !f.lockedUntil(ctx, "surf_user", "").IsZero() {
+		t.Fatal("locked before the limit")
+	}
+
+	f.fail(ctx, "surf_user", "")
+	until := f.lockedUntil(ctx, "surf_user", "")
+	if left := time.Until(until); left <= 50*time.Second || left > time.Minute {
+		t.Fatal("wrong first lockout:", left)
+	}
+	if f.count(ctx, "surf_user", "") != 0 {
+		t.Fatal("the failures are not cleared by the lockout")
+	}
+
+	// the next lockout doubles, and it is capped.
+	for i := 0; i < 3; i++ {
+		f.fail(ctx, "surf_user", "")
+	}
+	if left := time.Until(f.lockedUntil(ctx, "surf_user", "")); left <= 110*time.Second || left > 2*time.Minute {
+		t.Fatal("the second lockout is not doubled:", left)
+	}
+	for i := 0; i < 3; i++ {
+		f.fail(ctx, "surf_user", "")
+	}
+	if left := time.Until(f.lockedUntil(ctx, "surf_user", "")); left <= 170*time.Second || left > 3*time.Minute {
+		t.Fatal("the third lockout is not capped:", left)
+	}
+
+	f.unlock(ctx, accountFailureKey("surf_user"))
+	if !f.lockedUntil(ctx, "surf_user", "").IsZero() || !f.throttledUntil(ctx, "surf_user", "").IsZero() {
+		t.Fatal("the account is not unlocked")
+	}
+	f.fail(ctx, "surf_user", "")
+	f.fail(ctx, "surf_user", "")
+	f.fail(ctx, "surf_user", "")
+	if left := time.Until(f.lockedUntil(ctx, "surf_user", "")); left > time.Minute {
+		t.Fatal("the lockout history is not cleared by unlock:", left)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	e, ok := errors.As(retryAfter("locked", time.Now().Add(1500*time.Millisecond)))
+	if !ok || e.Code != int32(msg.ResponseFlag_AccountLocked) || e.Metadata["retry_after"] != "2" {
+		t.Fatal("wrong retry after:", e)
+	}
+	if e, _ := errors.As(retryAfter("locked", time.Now())); e.Metadata["retry_after"] != "1" {
+		t.Fatal("the retry after is less than a second:", e)
+	}
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"gorm.io/gorm"
+
+	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	msgcore "github.com/ajenpan/surf/msg/core"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+const guestUnamePrefix = "guest_"
+
+// guestUnameChars are the chars of the generated unames, which match the uname pattern of Login.
+const guestUnameChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// newGuestUname is like "guest_k3v9x0q2ma", 16 chars as the longest uname.
+func newGuestUname() (string, error) {
+	return newUname(guestUnamePrefix)
+}
+
+// newUname is the prefix with 10 random chars.
+func newUname(prefix string) (string, error) {
+	buf := make([]byte, 10)
+	for i := range buf {
+		n, err := randInt(int64(len(guestUnameChars)))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = guestUnameChars[n]
+	}
+	return prefix + string(buf), nil
+}
+
+// createGuest creates the guest and binds it to the device, the secret of the device is returned.
+// The guest has no password, so it can't login by Login until it is upgraded.
+func (h *Auth) createGuest(deviceID string) (*models.Users, string, error) {
+	uname, err := newGuestUname()
+	if err != nil {
+		return nil, "", errors.Wrap(err, errors.CodeInternal, "generate guest failed")
+	}
+	secret, err := newRefreshSecret()
+	if err != nil {
+		return nil, "", errors.Wrap(err, errors.CodeInternal, "generate guest failed")
+	}
+	user := &models.Users{
+		Uname:    uname,
+		Nickname: "游客",
+		Guest:    1,
+		Role:     uint32(msgcore.Role_RoleUser),
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.GuestDevices{
+			DeviceID: deviceID,
+			UID:      user.UID,
+			Secret:   hashRefreshSecret(secret),
+		}).Error
+	})
+	if err != nil {
+		return nil, "", errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "create guest failed")
+	}
+	return user, secret, nil
+}
+
+// AnonymousLogin logs in the guest of the device, the first login of a device without the secret creates the guest.
+// A lost secret can't be issued again, since anyone may know the device id.
+func (h *Auth) AnonymousLogin(ctx context.Context, in *msg.AnonymousLoginRequest) (*msg.AnonymousLoginResponse, error) {
+	out := &msg.AnonymousLoginResponse{}
+
+	device := &models.GuestDevices{DeviceID: in.DeviceId}
+	res := h.DB.Limit(1).Find(device, device)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find guest failed")
+	}
+
+	var user *models.Users
+	if res.RowsAffected == 0 {
+		if in.DeviceSecret != "" {
+			return nil, errors.New(int32(msg.ResponseFlag_GuestNotFound), "guest not found")
+		}
+		created, secret, err := h.createGuest(in.DeviceId)
+		if err != nil {
+			return nil, err
+		}
+		user, out.DeviceSecret = created, secret
+	} else {
+		if subtle.ConstantTimeCompare([]byte(hashRefreshSecret(in.DeviceSecret)), []byte(device.Secret)) != 1 {
+			return nil, errors.New(int32(msg.ResponseFlag_DeviceMismatch), "wrong device secret")
+		}
+		user = &models.Users{UID: device.UID}
+		res := h.DB.Limit(1).Find(user, user)
+		if res.Error != nil {
+			return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+		}
+		if res.RowsAffected == 0 || user.Guest == 0 {
+			return nil, errors.New(int32(msg.ResponseFlag_GuestNotFound), "guest not found")
+		}
+	}
+
+	if user.Stat != 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_StatErr), "user stat is not ok")
+	}
+
+	tokens, err := h.login(user, clientOf(core.ContextOf(ctx), in.DeviceId), false)
+	if err != nil {
+		return nil, err
+	}
+	out.AssessToken = tokens.access
+	out.RefreshToken = tokens.refresh
+	out.ExpiresIn = tokens.expiresIn
+	out.UserInfo = userInfoOf(user)
+	return out, nil
+}
+
+// UpgradeGuest binds the guest of the caller to the password, and the uname, the email or the phone.
+// The uid is kept, so everything of the guest stays with the account. The device can't login the guest after it.
+// The email and the phone are stored unverified, only the uname is a login identity.
+func (h *Auth) UpgradeGuest(ctx context.Context, in *msg.UpgradeGuestRequest) (*msg.UpgradeGuestResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	if in.Uname == "" && in.Email == "" && in.Phone == "" {
+		return nil, errors.InvalidArgument("one of uname, email and phone is required")
+	}
+	uid := int64(caller.UserID())
+
+	user := &models.Users{UID: uid}
+	res := h.DB.Limit(1).Find(user, user)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_UnameNotFound), "uname not found")
+	}
+	if user.Guest == 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_NotGuest), "not a guest")
+	}
+
+	hashed, err := h.passwd.hash(in.Passwd)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "hash passwd failed")
+	}
+	updates := map[string]interface{}{
+		models.UsersColumns.Passwd: hashed,
+		models.UsersColumns.Guest:  0,
+	}
+	if in.Uname != "" {
+		var n int64
+		if err := h.DB.Model(&models.Users{}).Where("uname = ? AND uid <> ?", in.Uname, uid).Count(&n).Error; err != nil {
+			return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "check uname failed")
+		}
+		if n > 0 {
+			return nil, errors.AlreadyExists("uname already exists")
+		}
+		updates[models.UsersColumns.Uname] = in.Uname
+	}
+	// the email and the phone are not verified, they are kept as the contacts and never identify the user,
+	// so they are not required to be unique.
+	if in.Email != "" {
+		updates[models.UsersColumns.Email] = in.Email
+	}
+	if in.Phone != "" {
+		updates[models.UsersColumns.Phone] = in.Phone
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// the guest may be upgraded by a concurrent call.
+		res := tx.Model(&models.Users{}).Where("uid = ? AND guest <> 0", uid).Updates(updates)
+		if res.Error != nil {
+			return errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "upgrade guest failed")
+		}
+		if res.RowsAffected == 0 {
+			return errors.New(int32(msg.ResponseFlag_NotGuest), "not a guest")
+		}
+		if err := tx.Where("uid = ?", uid).Delete(&models.GuestDevices{}).Error; err != nil {
+			return errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "unbind guest device failed")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.Cache.DeleteUser(context.Background(), uid)
+
+	if res := h.DB.Limit(1).Find(user, &models.Users{UID: uid}); res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+	return &msg.UpgradeGuestResponse{UserInfo: userInfoOf(user)}, nil
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGuestCredentials(t *testing.T) {
+	// the uname of Login, so the upgraded guest which keeps it can login.
+	pattern := regexp.MustCompile("^[a-zA-Z0-9_]{4,16}$")
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		uname, err := newGuestUname()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pattern.MatchString(uname) {
+			t.Fatal("the guest uname doesn't match the uname pattern:", uname)
+		}
+		if seen[uname] {
+			t.Fatal("duplicate guest uname:", uname)
+		}
+		seen[uname] = true
+	}
+
+	// a guest has no password, nothing matches it.
+	p := &passwdHasher{iterations: 1000}
+	if ok, _ := p.verify("", ""); ok {
+		t.Fatal("the empty password of a guest is verified")
+	}
+}
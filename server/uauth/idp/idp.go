@@ -0,0 +1,154 @@
+package idp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+)
+
+// Identity is the user of an identity provider, the subject is unique in the provider.
+type Identity struct {
+	Provider string
+	Subject  string
+	// Email is verified only if the provider verified it and the provider is trusted by TrustEmail.
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+}
+
+// AuthRequest is an authorization of the code flow, it is kept by uauth until the code comes back.
+type AuthRequest struct {
+	State string `json:"state"`
+	Nonce string `json:"nonce"`
+	// CodeVerifier is the secret of PKCE, the provider gets its challenge by AuthURL.
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// NewAuthRequest generates the state, the nonce and the PKCE verifier of an authorization.
+func NewAuthRequest() (*AuthRequest, error) {
+	var err error
+	req := &AuthRequest{}
+	for _, v := range []*string{&req.State, &req.Nonce, &req.CodeVerifier} {
+		if *v, err = randomString(32); err != nil {
+			return nil, err
+		}
+	}
+	return req, nil
+}
+
+// CodeChallenge is the S256 challenge of the PKCE verifier, RFC 7636.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Provider logs in the users by the authorization code flow of OAuth2.
+type Provider interface {
+	Name() string
+	// AuthURL is where the user is sent to authorize the login.
+	AuthURL(ctx context.Context, req *AuthRequest) (string, error)
+	// Exchange redeems the code of the authorization for the identity of the user.
+	Exchange(ctx context.Context, req *AuthRequest, code string) (*Identity, error)
+}
+
+// Config is a provider of the providers file, see LoadConfigs.
+type Config struct {
+	// Name is how the clients select the provider, like "google".
+	Name string `json:"name"`
+	// Issuer is the OIDC issuer, its endpoints are discovered by "/.well-known/openid-configuration".
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is registered to the provider, the clients get the code there.
+	RedirectURL string `json:"redirect_url"`
+	// Scopes are "openid email profile" if empty.
+	Scopes []string `json:"scopes"`
+
+	// the endpoints are discovered if any of them is empty.
+	AuthURL  string `json:"auth_url"`
+	TokenURL string `json:"token_url"`
+	JWKSURL  string `json:"jwks_url"`
+
+	// TrustEmail takes the emails verified by the provider, so an identity is linked to the user
+	// who has an identity of the same email verified.
+	// Only trust the providers which own the emails they verify.
+	TrustEmail bool `json:"trust_email"`
+}
+
+// LoadConfigs reads the json array of the providers.
+func LoadConfigs(path string) ([]Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	confs := []Config{}
+	if err := json.Unmarshal(raw, &confs); err != nil {
+		return nil, fmt.Errorf("parse providers %s: %w", path, err)
+	}
+	for _, c := range confs {
+		if c.Name == "" || c.ClientID == "" || c.RedirectURL == "" {
+			return nil, fmt.Errorf("provider %q: name, client_id and redirect_url are required", c.Name)
+		}
+		if c.Issuer == "" {
+			return nil, fmt.Errorf("provider %s: issuer is required", c.Name)
+		}
+	}
+	return confs, nil
+}
+
+// Registry is the providers by their names.
+type Registry struct {
+	providers map[string]Provider
+}
+
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider, len(providers))}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+	}
+	return r
+}
+
+// NewOIDCRegistry creates the OIDC providers of the configs.
+func NewOIDCRegistry(confs []Config, client *http.Client) *Registry {
+	providers := make([]Provider, 0, len(confs))
+	for _, c := range confs {
+		providers = append(providers, NewOIDC(c, client))
+	}
+	return NewRegistry(providers...)
+}
+
+func (r *Registry) Get(name string) (Provider, bool) {
+	if r == nil {
+		return nil, false
+	}
+	p, has := r.providers[name]
+	return p, has
+}
+
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,224 @@
+package idp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	coreauth "github.com/ajenpan/surf/core/auth"
+)
+
+var (
+	ErrExchange     = errors.New("exchange code failed")
+	ErrInvalidToken = errors.New("invalid id token")
+)
+
+type discovery struct {
+	Issuer   string `json:"issuer"`
+	AuthURL  string `json:"authorization_endpoint"`
+	TokenURL string `json:"token_endpoint"`
+	JWKSURL  string `json:"jwks_uri"`
+}
+
+// OIDC is a provider of OpenID Connect, the identity is of the id token, whose signature,
+// issuer, audience, expiry and nonce are validated. The endpoints are discovered at the first login.
+type OIDC struct {
+	conf   Config
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *discovery
+	keys      *coreauth.RemoteKeySet
+}
+
+func NewOIDC(conf Config, client *http.Client) *OIDC {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(conf.Scopes) == 0 {
+		conf.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDC{conf: conf, client: client}
+}
+
+func (p *OIDC) Name() string {
+	return p.conf.Name
+}
+
+// discover fetches the endpoints which are not configured, a failed discovery is tried again by the next login.
+func (p *OIDC) discover(ctx context.Context) (*discovery, *coreauth.RemoteKeySet, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoints != nil {
+		return p.endpoints, p.keys, nil
+	}
+
+	d := &discovery{Issuer: p.conf.Issuer, AuthURL: p.conf.AuthURL, TokenURL: p.conf.TokenURL, JWKSURL: p.conf.JWKSURL}
+	if d.AuthURL == "" || d.TokenURL == "" || d.JWKSURL == "" {
+		found := &discovery{}
+		if err := p.getJSON(ctx, strings.TrimSuffix(p.conf.Issuer, "/")+"/.well-known/openid-configuration", found); err != nil {
+			return nil, nil, fmt.Errorf("discover %s: %w", p.conf.Name, err)
+		}
+		if found.Issuer != p.conf.Issuer {
+			return nil, nil, fmt.Errorf("discover %s: issuer %q mismatch", p.conf.Name, found.Issuer)
+		}
+		if d.AuthURL == "" {
+			d.AuthURL = found.AuthURL
+		}
+		if d.TokenURL == "" {
+			d.TokenURL = found.TokenURL
+		}
+		if d.JWKSURL == "" {
+			d.JWKSURL = found.JWKSURL
+		}
+	}
+	keys := coreauth.NewRemoteKeySet(coreauth.HTTPKeySource(d.JWKSURL, p.client), coreauth.RemoteKeySetOptions{})
+	if err := keys.Refresh(ctx); err != nil {
+		return nil, nil, fmt.Errorf("fetch keys of %s: %w", p.conf.Name, err)
+	}
+	p.endpoints, p.keys = d, keys
+	return d, keys, nil
+}
+
+func (p *OIDC) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("get %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (p *OIDC) AuthURL(ctx context.Context, req *AuthRequest) (string, error) {
+	d, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.conf.ClientID},
+		"redirect_uri":          {p.conf.RedirectURL},
+		"scope":                 {strings.Join(p.conf.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {CodeChallenge(req.CodeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthURL, "?") {
+		sep = "&"
+	}
+	return d.AuthURL + sep + q.Encode(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (p *OIDC) Exchange(ctx context.Context, req *AuthRequest, code string) (*Identity, error) {
+	d, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.conf.RedirectURL},
+		"client_id":     {p.conf.ClientID},
+		"code_verifier": {req.CodeVerifier},
+	}
+	if p.conf.ClientSecret != "" {
+		form.Set("client_secret", p.conf.ClientSecret)
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, d.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	tokens := &tokenResponse{}
+	if err := json.Unmarshal(raw, tokens); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrExchange, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK || tokens.Error != "" {
+		return nil, fmt.Errorf("%w: %s %s", ErrExchange, tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id token", ErrExchange)
+	}
+	return p.verifyIDToken(keys, d.Issuer, tokens.IDToken, req.Nonce)
+}
+
+type idClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	AuthorizedBy  string `json:"azp"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+func (p *OIDC) verifyIDToken(keys *coreauth.RemoteKeySet, issuer string, raw string, nonce string) (*Identity, error) {
+	claims := &idClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		if pk := keys.Key(kid); pk != nil {
+			return pk, nil
+		}
+		return nil, fmt.Errorf("%w: %s", coreauth.ErrUnknownKey, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithAudience(p.conf.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedBy != p.conf.ClientID {
+		return nil, fmt.Errorf("%w: azp mismatch", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+	return &Identity{
+		Provider:      p.conf.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified && p.conf.TrustEmail,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+	}, nil
+}
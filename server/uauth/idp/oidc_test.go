@@ -0,0 +1,172 @@
+package idp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	coreauth "github.com/ajenpan/surf/core/auth"
+)
+
+// fakeOIDC is a provider which authorizes anyone as the subject "alice".
+type fakeOIDC struct {
+	*httptest.Server
+	pk *rsa.PrivateKey
+
+	mu sync.Mutex
+	// aud overrides the audience of the id tokens
+	aud   string
+	codes map[string]url.Values // code -> the query of the authorization
+}
+
+func newFakeOIDC(t *testing.T) *fakeOIDC {
+	pk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeOIDC{pk: pk, codes: map[string]url.Values{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := coreauth.NewKeySet(&pk.PublicKey).MarshalJSON()
+		w.Write(raw)
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		code, _ := randomString(16)
+		f.mu.Lock()
+		f.codes[code] = q
+		f.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?"+url.Values{"code": {code}, "state": {q.Get("state")}}.Encode(), http.StatusFound)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.mu.Lock()
+		q, has := f.codes[r.PostForm.Get("code")]
+		delete(f.codes, r.PostForm.Get("code"))
+		aud := f.aud
+		f.mu.Unlock()
+		if aud == "" {
+			aud = q.Get("client_id")
+		}
+		if !has || q.Get("client_id") != r.PostForm.Get("client_id") ||
+			q.Get("redirect_uri") != r.PostForm.Get("redirect_uri") ||
+			q.Get("code_challenge") != CodeChallenge(r.PostForm.Get("code_verifier")) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            f.URL,
+			"aud":            aud,
+			"sub":            "alice",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          q.Get("nonce"),
+			"email":          "alice@example.com",
+			"email_verified": true,
+		})
+		token.Header["kid"] = coreauth.KeyID(&pk.PublicKey)
+		signed, _ := token.SignedString(pk)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "access_token": "at", "token_type": "Bearer"})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// authorize follows the auth url, and returns the code of the redirect.
+func authorize(t *testing.T, p Provider, req *AuthRequest) string {
+	authURL, err := p.AuthURL(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.Query().Get("state") != req.State {
+		t.Fatal("the state is not returned")
+	}
+	return loc.Query().Get("code")
+}
+
+func TestOIDC(t *testing.T) {
+	f := newFakeOIDC(t)
+	p := NewOIDC(Config{
+		Name:        "fake",
+		Issuer:      f.URL,
+		ClientID:    "surf",
+		RedirectURL: "https://surf.example.com/callback",
+	}, nil)
+
+	req, err := NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := p.Exchange(context.Background(), req, authorize(t, p, req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Provider != "fake" || id.Subject != "alice" || id.Email != "alice@example.com" {
+		t.Fatal("wrong identity:", id)
+	}
+	if id.EmailVerified {
+		t.Fatal("the email of an untrusted provider is verified")
+	}
+
+	// the code of another authorization is refused by PKCE.
+	other, _ := NewAuthRequest()
+	code := authorize(t, p, req)
+	if _, err := p.Exchange(context.Background(), other, code); !errors.Is(err, ErrExchange) {
+		t.Fatal("the code is redeemed without its verifier:", err)
+	}
+
+	// the id token of another authorization is refused by the nonce.
+	code = authorize(t, p, req)
+	replayed := *req
+	replayed.Nonce = other.Nonce
+	if _, err := p.Exchange(context.Background(), &replayed, code); !errors.Is(err, ErrInvalidToken) {
+		t.Fatal("the id token of another nonce is accepted:", err)
+	}
+
+	// the id token of another client is refused by the audience.
+	f.mu.Lock()
+	f.aud = "stranger"
+	f.mu.Unlock()
+	if _, err := p.Exchange(context.Background(), req, authorize(t, p, req)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatal("the id token of another client is accepted:", err)
+	}
+	f.mu.Lock()
+	f.aud = ""
+	f.mu.Unlock()
+
+	trusted := NewOIDC(Config{Name: "fake", Issuer: f.URL, ClientID: "surf", RedirectURL: "https://surf.example.com/callback", TrustEmail: true}, nil)
+	id, err = trusted.Exchange(context.Background(), req, authorize(t, trusted, req))
+	if err != nil || !id.EmailVerified {
+		t.Fatal("the email of a trusted provider is not verified:", err)
+	}
+}
@@ -0,0 +1,379 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	msgcore "github.com/ajenpan/surf/msg/core"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+	"github.com/ajenpan/surf/server/uauth/idp"
+)
+
+const (
+	oauthStateTTL = 10 * time.Minute
+	// oauthUnamePrefix names the users created by the identity providers, like "user_k3v9x0q2ma".
+	oauthUnamePrefix = "user_"
+)
+
+// oauthState is an authorization of OAuthStart, kept until its code comes back.
+type oauthState struct {
+	Provider string `json:"provider"`
+	DeviceID string `json:"device_id,omitempty"`
+	// LinkUID is the caller which links the identity, zero for a login.
+	LinkUID int64            `json:"link_uid,omitempty"`
+	Request *idp.AuthRequest `json:"request"`
+}
+
+func oauthStateKey(state string) string {
+	return "oauth:" + state
+}
+
+func (h *Auth) storeOAuthState(s *oauthState) error {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return errors.Wrap(err, errors.CodeInternal, "marshal state failed")
+	}
+	if err := h.Cache.StoreOnce(context.Background(), oauthStateKey(s.Request.State), string(raw), oauthStateTTL); err != nil {
+		return errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "store state failed")
+	}
+	return nil
+}
+
+// takeOAuthState returns the authorization of the state, a state can be taken only once.
+func (h *Auth) takeOAuthState(state string) (*oauthState, bool) {
+	raw, has := h.Cache.TakeOnce(context.Background(), oauthStateKey(state))
+	if !has {
+		return nil, false
+	}
+	s := &oauthState{}
+	if err := json.Unmarshal([]byte(raw), s); err != nil || s.Request == nil {
+		return nil, false
+	}
+	return s, true
+}
+
+// exchangeIdentity redeems the code of the state for the identity.
+func (h *Auth) exchangeIdentity(state string, code string) (*oauthState, *idp.Identity, error) {
+	s, has := h.takeOAuthState(state)
+	if !has {
+		return nil, nil, errors.New(int32(msg.ResponseFlag_OAuthStateInvalid), "state invalid")
+	}
+	p, has := h.Providers.Get(s.Provider)
+	if !has {
+		// the provider is removed after the state.
+		return nil, nil, errors.New(int32(msg.ResponseFlag_OAuthStateInvalid), "state invalid")
+	}
+	ident, err := p.Exchange(context.Background(), s.Request, code)
+	if err != nil {
+		log.Warnf("login by %s failed: %v", s.Provider, err)
+		return nil, nil, errors.Wrap(err, int32(msg.ResponseFlag_OAuthFailed), "identity provider login failed")
+	}
+	return s, ident, nil
+}
+
+// linkIdentity binds the identity to the user, an identity is of one user, and a user has one identity of a provider.
+func linkIdentity(tx *gorm.DB, uid int64, ident *idp.Identity) (*models.UserIdentities, error) {
+	var n int64
+	err := tx.Model(&models.UserIdentities{}).
+		Where("(provider = ? AND subject = ?) OR (uid = ? AND provider = ?)", ident.Provider, ident.Subject, uid, ident.Provider).
+		Count(&n).Error
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "check identity failed")
+	}
+	if n > 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_IdentityLinked), "identity linked")
+	}
+	link := &models.UserIdentities{
+		Provider: ident.Provider,
+		Subject:  ident.Subject,
+		UID:      uid,
+		Email:    truncate(ident.Email, 64),
+		CreateAt: time.Now(),
+	}
+	if ident.EmailVerified {
+		link.EmailVerified = 1
+	}
+	if err := tx.Create(link).Error; err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "link identity failed")
+	}
+	return link, nil
+}
+
+// identityUser finds the user of the identity. A new identity is linked to the user of its email
+// if the email is verified by a trusted provider and the user has an identity of the same email verified,
+// or to a new user. The email of the users is not verified by uauth, so a new identity of a user's email
+// is refused, the user must login and link it by OAuthLink.
+func (h *Auth) identityUser(ident *idp.Identity) (*models.Users, error) {
+	link := &models.UserIdentities{}
+	res := h.DB.Where("provider = ? AND subject = ?", ident.Provider, ident.Subject).Limit(1).Find(link)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find identity failed")
+	}
+	user := &models.Users{}
+	if res.RowsAffected > 0 {
+		res := h.DB.Limit(1).Find(user, &models.Users{UID: link.UID})
+		if res.Error != nil {
+			return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+		}
+		if res.RowsAffected == 0 {
+			return nil, errors.New(int32(msg.ResponseFlag_IdentityNotFound), "the user of the identity not found")
+		}
+		return user, nil
+	}
+
+	if ident.EmailVerified && ident.Email != "" {
+		email := truncate(ident.Email, 64)
+		verified := &models.UserIdentities{}
+		res := h.DB.Where("email = ? AND email_verified <> 0", email).Order("id").Limit(1).Find(verified)
+		if res.Error != nil {
+			return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find identity failed")
+		}
+		if res.RowsAffected > 0 {
+			res := h.DB.Where("uid = ? AND guest = 0", verified.UID).Limit(1).Find(user)
+			if res.Error != nil {
+				return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+			}
+			if res.RowsAffected > 0 {
+				if _, err := linkIdentity(h.DB, user.UID, ident); err != nil {
+					return nil, err
+				}
+				log.Infof("identity of %s is linked to uid %d by the verified email", ident.Provider, user.UID)
+				return user, nil
+			}
+		}
+
+		var n int64
+		if err := h.DB.Model(&models.Users{}).Where("email = ?", email).Count(&n).Error; err != nil {
+			return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+		}
+		if n > 0 {
+			return nil, errors.New(int32(msg.ResponseFlag_IdentityNotFound), "identity not found, login and link it")
+		}
+	}
+
+	uname, err := newUname(oauthUnamePrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "generate uname failed")
+	}
+	user = &models.Users{
+		Uname:    uname,
+		Nickname: truncate(ident.Name, 32),
+		Avatar:   truncate(ident.Picture, 1024),
+		Gender:   'X',
+		Role:     uint32(msgcore.Role_RoleUser),
+	}
+	if ident.EmailVerified {
+		user.Email = truncate(ident.Email, 64)
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "create user failed")
+		}
+		_, err := linkIdentity(tx, user.UID, ident)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// truncate cuts the string to at most n bytes, on a rune boundary.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// canUnlink tells the user can still login without the identity, by the password, the device of a guest,
+// or another identity.
+func canUnlink(user *models.Users, identities int) bool {
+	return user.Passwd != "" || user.Guest != 0 || identities > 1
+}
+
+func identityInfoOf(link *models.UserIdentities) *msg.Identity {
+	return &msg.Identity{
+		Provider: link.Provider,
+		Email:    link.Email,
+		Created:  link.CreateAt.Unix(),
+	}
+}
+
+// OAuthProviders lists the identity providers.
+func (h *Auth) OAuthProviders(ctx context.Context, in *msg.OAuthProvidersRequest) (*msg.OAuthProvidersResponse, error) {
+	return &msg.OAuthProvidersResponse{Providers: h.Providers.Names()}, nil
+}
+
+// OAuthStart starts the authorization code flow with PKCE, the verifier and the nonce are kept by the state.
+func (h *Auth) OAuthStart(ctx context.Context, in *msg.OAuthStartRequest) (*msg.OAuthStartResponse, error) {
+	p, has := h.Providers.Get(in.Provider)
+	if !has {
+		return nil, errors.InvalidArgument("unknown provider %s", in.Provider)
+	}
+	s := &oauthState{Provider: in.Provider, DeviceID: in.DeviceId}
+	if in.Link {
+		caller, _ := coreauth.UserFromContext(ctx)
+		if caller == nil {
+			return nil, errors.Unauthenticated("login required")
+		}
+		s.LinkUID = int64(caller.UserID())
+	}
+
+	req, err := idp.NewAuthRequest()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "generate state failed")
+	}
+	authURL, err := p.AuthURL(context.Background(), req)
+	if err != nil {
+		log.Warnf("start login by %s failed: %v", in.Provider, err)
+		return nil, errors.Wrap(err, errors.CodeUnavailable, "identity provider unavailable")
+	}
+	s.Request = req
+	if err := h.storeOAuthState(s); err != nil {
+		return nil, err
+	}
+	return &msg.OAuthStartResponse{
+		AuthorizeUrl: authURL,
+		State:        req.State,
+		ExpiresIn:    int64(oauthStateTTL / time.Second),
+	}, nil
+}
+
+// OAuthLogin logs in the user of the identity like Login, the user with the second factor gets a challenge.
+func (h *Auth) OAuthLogin(ctx context.Context, in *msg.OAuthLoginRequest) (*msg.LoginResponse, error) {
+	s, ident, err := h.exchangeIdentity(in.State, in.Code)
+	if err != nil {
+		return nil, err
+	}
+	if s.LinkUID != 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_OAuthStateInvalid), "the state is to link")
+	}
+	user, err := h.identityUser(ident)
+	if err != nil {
+		return nil, err
+	}
+	if user.Stat != 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_StatErr), "user stat is not ok")
+	}
+
+	out := &msg.LoginResponse{}
+	totp, err := h.enabledTotp(user.UID)
+	if err != nil {
+		return nil, err
+	}
+	if totp != nil {
+		out.Challenge, err = h.storeChallenge(user, s.DeviceID)
+		out.ChallengeExpiresIn = int64(challengeTTL / time.Second)
+		return out, err
+	}
+
+	tokens, err := h.login(user, clientOf(core.ContextOf(ctx), s.DeviceID), false)
+	if err != nil {
+		return nil, err
+	}
+	out.AssessToken = tokens.access
+	out.RefreshToken = tokens.refresh
+	out.ExpiresIn = tokens.expiresIn
+	out.UserInfo = userInfoOf(user)
+	return out, nil
+}
+
+// OAuthLink links the identity to the caller, who started the state with link.
+func (h *Auth) OAuthLink(ctx context.Context, in *msg.OAuthLinkRequest) (*msg.OAuthLinkResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	s, ident, err := h.exchangeIdentity(in.State, in.Code)
+	if err != nil {
+		return nil, err
+	}
+	uid := int64(caller.UserID())
+	if s.LinkUID != uid {
+		return nil, errors.New(int32(msg.ResponseFlag_OAuthStateInvalid), "the state is not to link the caller")
+	}
+	link, err := linkIdentity(h.DB, uid, ident)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("identity of %s is linked to uid %d", ident.Provider, uid)
+	return &msg.OAuthLinkResponse{Identity: identityInfoOf(link)}, nil
+}
+
+func (h *Auth) findIdentities(uid int64) ([]*models.UserIdentities, error) {
+	links := []*models.UserIdentities{}
+	err := h.DB.Where("uid = ?", uid).Order(models.UserIdentitiesColumns.CreateAt).Find(&links).Error
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "find identities failed")
+	}
+	return links, nil
+}
+
+// ListIdentities lists the identities linked to the caller.
+func (h *Auth) ListIdentities(ctx context.Context, in *msg.ListIdentitiesRequest) (*msg.ListIdentitiesResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	links, err := h.findIdentities(int64(caller.UserID()))
+	if err != nil {
+		return nil, err
+	}
+	out := &msg.ListIdentitiesResponse{Identities: make([]*msg.Identity, 0, len(links))}
+	for _, link := range links {
+		out.Identities = append(out.Identities, identityInfoOf(link))
+	}
+	return out, nil
+}
+
+// UnlinkIdentity unlinks the identity of the provider from the caller, unless the caller can't login without it.
+func (h *Auth) UnlinkIdentity(ctx context.Context, in *msg.UnlinkIdentityRequest) (*msg.UnlinkIdentityResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	uid := int64(caller.UserID())
+
+	user := &models.Users{UID: uid}
+	res := h.DB.Limit(1).Find(user, user)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_UnameNotFound), "uname not found")
+	}
+	links, err := h.findIdentities(uid)
+	if err != nil {
+		return nil, err
+	}
+	var target *models.UserIdentities
+	for _, link := range links {
+		if link.Provider == in.Provider {
+			target = link
+		}
+	}
+	if target == nil {
+		return nil, errors.New(int32(msg.ResponseFlag_IdentityNotFound), "identity not found")
+	}
+	if !canUnlink(user, len(links)) {
+		return nil, errors.New(int32(msg.ResponseFlag_LastLoginMethod), "the only login method")
+	}
+	if err := h.DB.Where("id = ?", target.ID).Delete(&models.UserIdentities{}).Error; err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "unlink identity failed")
+	}
+	log.Infof("identity of %s is unlinked from uid %d", in.Provider, uid)
+	return &msg.UnlinkIdentityResponse{}, nil
+}
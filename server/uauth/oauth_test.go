@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/ajenpan/surf/core/errors"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+	"github.com/ajenpan/surf/server/uauth/idp"
+)
+
+// fakeProvider authorizes the code "code-<challenge>", so only the verifier of the state redeems it.
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string { return "fake" }
+
+func (fakeProvider) AuthURL(ctx context.Context, req *idp.AuthRequest) (string, error) {
+	return "https://idp.example.com/authorize?" + url.Values{
+		"state":          {req.State},
+		"code_challenge": {idp.CodeChallenge(req.CodeVerifier)},
+	}.Encode(), nil
+}
+
+func (fakeProvider) Exchange(ctx context.Context, req *idp.AuthRequest, code string) (*idp.Identity, error) {
+	if code != "code-"+idp.CodeChallenge(req.CodeVerifier) {
+		return nil, idp.ErrExchange
+	}
+	return &idp.Identity{Provider: "fake", Subject: "alice"}, nil
+}
+
+func TestOAuthState(t *testing.T) {
+	h := &Auth{AuthOptions: AuthOptions{Cache: cache.NewMemory(), Providers: idp.NewRegistry(fakeProvider{})}}
+
+	if _, err := h.OAuthStart(nil, &msg.OAuthStartRequest{Provider: "unknown"}); err == nil {
+		t.Fatal("an unknown provider is started")
+	}
+	out, err := h.OAuthStart(nil, &msg.OAuthStartRequest{Provider: "fake", DeviceId: "device-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, _ := url.Parse(out.AuthorizeUrl)
+	code := "code-" + authURL.Query().Get("code_challenge")
+
+	if _, _, err := h.exchangeIdentity(out.State, "code-wrong"); !isFlag(err, msg.ResponseFlag_OAuthFailed) {
+		t.Fatal("a wrong code is redeemed:", err)
+	}
+	// the state is used by the failure.
+	if _, _, err := h.exchangeIdentity(out.State, code); !isFlag(err, msg.ResponseFlag_OAuthStateInvalid) {
+		t.Fatal("a used state is accepted:", err)
+	}
+
+	out, _ = h.OAuthStart(nil, &msg.OAuthStartRequest{Provider: "fake", DeviceId: "device-a"})
+	authURL, _ = url.Parse(out.AuthorizeUrl)
+	s, ident, err := h.exchangeIdentity(out.State, "code-"+authURL.Query().Get("code_challenge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DeviceID != "device-a" || s.LinkUID != 0 || ident.Subject != "alice" {
+		t.Fatalf("wrong state %+v of the identity %+v", s, ident)
+	}
+}
+
+func isFlag(err error, flag msg.ResponseFlag) bool {
+	e, ok := errors.As(err)
+	return ok && e.Code == int32(flag)
+}
+
+func TestCanUnlink(t *testing.T) {
+	if canUnlink(&models.Users{}, 1) {
+		t.Fatal("the only login method is unlinked")
+	}
+	if !canUnlink(&models.Users{Passwd: "hashed"}, 1) || !canUnlink(&models.Users{Guest: 1}, 1) || !canUnlink(&models.Users{}, 2) {
+		t.Fatal("the user with another login method can't unlink")
+	}
+	if got := truncate("用户名", 4); got != "用" {
+		t.Fatal("wrong truncate:", got)
+	}
+}
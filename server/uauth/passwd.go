@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+// DefaultPasswdIterations is the PBKDF2 cost of the passwords, as recommended by OWASP for HMAC-SHA256.
+const DefaultPasswdIterations = 600000
+
+const passwdHashPrefix = "pbkdf2-sha256"
+
+// passwdHasher stores the passwords as "pbkdf2-sha256$<iterations>$<salt>$<hash>".
+// The rows of the old deployments keep the plaintext, they are hashed at the next login.
+type passwdHasher struct {
+	iterations int
+
+	// dummy is verified for no password, so the check takes as long as the one of a password.
+	dummyOnce sync.Once
+	dummy     string
+}
+
+func (p *passwdHasher) hash(passwd string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	sum := pbkdf2SHA256([]byte(passwd), salt, p.iterations, sha256.Size)
+	return fmt.Sprintf("%s$%d$%s$%s", passwdHashPrefix, p.iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(sum)), nil
+}
+
+// verify compares the password in constant time,
+// rehash tells the stored one is plaintext or hashed with a lower cost.
+// An empty stored one is no password, like the one of a guest or an unknown user, nothing matches it.
+func (p *passwdHasher) verify(stored string, passwd string) (ok bool, rehash bool) {
+	if stored == "" {
+		p.dummyOnce.Do(func() {
+			p.dummy, _ = p.hash("")
+		})
+		if p.dummy != "" {
+			p.verify(p.dummy, passwd)
+		}
+		return false, false
+	}
+	if !strings.HasPrefix(stored, passwdHashPrefix+"$") {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(passwd)) == 1, true
+	}
+	parts := strings.Split(stored, "$")
+	if len(parts) != 4 {
+		return false, false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, false
+	}
+	sum := pbkdf2SHA256([]byte(passwd), salt, iterations, len(want))
+	return subtle.ConstantTimeCompare(sum, want) == 1, iterations < p.iterations
+}
+
+// pbkdf2SHA256 derives the key of RFC 8018 with HMAC-SHA256.
+func pbkdf2SHA256(passwd, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, passwd)
+	blocks := (keyLen + sha256.Size - 1) / sha256.Size
+	ret := make([]byte, 0, blocks*sha256.Size)
+	u := make([]byte, sha256.Size)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		ret = append(ret, t...)
+	}
+	return ret[:keyLen]
+}
+
+const resetCodeTTL = 15 * time.Minute
+
+// checkPasswd verifies the password of the user, and upgrades the stored one if it needs rehash.
+func (h *Auth) checkPasswd(user *models.Users, passwd string) bool {
+	ok, rehash := h.passwd.verify(user.Passwd, passwd)
+	if !ok || !rehash {
+		return ok
+	}
+	hashed, err := h.passwd.hash(passwd)
+	if err != nil {
+		log.Errorf("rehash passwd of uid %d failed: %v", user.UID, err)
+		return true
+	}
+	// only the row which still has the old one is updated.
+	res := h.DB.Model(&models.Users{}).
+		Where("uid = ? AND passwd = ?", user.UID, user.Passwd).
+		Update(models.UsersColumns.Passwd, hashed)
+	if res.Error != nil {
+		log.Errorf("rehash passwd of uid %d failed: %v", user.UID, res.Error)
+		return true
+	}
+	user.Passwd = hashed
+	return true
+}
+
+// updatePasswd changes the password and revokes the sessions of the user,
+// except the one of the access token keepTokenID, so the old password logs in nowhere.
+func (h *Auth) updatePasswd(uid int64, passwd string, keepTokenID string) error {
+	hashed, err := h.passwd.hash(passwd)
+	if err != nil {
+		return errors.Wrap(err, errors.CodeInternal, "hash passwd failed")
+	}
+	res := h.DB.Model(&models.Users{}).Where("uid = ?", uid).Update(models.UsersColumns.Passwd, hashed)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "update passwd failed")
+	}
+	h.Cache.DeleteUser(context.Background(), uid)
+
+	sessions, err := h.findSessions(uid)
+	if err != nil {
+		return err
+	}
+	revoked := sessions[:0]
+	for _, s := range sessions {
+		if keepTokenID == "" || s.TokenID != keepTokenID {
+			revoked = append(revoked, s)
+		}
+	}
+	_, err = h.revokeSessions(revoked)
+	return err
+}
+
+// ModifyPasswd changes the password of the caller, the old one must be given.
+// The other sessions of the caller are revoked, the current one is kept.
+func (h *Auth) ModifyPasswd(ctx context.Context, in *msg.ModifyPasswdRequest) (*msg.ModifyPasswdResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+
+	user := &models.Users{UID: int64(caller.UserID())}
+	res := h.DB.Limit(1).Find(user, user)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_UnameNotFound), "uname not found")
+	}
+	if ok, _ := h.passwd.verify(user.Passwd, in.OldPasswd); !ok {
+		return nil, errors.New(int32(msg.ResponseFlag_PasswdWrong), "passwd wrong")
+	}
+
+	if err := h.updatePasswd(user.UID, in.NewPasswd, callerTokenID(caller)); err != nil {
+		return nil, err
+	}
+	return &msg.ModifyPasswdResponse{}, nil
+}
+
+// ResetPasswd sends a reset code to the user if the code is not given, or resets the password with the code.
+// A code can be tried only once, and an unknown user looks the same as a known one.
+// All the sessions of the user are revoked by the reset.
+func (h *Auth) ResetPasswd(ctx context.Context, in *msg.ResetPasswdRequest) (*msg.ResetPasswdResponse, error) {
+	if h.SendResetCode == nil {
+		return nil, errors.Unimplemented("reset passwd is not supported")
+	}
+
+	user := &models.Users{Uname: in.Uname}
+	res := h.DB.Limit(1).Find(user, user)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+
+	// a guest has no password to reset, it is upgraded by UpgradeGuest.
+	found := res.RowsAffected != 0 && user.Guest == 0
+	if in.ResetCode == "" {
+		if !found {
+			return &msg.ResetPasswdResponse{CodeSent: true}, nil
+		}
+		code, err := newResetCode()
+		if err != nil {
+			return nil, errors.Wrap(err, errors.CodeInternal, "generate reset code failed")
+		}
+		if err := h.Cache.StoreOnce(context.Background(), resetCodeKey(user.UID), code, resetCodeTTL); err != nil {
+			return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "store reset code failed")
+		}
+		if err := h.SendResetCode(user, code); err != nil {
+			return nil, errors.Wrap(err, errors.CodeUnavailable, "send reset code failed")
+		}
+		return &msg.ResetPasswdResponse{CodeSent: true}, nil
+	}
+
+	if len(in.NewPasswd) < 6 {
+		return nil, errors.InvalidArgument("new passwd is too short")
+	}
+	if !found {
+		return nil, errors.New(int32(msg.ResponseFlag_ResetCodeWrong), "reset code wrong")
+	}
+	code, has := h.Cache.TakeOnce(context.Background(), resetCodeKey(user.UID))
+	if !has || subtle.ConstantTimeCompare([]byte(code), []byte(in.ResetCode)) != 1 {
+		return nil, errors.New(int32(msg.ResponseFlag_ResetCodeWrong), "reset code wrong")
+	}
+	if err := h.updatePasswd(user.UID, in.NewPasswd, ""); err != nil {
+		return nil, err
+	}
+	return &msg.ResetPasswdResponse{}, nil
+}
+
+func resetCodeKey(uid int64) string {
+	return "resetpasswd:" + strconv.FormatInt(uid, 10)
+}
+
+// newResetCode is 6 random digits.
+func newResetCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2(t *testing.T) {
+	// the PBKDF2-HMAC-SHA256 vector of RFC 7914.
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1, 64)); got != want {
+		t.Fatal("wrong derived key:", got)
+	}
+}
+
+func TestPasswdHasher(t *testing.T) {
+	p := &passwdHasher{iterations: 1000}
+	hashed, err := p.hash("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hashed, "pbkdf2-sha256$1000$") || len(hashed) > 128 {
+		t.Fatal("wrong hashed passwd:", hashed)
+	}
+	if other, _ := p.hash("123456"); other == hashed {
+		t.Fatal("the passwd is hashed without salt")
+	}
+
+	if ok, rehash := p.verify(hashed, "123456"); !ok || rehash {
+		t.Fatal("verify the hashed passwd:", ok, rehash)
+	}
+	if ok, _ := p.verify(hashed, "1234567"); ok {
+		t.Fatal("a wrong passwd is verified")
+	}
+
+	// the plaintext of the old rows and the lower costs need rehash.
+	if ok, rehash := p.verify("123456", "123456"); !ok || !rehash {
+		t.Fatal("verify the plaintext passwd:", ok, rehash)
+	}
+	stronger := &passwdHasher{iterations: 2000}
+	if ok, rehash := stronger.verify(hashed, "123456"); !ok || !rehash {
+		t.Fatal("verify the passwd of a lower cost:", ok, rehash)
+	}
+	if ok, _ := p.verify("pbkdf2-sha256$x$y", "123456"); ok {
+		t.Fatal("a malformed hash is verified")
+	}
+
+	// no password matches nothing, but it is checked against a hash of the same cost.
+	if ok, _ := p.verify("", ""); ok {
+		t.Fatal("no passwd is verified")
+	}
+	if !strings.HasPrefix(p.dummy, "pbkdf2-sha256$1000$") {
+		t.Fatal("no passwd is not checked against a hash:", p.dummy)
+	}
+}
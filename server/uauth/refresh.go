@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// refreshTokens issues the refresh tokens as "<family id>.<secret>", only the hash of the secret is stored.
+// Every use rotates the secret, and the use of a rotated secret revokes the whole family,
+// since either the user or the thief of the token has a newer one.
+type refreshTokens struct {
+	cache cache.AuthCache
+	ttl   time.Duration
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRefreshSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// issue starts a new family for a login, its tokens keep the role granted by the login.
+func (r *refreshTokens) issue(ctx context.Context, uid int64, deviceID string, role uint32) (string, *cache.RefreshFamily, error) {
+	secret, err := newRefreshSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	f := &cache.RefreshFamily{
+		ID:        uuid.NewString(),
+		UID:       uid,
+		DeviceID:  deviceID,
+		Role:      role,
+		TokenHash: hashRefreshSecret(secret),
+		CreateAt:  now,
+		ExpireAt:  now.Add(r.ttl),
+	}
+	if err := r.cache.StoreRefreshFamily(ctx, f); err != nil {
+		return "", nil, err
+	}
+	return f.ID + "." + secret, f, nil
+}
+
+// rotate exchanges the token for a new one of the same family.
+func (r *refreshTokens) rotate(ctx context.Context, token string, deviceID string) (string, *cache.RefreshFamily, error) {
+	id, secret, found := strings.Cut(token, ".")
+	if !found {
+		return "", nil, errors.New(int32(msg.ResponseFlag_RefreshTokenInvalid), "malformed refresh token")
+	}
+	f := r.cache.FetchRefreshFamily(ctx, id)
+	if f == nil {
+		return "", nil, errors.New(int32(msg.ResponseFlag_RefreshTokenInvalid), "refresh token not found")
+	}
+	if f.DeviceID != "" && f.DeviceID != deviceID {
+		return "", nil, errors.New(int32(msg.ResponseFlag_DeviceMismatch), "refresh token of another device")
+	}
+
+	oldHash := hashRefreshSecret(secret)
+	if subtle.ConstantTimeCompare([]byte(oldHash), []byte(f.TokenHash)) != 1 {
+		r.revoke(ctx, f)
+		return "", nil, errors.New(int32(msg.ResponseFlag_RefreshTokenReused), "refresh token reused")
+	}
+
+	next, err := newRefreshSecret()
+	if err != nil {
+		return "", nil, err
+	}
+	swapped, err := r.cache.SwapRefreshToken(ctx, f.ID, oldHash, hashRefreshSecret(next))
+	if err != nil {
+		return "", nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "rotate refresh token failed")
+	}
+	if !swapped {
+		// used by a concurrent request
+		r.revoke(ctx, f)
+		return "", nil, errors.New(int32(msg.ResponseFlag_RefreshTokenReused), "refresh token reused")
+	}
+	return f.ID + "." + next, f, nil
+}
+
+func (r *refreshTokens) revoke(ctx context.Context, f *cache.RefreshFamily) {
+	log.Warnf("refresh token of uid %d reused, revoke the family %s", f.UID, f.ID)
+	r.cache.DeleteRefreshFamily(ctx, f.ID)
+}
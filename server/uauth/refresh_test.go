@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ajenpan/surf/core/errors"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+)
+
+func codeOf(err error) int32 {
+	if e, ok := errors.As(err); ok {
+		return e.Code
+	}
+	return 0
+}
+
+func TestRefreshTokenRotate(t *testing.T) {
+	ctx := context.Background()
+	r := &refreshTokens{cache: cache.NewMemory(), ttl: time.Hour}
+
+	first, family, err := r.issue(ctx, 10001, "device-a", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := r.rotate(ctx, first, "device-b"); codeOf(err) != int32(msg.ResponseFlag_DeviceMismatch) {
+		t.Fatal("expected DeviceMismatch, got", err)
+	}
+
+	second, f, err := r.rotate(ctx, first, "device-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.ID != family.ID || f.UID != 10001 || f.Role != 1 || second == first {
+		t.Fatalf("wrong rotated token: %v, %s", f, second)
+	}
+
+	// the reuse of the first token revokes the family, the second one is invalid too.
+	if _, _, err := r.rotate(ctx, first, "device-a"); codeOf(err) != int32(msg.ResponseFlag_RefreshTokenReused) {
+		t.Fatal("expected RefreshTokenReused, got", err)
+	}
+	if _, _, err := r.rotate(ctx, second, "device-a"); codeOf(err) != int32(msg.ResponseFlag_RefreshTokenInvalid) {
+		t.Fatal("expected RefreshTokenInvalid, got", err)
+	}
+}
+
+func TestRefreshTokenExpire(t *testing.T) {
+	ctx := context.Background()
+	r := &refreshTokens{cache: cache.NewMemory(), ttl: time.Millisecond}
+
+	token, _, err := r.issue(ctx, 10001, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, _, err := r.rotate(ctx, token, ""); codeOf(err) != int32(msg.ResponseFlag_RefreshTokenInvalid) {
+		t.Fatal("expected RefreshTokenInvalid, got", err)
+	}
+	if _, _, err := r.rotate(ctx, "malformed", ""); codeOf(err) != int32(msg.ResponseFlag_RefreshTokenInvalid) {
+		t.Fatal("expected RefreshTokenInvalid, got", err)
+	}
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+
+	msg "github.com/ajenpan/surf/msg/uauth"
+)
+
+// revocations checks the tokens against the revocations stored in the cache,
+// it is the RevokeChecker of the servers which share the cache with uauth.
+type revocations struct {
+	cache cache.AuthCache
+}
+
+func (r *revocations) IsRevoked(u coreauth.User) bool {
+	ctx := context.Background()
+	var token *coreauth.UserInfo
+	if tu, ok := u.(coreauth.TokenUser); ok {
+		token = tu.TokenInfo()
+	}
+	if token != nil && token.TokenID != "" && r.cache.IsTokenRevoked(ctx, token.TokenID) {
+		return true
+	}
+	validAfter := r.cache.UserValidAfter(ctx, int64(u.UserID()))
+	if validAfter.IsZero() {
+		return false
+	}
+	// the tokens are issued in seconds, the ones of the same second as the revocation are revoked too.
+	return token == nil || token.IssuedAt <= validAfter.Unix()
+}
+
+// RevokeChecker denies the tokens revoked by Logout and RevokeUser,
+// wrap the verifier of the tokens by coreauth.WithRevocation with it.
+func (h *Auth) RevokeChecker() coreauth.RevokeChecker {
+	return h.revoked
+}
+
+func (h *Auth) publishRevocation(r *coreauth.Revocation) {
+	e, err := coreauth.RevocationEvent(r)
+	if err != nil {
+		log.Errorf("marshal revocation failed: %v", err)
+		return
+	}
+	h.Publisher.Publish(e)
+}
+
+// Logout revokes the access token of the caller, and the refresh token family of the login if it is given.
+func (h *Auth) Logout(ctx context.Context, in *msg.LogoutRequest) (*msg.LogoutResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+
+	if tu, ok := caller.(coreauth.TokenUser); ok && tu.TokenInfo().TokenID != "" {
+		token := tu.TokenInfo()
+		if err := h.Cache.RevokeToken(context.Background(), token.TokenID, time.Unix(token.ExpireAt, 0)); err != nil {
+			return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "revoke token failed")
+		}
+		h.publishRevocation(&coreauth.Revocation{
+			TokenId:  token.TokenID,
+			Uid:      token.UId,
+			ExpireAt: token.ExpireAt,
+		})
+	}
+
+	if id, _, found := strings.Cut(in.RefreshToken, "."); found {
+		// only the family of the caller can be revoked.
+		if f := h.Cache.FetchRefreshFamily(context.Background(), id); f != nil && f.UID == int64(caller.UserID()) {
+			h.Cache.DeleteRefreshFamily(context.Background(), id)
+			if err := h.DB.Where("id = ?", id).Delete(&models.UserSessions{}).Error; err != nil {
+				log.Errorf("delete the session %s failed: %v", id, err)
+			}
+		}
+	}
+	return &msg.LogoutResponse{}, nil
+}
+
+// RevokeUser revokes all the tokens and refresh tokens of the user issued until now,
+// and disables the user if ban is set. The servers close the conns of the user by the event.
+func (h *Auth) RevokeUser(ctx context.Context, in *msg.RevokeUserRequest) (*msg.RevokeUserResponse, error) {
+	if in.Ban {
+		res := h.DB.Model(&models.Users{}).Where("uid = ?", in.Uid).Update(models.UsersColumns.Stat, 1)
+		if res.Error != nil {
+			return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "ban user failed")
+		}
+		if res.RowsAffected == 0 {
+			return nil, errors.NotFound("user %d not found", in.Uid)
+		}
+	}
+
+	now := time.Now()
+	// kept as long as a refresh token family lives, the families created before are refused.
+	if err := h.Cache.RevokeUser(context.Background(), in.Uid, now, h.RefreshTokenTTL); err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "revoke user failed")
+	}
+	h.Cache.DeleteUser(context.Background(), in.Uid)
+	// the tokens of the sessions are revoked above.
+	if err := h.DB.Where("uid = ?", in.Uid).Delete(&models.UserSessions{}).Error; err != nil {
+		log.Errorf("delete the sessions of uid %d failed: %v", in.Uid, err)
+	}
+
+	h.publishRevocation(&coreauth.Revocation{
+		Uid:        uint32(in.Uid),
+		ValidAfter: now.Unix(),
+		ExpireAt:   now.Add(h.AccessTokenTTL).Unix(),
+	})
+	log.Infof("uid %d is revoked, ban: %v", in.Uid, in.Ban)
+	return &msg.RevokeUserResponse{}, nil
+}
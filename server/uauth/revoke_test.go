@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/server/uauth/database/cache"
+)
+
+func TestRevocations(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewMemory()
+	r := &revocations{cache: c}
+
+	now := time.Now()
+	token := &coreauth.UserInfo{UId: 10001, TokenID: "jti-1", IssuedAt: now.Unix() - 10, ExpireAt: now.Add(time.Minute).Unix()}
+	if r.IsRevoked(token) {
+		t.Fatal("the token is revoked before any revocation")
+	}
+
+	if err := c.RevokeToken(ctx, token.TokenID, time.Unix(token.ExpireAt, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsRevoked(token) {
+		t.Fatal("the revoked token is not revoked")
+	}
+
+	other := &coreauth.UserInfo{UId: 10001, TokenID: "jti-2", IssuedAt: now.Unix() - 10}
+	if err := c.RevokeUser(ctx, 10001, now, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsRevoked(other) {
+		t.Fatal("the token issued before valid after is not revoked")
+	}
+	same := &coreauth.UserInfo{UId: 10001, TokenID: "jti-4", IssuedAt: now.Unix()}
+	if !r.IsRevoked(same) {
+		t.Fatal("the token issued in the second of valid after is not revoked")
+	}
+	relogin := &coreauth.UserInfo{UId: 10001, TokenID: "jti-3", IssuedAt: now.Unix() + 1}
+	if r.IsRevoked(relogin) {
+		t.Fatal("the token issued after valid after is revoked")
+	}
+
+	// a later revocation never moves valid after back.
+	if err := c.RevokeUser(ctx, 10001, now.Add(-time.Hour), time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if !c.UserValidAfter(ctx, 10001).Equal(now) {
+		t.Fatal("valid after is moved back")
+	}
+}
+
+// TestIntrospectByAuthClient calls Introspect over the http server of core, as the services do by AuthClient.
+func TestIntrospectByAuthClient(t *testing.T) {
+	keys, err := coreauth.NewKeyRing(coreauth.KeyRingOptions{Bits: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := cache.NewMemory()
+	h := &Auth{AuthOptions: AuthOptions{Keys: keys, Cache: c}, revoked: &revocations{cache: c}}
+	surf := core.New(core.Options{CTByName: h.CTByName()})
+	svr := httptest.NewServer(surf.HttpHandler())
+	defer svr.Close()
+
+	token, err := keys.Sign(&coreauth.UserInfo{UId: 10001, UName: "surf_user"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := coreauth.NewAuthClient(coreauth.AuthClientOptions{RemoteUrl: svr.URL}).Verify([]byte(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.UId != 10001 || u.UName != "surf_user" || u.TokenID == "" {
+		t.Fatal("wrong user:", u)
+	}
+
+	if err := h.Cache.RevokeToken(context.Background(), u.TokenID, time.Unix(u.ExpireAt, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := coreauth.NewAuthClient(coreauth.AuthClientOptions{RemoteUrl: svr.URL}).Verify([]byte(token)); !errors.Is(err, coreauth.ErrTokenInactive) {
+		t.Fatal("the revoked token is active:", err)
+	}
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+const maxUserAgentLen = 256
+
+// loginClient is where a login comes from, it is recorded in the session of the login.
+type loginClient struct {
+	deviceID  string
+	ip        string
+	userAgent string
+}
+
+// clientOf reads the ip and the user agent of the call, the user agent is only known over http.
+func clientOf(ctx core.Context, deviceID string) *loginClient {
+	c := &loginClient{deviceID: deviceID}
+	if ctx == nil {
+		return c
+	}
+	c.ip = remoteIP(ctx.RemoteAddr())
+	if hc, ok := ctx.(interface{ Request() *http.Request }); ok {
+		c.userAgent = hc.Request().UserAgent()
+	}
+	if len(c.userAgent) > maxUserAgentLen {
+		c.userAgent = c.userAgent[:maxUserAgentLen]
+	}
+	return c
+}
+
+// callerTokenID is the jti of the token of the caller, empty if it is unknown.
+func callerTokenID(caller coreauth.User) string {
+	if tu, ok := caller.(coreauth.TokenUser); ok {
+		return tu.TokenInfo().TokenID
+	}
+	return ""
+}
+
+// createSession records the login, its id is the refresh token family.
+func (h *Auth) createSession(s *models.UserSessions) error {
+	if err := h.DB.Create(s).Error; err != nil {
+		return errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "create session failed")
+	}
+	return nil
+}
+
+// touchSession records the access token issued by the refresh, and where it comes from.
+// The session of a family issued before the sessions were recorded is created here.
+func (h *Auth) touchSession(s *models.UserSessions) error {
+	updates := map[string]interface{}{
+		models.UserSessionsColumns.TokenID:     s.TokenID,
+		models.UserSessionsColumns.TokenExpire: s.TokenExpire,
+		models.UserSessionsColumns.LastSeenAt:  s.LastSeenAt,
+		models.UserSessionsColumns.IP:          s.IP,
+	}
+	if s.UserAgent != "" {
+		updates[models.UserSessionsColumns.UserAgent] = s.UserAgent
+	}
+	res := h.DB.Model(&models.UserSessions{}).Where("id = ?", s.ID).Updates(updates)
+	if res.Error != nil {
+		return errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "update session failed")
+	}
+	if res.RowsAffected == 0 {
+		return h.createSession(s)
+	}
+	return nil
+}
+
+// findSessions lists the live sessions of the user, the expired ones are dropped.
+func (h *Auth) findSessions(uid int64) ([]*models.UserSessions, error) {
+	now := time.Now()
+	if err := h.DB.Where("uid = ? AND expire_at <= ?", uid, now).Delete(&models.UserSessions{}).Error; err != nil {
+		log.Errorf("drop the expired sessions of uid %d failed: %v", uid, err)
+	}
+	sessions := []*models.UserSessions{}
+	err := h.DB.Where("uid = ? AND expire_at > ?", uid, now).
+		Order(models.UserSessionsColumns.LastSeenAt + " DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "find sessions failed")
+	}
+	return sessions, nil
+}
+
+// revokeSessions denies the refresh tokens and the latest access tokens of the sessions,
+// the servers close the conns of the access tokens by the events.
+func (h *Auth) revokeSessions(sessions []*models.UserSessions) (int32, error) {
+	if len(sessions) == 0 {
+		return 0, nil
+	}
+	ctx := context.Background()
+	now := time.Now().Unix()
+	ids := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		h.Cache.DeleteRefreshFamily(ctx, s.ID)
+		if s.TokenID != "" && s.TokenExpire > now {
+			if err := h.Cache.RevokeToken(ctx, s.TokenID, time.Unix(s.TokenExpire, 0)); err != nil {
+				return 0, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "revoke token failed")
+			}
+			h.publishRevocation(&coreauth.Revocation{
+				TokenId:  s.TokenID,
+				Uid:      uint32(s.UID),
+				ExpireAt: s.TokenExpire,
+			})
+		}
+		ids = append(ids, s.ID)
+	}
+	res := h.DB.Where("id IN ?", ids).Delete(&models.UserSessions{})
+	if res.Error != nil {
+		return 0, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "delete sessions failed")
+	}
+	log.Infof("revoked %d sessions of uid %d", len(ids), sessions[0].UID)
+	return int32(len(ids)), nil
+}
+
+// selectSessions is the session of the id, or all the sessions if the id is empty.
+func (h *Auth) selectSessions(uid int64, id string) ([]*models.UserSessions, error) {
+	sessions, err := h.findSessions(uid)
+	if err != nil || id == "" {
+		return sessions, err
+	}
+	for _, s := range sessions {
+		if s.ID == id {
+			return []*models.UserSessions{s}, nil
+		}
+	}
+	return nil, errors.New(int32(msg.ResponseFlag_SessionNotFound), "session not found")
+}
+
+func sessionInfoOf(s *models.UserSessions, currentTokenID string) *msg.Session {
+	return &msg.Session{
+		SessionId: s.ID,
+		DeviceId:  s.DeviceID,
+		Ip:        s.IP,
+		UserAgent: s.UserAgent,
+		Created:   s.CreateAt.Unix(),
+		LastSeen:  s.LastSeenAt.Unix(),
+		Expires:   s.ExpireAt.Unix(),
+		Current:   currentTokenID != "" && s.TokenID == currentTokenID,
+	}
+}
+
+func sessionInfosOf(sessions []*models.UserSessions, currentTokenID string) []*msg.Session {
+	ret := make([]*msg.Session, 0, len(sessions))
+	for _, s := range sessions {
+		ret = append(ret, sessionInfoOf(s, currentTokenID))
+	}
+	return ret
+}
+
+// ListSessions lists the sessions of the caller, the current one is of the latest access token of its session.
+func (h *Auth) ListSessions(ctx context.Context, in *msg.ListSessionsRequest) (*msg.ListSessionsResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	sessions, err := h.findSessions(int64(caller.UserID()))
+	if err != nil {
+		return nil, err
+	}
+	return &msg.ListSessionsResponse{Sessions: sessionInfosOf(sessions, callerTokenID(caller))}, nil
+}
+
+// RevokeSessions revokes a session of the caller, or all of them, keeping the current one if it is asked.
+func (h *Auth) RevokeSessions(ctx context.Context, in *msg.RevokeSessionsRequest) (*msg.RevokeSessionsResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	sessions, err := h.selectSessions(int64(caller.UserID()), in.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	if in.KeepCurrent {
+		current := callerTokenID(caller)
+		kept := sessions[:0]
+		for _, s := range sessions {
+			if current == "" || s.TokenID != current {
+				kept = append(kept, s)
+			}
+		}
+		sessions = kept
+	}
+	n, err := h.revokeSessions(sessions)
+	if err != nil {
+		return nil, err
+	}
+	return &msg.RevokeSessionsResponse{Revoked: n}, nil
+}
+
+// ListUserSessions lists the sessions of the user for the support staff.
+func (h *Auth) ListUserSessions(ctx context.Context, in *msg.ListUserSessionsRequest) (*msg.ListSessionsResponse, error) {
+	sessions, err := h.findSessions(in.Uid)
+	if err != nil {
+		return nil, err
+	}
+	return &msg.ListSessionsResponse{Sessions: sessionInfosOf(sessions, "")}, nil
+}
+
+// RevokeUserSessions revokes a session of the user, or all of them, for the support staff.
+// Unlike RevokeUser, the user can login again.
+func (h *Auth) RevokeUserSessions(ctx context.Context, in *msg.RevokeUserSessionsRequest) (*msg.RevokeSessionsResponse, error) {
+	sessions, err := h.selectSessions(in.Uid, in.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	n, err := h.revokeSessions(sessions)
+	if err != nil {
+		return nil, err
+	}
+	return &msg.RevokeSessionsResponse{Revoked: n}, nil
+}
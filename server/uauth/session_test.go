@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+type httpContext struct {
+	core.Context
+	r *http.Request
+}
+
+func (c *httpContext) RemoteAddr() string     { return c.r.RemoteAddr }
+func (c *httpContext) Request() *http.Request { return c.r }
+
+func TestSessionClient(t *testing.T) {
+	r := httptest.NewRequest("POST", "/Login", nil)
+	r.RemoteAddr = "1.2.3.4:5678"
+	r.Header.Set("User-Agent", strings.Repeat("a", 300))
+
+	c := clientOf(&httpContext{r: r}, "device-a")
+	if c.deviceID != "device-a" || c.ip != "1.2.3.4" || len(c.userAgent) != maxUserAgentLen {
+		t.Fatalf("wrong client: %+v", c)
+	}
+	if c := clientOf(nil, "device-a"); c.ip != "" || c.userAgent != "" {
+		t.Fatalf("wrong client without the context: %+v", c)
+	}
+
+	now := time.Now()
+	s := &models.UserSessions{ID: "family-1", UID: 10001, TokenID: "jti-1", CreateAt: now, LastSeenAt: now, ExpireAt: now.Add(time.Hour)}
+	caller := &coreauth.UserInfo{UId: 10001, TokenID: "jti-1"}
+	if info := sessionInfoOf(s, callerTokenID(caller)); !info.Current || info.SessionId != "family-1" {
+		t.Fatalf("the session of the caller is not current: %v", info)
+	}
+	if info := sessionInfoOf(s, ""); info.Current {
+		t.Fatal("an unknown token is current")
+	}
+}
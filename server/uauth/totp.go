@@ -0,0 +1,430 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ajenpan/surf/core"
+	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/errors"
+	log "github.com/ajenpan/surf/core/log"
+	msgcore "github.com/ajenpan/surf/msg/core"
+	msg "github.com/ajenpan/surf/msg/uauth"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+// DefaultTotpIssuer names the accounts in the authenticator apps.
+const DefaultTotpIssuer = "surf"
+
+const (
+	// the totp of RFC 6238 as the authenticator apps expect, HMAC-SHA1 with 6 digits every 30 seconds.
+	totpPeriod = 30
+	totpDigits = 6
+	totpModulo = 1000000
+	// totpSkew is the steps accepted before and after the current one, for the drift of the clocks.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+	// recoveryCodeChars leaves out the chars like each other, such as 0 and o.
+	recoveryCodeChars = "abcdefghjkmnpqrstuvwxyz23456789"
+
+	challengeTTL = 5 * time.Minute
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTotpSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// totpCode is the HOTP of RFC 4226 of the step.
+func totpCode(key []byte, step int64) string {
+	mac := hmac.New(sha1.New, key)
+	binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	v := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, v%totpModulo)
+}
+
+// matchTotp finds the step of the code around now, the steps not after lastStep are refused,
+// so a code can't be replayed. The step of the code is returned.
+func matchTotp(secret string, code string, now time.Time, lastStep int64) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	cur := now.Unix() / totpPeriod
+	for step := cur - totpSkew; step <= cur+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpURI is the otpauth uri of the qr code which the authenticator apps scan.
+func totpURI(issuer string, account string, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(totpDigits))
+	q.Set("period", strconv.Itoa(totpPeriod))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+// newRecoveryCode is like "k3v9x-0q2ma".
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	for i := range buf {
+		n, err := randInt(int64(len(recoveryCodeChars)))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = recoveryCodeChars[n]
+	}
+	return string(buf[:5]) + "-" + string(buf[5:]), nil
+}
+
+// hashRecoveryCode ignores the case, the dashes and the spaces which the users type.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return hashRefreshSecret(code)
+}
+
+// grantedRole is the role of the tokens of the login, the admin role needs the second factor.
+func grantedRole(user *models.Users, secondFactor bool) uint32 {
+	if user.Role >= uint32(msgcore.Role_RoleAdmin) && !secondFactor {
+		return uint32(msgcore.Role_RoleUser)
+	}
+	return user.Role
+}
+
+// enabledTotp is nil if the user has no enabled totp.
+func (h *Auth) enabledTotp(uid int64) (*models.UserTotps, error) {
+	t := &models.UserTotps{}
+	res := h.DB.Limit(1).Where("uid = ? AND enabled <> 0", uid).Find(t)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find totp failed")
+	}
+	if res.RowsAffected == 0 {
+		return nil, nil
+	}
+	return t, nil
+}
+
+// useTotp verifies the code, and moves the last step of the totp to it.
+// Only one of the concurrent uses of a code moves it.
+func (h *Auth) useTotp(t *models.UserTotps, code string) (bool, error) {
+	step, ok := matchTotp(t.Secret, code, time.Now(), t.LastStep)
+	if !ok {
+		return false, nil
+	}
+	res := h.DB.Model(&models.UserTotps{}).
+		Where("uid = ? AND last_step < ?", t.UID, step).
+		Update(models.UserTotpsColumns.LastStep, step)
+	if res.Error != nil {
+		return false, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "update totp failed")
+	}
+	t.LastStep = step
+	return res.RowsAffected != 0, nil
+}
+
+// useRecoveryCode deletes the code, so it passes only once.
+func (h *Auth) useRecoveryCode(uid int64, code string) (bool, error) {
+	res := h.DB.Where("uid = ? AND code_hash = ?", uid, hashRecoveryCode(code)).Delete(&models.UserRecoveryCodes{})
+	if res.Error != nil {
+		return false, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "use recovery code failed")
+	}
+	if res.RowsAffected != 0 {
+		log.Infof("uid %d used a recovery code", uid)
+	}
+	return res.RowsAffected != 0, nil
+}
+
+// passSecondFactor verifies the totp code, or the recovery code if the totp code is not given.
+func (h *Auth) passSecondFactor(t *models.UserTotps, totpCode string, recoveryCode string) (bool, error) {
+	if totpCode != "" {
+		return h.useTotp(t, totpCode)
+	}
+	if recoveryCode != "" {
+		return h.useRecoveryCode(t.UID, recoveryCode)
+	}
+	return false, nil
+}
+
+// replaceRecoveryCodes drops the recovery codes of the user, and returns the new ones.
+func replaceRecoveryCodes(tx *gorm.DB, uid int64) ([]string, error) {
+	if err := tx.Where("uid = ?", uid).Delete(&models.UserRecoveryCodes{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]*models.UserRecoveryCodes, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		rows[i] = &models.UserRecoveryCodes{UID: uid, CodeHash: hashRecoveryCode(code)}
+	}
+	if err := tx.Create(rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func challengeKey(id string) string {
+	return "challenge:" + id
+}
+
+// storeChallenge keeps the login which passed the password until the second factor.
+func (h *Auth) storeChallenge(user *models.Users, deviceID string) (string, error) {
+	id := uuid.NewString()
+	value := strconv.FormatInt(user.UID, 10) + "|" + deviceID
+	if err := h.Cache.StoreOnce(context.Background(), challengeKey(id), value, challengeTTL); err != nil {
+		return "", errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "store challenge failed")
+	}
+	return id, nil
+}
+
+// takeChallenge returns the uid and the device id of the login, a challenge can be taken only once.
+func (h *Auth) takeChallenge(id string) (int64, string, bool) {
+	value, has := h.Cache.TakeOnce(context.Background(), challengeKey(id))
+	if !has {
+		return 0, "", false
+	}
+	raw, deviceID, _ := strings.Cut(value, "|")
+	uid, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return uid, deviceID, true
+}
+
+// LoginSecondFactor passes the challenge of Login by the totp code or a recovery code, then issues the tokens.
+// A wrong code fails the challenge and counts as a failed login, the user has to login again.
+func (h *Auth) LoginSecondFactor(ctx context.Context, in *msg.LoginSecondFactorRequest) (*msg.LoginResponse, error) {
+	uid, deviceID, has := h.takeChallenge(in.Challenge)
+	if !has {
+		return nil, errors.New(int32(msg.ResponseFlag_ChallengeInvalid), "challenge invalid")
+	}
+
+	user := &models.Users{UID: uid}
+	res := h.DB.Limit(1).Find(user, user)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+	if res.RowsAffected == 0 || user.Stat != 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_StatErr), "user stat is not ok")
+	}
+	t, err := h.enabledTotp(uid)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		// disabled after the challenge.
+		return nil, errors.New(int32(msg.ResponseFlag_ChallengeInvalid), "challenge invalid")
+	}
+
+	passed, err := h.passSecondFactor(t, in.TotpCode, in.RecoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	if !passed {
+		h.failures.fail(context.Background(), user.Uname, remoteIPOf(ctx))
+		return nil, errors.New(int32(msg.ResponseFlag_SecondFactorWrong), "second factor wrong")
+	}
+
+	tokens, err := h.login(user, clientOf(core.ContextOf(ctx), deviceID), true)
+	if err != nil {
+		return nil, err
+	}
+	return &msg.LoginResponse{
+		AssessToken:  tokens.access,
+		RefreshToken: tokens.refresh,
+		ExpiresIn:    tokens.expiresIn,
+		UserInfo:     userInfoOf(user),
+	}, nil
+}
+
+// TotpSetup generates a totp secret of the caller, it is pending until TotpEnable verifies a code of it.
+func (h *Auth) TotpSetup(ctx context.Context, in *msg.TotpSetupRequest) (*msg.TotpSetupResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	uid := int64(caller.UserID())
+
+	t, err := h.enabledTotp(uid)
+	if err != nil {
+		return nil, err
+	}
+	if t != nil {
+		return nil, errors.New(int32(msg.ResponseFlag_TotpAlreadyEnabled), "totp already enabled")
+	}
+
+	secret, err := newTotpSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "generate totp secret failed")
+	}
+	// replaces the pending one.
+	if err := h.DB.Save(&models.UserTotps{UID: uid, Secret: secret, CreateAt: time.Now()}).Error; err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "store totp failed")
+	}
+	return &msg.TotpSetupResponse{
+		Secret: secret,
+		Uri:    totpURI(h.TotpIssuer, caller.UserName(), secret),
+	}, nil
+}
+
+// TotpEnable enables the pending totp of the caller by a code of it, and returns the recovery codes.
+func (h *Auth) TotpEnable(ctx context.Context, in *msg.TotpEnableRequest) (*msg.TotpEnableResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	uid := int64(caller.UserID())
+
+	t := &models.UserTotps{UID: uid}
+	res := h.DB.Limit(1).Find(t, t)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find totp failed")
+	}
+	if res.RowsAffected == 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_TotpNotEnabled), "totp is not set up")
+	}
+	if t.Enabled != 0 {
+		return nil, errors.New(int32(msg.ResponseFlag_TotpAlreadyEnabled), "totp already enabled")
+	}
+	step, ok := matchTotp(t.Secret, in.TotpCode, time.Now(), t.LastStep)
+	if !ok {
+		return nil, errors.New(int32(msg.ResponseFlag_SecondFactorWrong), "totp code wrong")
+	}
+
+	var codes []string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// the secret may be replaced by a concurrent setup.
+		res := tx.Model(&models.UserTotps{}).
+			Where("uid = ? AND secret = ? AND enabled = 0", uid, t.Secret).
+			Updates(map[string]interface{}{
+				models.UserTotpsColumns.Enabled:  1,
+				models.UserTotpsColumns.LastStep: step,
+			})
+		if res.Error != nil {
+			return errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "enable totp failed")
+		}
+		if res.RowsAffected == 0 {
+			return errors.New(int32(msg.ResponseFlag_SecondFactorWrong), "totp code wrong")
+		}
+		var err error
+		if codes, err = replaceRecoveryCodes(tx, uid); err != nil {
+			return errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "generate recovery codes failed")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("uid %d enabled totp", uid)
+	return &msg.TotpEnableResponse{RecoveryCodes: codes}, nil
+}
+
+// TotpDisable disables the totp of the caller by the totp code or a recovery code.
+func (h *Auth) TotpDisable(ctx context.Context, in *msg.TotpDisableRequest) (*msg.TotpDisableResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	uid := int64(caller.UserID())
+
+	t, err := h.enabledTotp(uid)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.New(int32(msg.ResponseFlag_TotpNotEnabled), "totp not enabled")
+	}
+	passed, err := h.passSecondFactor(t, in.TotpCode, in.RecoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	if !passed {
+		return nil, errors.New(int32(msg.ResponseFlag_SecondFactorWrong), "second factor wrong")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uid = ?", uid).Delete(&models.UserTotps{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("uid = ?", uid).Delete(&models.UserRecoveryCodes{}).Error
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "disable totp failed")
+	}
+	log.Infof("uid %d disabled totp", uid)
+	return &msg.TotpDisableResponse{}, nil
+}
+
+// TotpRecoveryCodes replaces the recovery codes of the caller by the totp code.
+func (h *Auth) TotpRecoveryCodes(ctx context.Context, in *msg.TotpRecoveryCodesRequest) (*msg.TotpRecoveryCodesResponse, error) {
+	caller, _ := coreauth.UserFromContext(ctx)
+	if caller == nil {
+		return nil, errors.Unauthenticated("login required")
+	}
+	uid := int64(caller.UserID())
+
+	t, err := h.enabledTotp(uid)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, errors.New(int32(msg.ResponseFlag_TotpNotEnabled), "totp not enabled")
+	}
+	passed, err := h.useTotp(t, in.TotpCode)
+	if err != nil {
+		return nil, err
+	}
+	if !passed {
+		return nil, errors.New(int32(msg.ResponseFlag_SecondFactorWrong), "totp code wrong")
+	}
+
+	var codes []string
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, uid)
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_DataBaseErr), "generate recovery codes failed")
+	}
+	return &msg.TotpRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	msgcore "github.com/ajenpan/surf/msg/core"
+	"github.com/ajenpan/surf/server/uauth/database/models"
+)
+
+func TestTotp(t *testing.T) {
+	// the SHA1 vectors of RFC 6238, the last 6 digits.
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, code := range vectors {
+		step, ok := matchTotp(secret, code, time.Unix(unix, 0), 0)
+		if !ok || step != unix/totpPeriod {
+			t.Fatal("the code of the vector is not matched:", unix, code)
+		}
+	}
+
+	now := time.Unix(1234567890, 0)
+	key, _ := totpEncoding.DecodeString(secret)
+	cur := now.Unix() / totpPeriod
+	if _, ok := matchTotp(secret, totpCode(key, cur-1), now, 0); !ok {
+		t.Fatal("the code of the previous step is refused")
+	}
+	if _, ok := matchTotp(secret, totpCode(key, cur+2), now, 0); ok {
+		t.Fatal("the code out of the skew is accepted")
+	}
+	// the used step and the ones before are refused, so a code can't be replayed.
+	if _, ok := matchTotp(secret, totpCode(key, cur), now, cur); ok {
+		t.Fatal("the used code is accepted")
+	}
+	if _, ok := matchTotp(secret, "00592", now, 0); ok {
+		t.Fatal("a short code is accepted")
+	}
+
+	code, err := newRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashRecoveryCode(code) != hashRecoveryCode(" "+code[:5]+code[6:]+" ") {
+		t.Fatal("the typed recovery code doesn't match:", code)
+	}
+}
+
+func TestGrantedRole(t *testing.T) {
+	admin := &models.Users{Role: uint32(msgcore.Role_RoleAdmin)}
+	if grantedRole(admin, false) != uint32(msgcore.Role_RoleUser) {
+		t.Fatal("the admin role is granted without the second factor")
+	}
+	if grantedRole(admin, true) != uint32(msgcore.Role_RoleAdmin) {
+		t.Fatal("the admin role is not granted with the second factor")
+	}
+	user := &models.Users{Role: uint32(msgcore.Role_RoleUser)}
+	if grantedRole(user, false) != uint32(msgcore.Role_RoleUser) {
+		t.Fatal("the user role is not granted")
+	}
+}
@@ -2,222 +2,319 @@ package auth
 
 import (
 	"context"
-	"crypto/rsa"
-	"fmt"
 	"net/http"
-	"reflect"
-	"regexp"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 
 	coreauth "github.com/ajenpan/surf/core/auth"
+	"github.com/ajenpan/surf/core/event"
 	"github.com/ajenpan/surf/core/utils/calltable"
 
 	"github.com/ajenpan/surf/core"
 	"github.com/ajenpan/surf/core/errors"
 	log "github.com/ajenpan/surf/core/log"
+	msgcore "github.com/ajenpan/surf/msg/core"
 	msg "github.com/ajenpan/surf/msg/uauth"
 	"github.com/ajenpan/surf/server/uauth/database/cache"
 	"github.com/ajenpan/surf/server/uauth/database/models"
+	"github.com/ajenpan/surf/server/uauth/idp"
 )
 
-var RegUname = regexp.MustCompile(`^[a-zA-Z0-9_]{4,16}$`)
-
 type AuthOptions struct {
-	PK        *rsa.PrivateKey
-	PublicKey []byte
-	DB        *gorm.DB
-	Cache     cache.AuthCache
+	// Keys signs the tokens, its public keys are published by PublicKeys.
+	Keys  *coreauth.KeyRing
+	DB    *gorm.DB
+	Cache cache.AuthCache
+
+	// AccessTokenTTL is the validity of the access tokens, DefaultAccessTokenTTL if zero.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is how long a login can be refreshed, DefaultRefreshTokenTTL if zero.
+	RefreshTokenTTL time.Duration
+
+	// PasswdIterations is the PBKDF2 cost of the passwords, DefaultPasswdIterations if zero.
+	// The passwords hashed with a lower cost are rehashed at the next login.
+	PasswdIterations int
+
+	// SendResetCode delivers the reset code of ResetPasswd to the user, like by email.
+	// uauth sends no mail itself, so the deployments must supply it, ResetPasswd is unimplemented if it is nil.
+	SendResetCode func(user *models.Users, code string) error
+
+	// CaptchaAfterFailures is how many failed logins of an account or an ip require the captcha,
+	// DefaultCaptchaAfterFailures if zero, never if negative.
+	CaptchaAfterFailures int
+	// FailureWindow is the sliding window of the failed logins, DefaultFailureWindow if zero.
+	FailureWindow time.Duration
+	// LockAfterFailures locks the account after the failed logins in the FailureWindow,
+	// DefaultLockAfterFailures if zero, never if negative. LockIPAfterFailures is the same for an ip.
+	LockAfterFailures   int
+	LockIPAfterFailures int
+	// LockDuration is the first lockout, DefaultLockDuration if zero,
+	// the later ones in a day double it up to MaxLockDuration, DefaultMaxLockDuration if zero.
+	LockDuration    time.Duration
+	MaxLockDuration time.Duration
+
+	// TotpIssuer names the accounts in the authenticator apps, DefaultTotpIssuer if empty.
+	TotpIssuer string
+
+	// Providers are the identity providers of OAuthStart, the users can't login by them if it is nil.
+	Providers *idp.Registry
+
+	// Publisher publishes the revocations of Logout, RevokeUser and the revoked sessions,
+	// the servers which receive them close the conns of the revoked tokens.
+	// cmd/uauth serves them by an event.Hub at /events of its internal listener, nothing is published if it is nil.
+	Publisher event.Publisher
+}
+
+func init() {
+	errors.Register(int32(msg.ResponseFlag_CaptchaWrong), http.StatusBadRequest, "wrong captcha")
+	errors.Register(int32(msg.ResponseFlag_PasswdWrong), http.StatusUnauthorized, "wrong user name or password")
+	errors.Register(int32(msg.ResponseFlag_UnameNotFound), http.StatusUnauthorized, "wrong user name or password")
+	errors.Register(int32(msg.ResponseFlag_StatErr), http.StatusForbidden, "user is disabled")
+	errors.Register(int32(msg.ResponseFlag_DataBaseErr), http.StatusInternalServerError, "internal error")
+	errors.Register(int32(msg.ResponseFlag_GenTokenErr), http.StatusInternalServerError, "internal error")
+	errors.Register(int32(msg.ResponseFlag_RefreshTokenInvalid), http.StatusUnauthorized, "invalid refresh token")
+	errors.Register(int32(msg.ResponseFlag_RefreshTokenReused), http.StatusUnauthorized, "invalid refresh token")
+	errors.Register(int32(msg.ResponseFlag_DeviceMismatch), http.StatusUnauthorized, "invalid refresh token")
+	errors.Register(int32(msg.ResponseFlag_ResetCodeWrong), http.StatusBadRequest, "wrong reset code")
+	errors.Register(int32(msg.ResponseFlag_CaptchaRequired), http.StatusUnauthorized, "captcha required")
+	errors.Register(int32(msg.ResponseFlag_GuestNotFound), http.StatusUnauthorized, "guest not found")
+	errors.Register(int32(msg.ResponseFlag_NotGuest), http.StatusBadRequest, "not a guest")
+	errors.Register(int32(msg.ResponseFlag_AccountLocked), http.StatusTooManyRequests, "too many failed logins, try again later")
+	errors.Register(int32(msg.ResponseFlag_SecondFactorWrong), http.StatusUnauthorized, "wrong second factor")
+	errors.Register(int32(msg.ResponseFlag_ChallengeInvalid), http.StatusUnauthorized, "invalid challenge, login again")
+	errors.Register(int32(msg.ResponseFlag_TotpAlreadyEnabled), http.StatusConflict, "totp already enabled")
+	errors.Register(int32(msg.ResponseFlag_TotpNotEnabled), http.StatusBadRequest, "totp not enabled")
+	errors.Register(int32(msg.ResponseFlag_SessionNotFound), http.StatusNotFound, "session not found")
+	errors.Register(int32(msg.ResponseFlag_OAuthStateInvalid), http.StatusBadRequest, "invalid state, login again")
+	errors.Register(int32(msg.ResponseFlag_OAuthFailed), http.StatusUnauthorized, "identity provider login failed")
+	errors.Register(int32(msg.ResponseFlag_IdentityLinked), http.StatusConflict, "identity already linked")
+	errors.Register(int32(msg.ResponseFlag_IdentityNotFound), http.StatusNotFound, "identity not found")
+	errors.Register(int32(msg.ResponseFlag_LastLoginMethod), http.StatusBadRequest, "the only login method can't be unlinked")
 }
 
 func NewAuth(opts AuthOptions) *Auth {
+	if opts.AccessTokenTTL == 0 {
+		opts.AccessTokenTTL = DefaultAccessTokenTTL
+	}
+	if opts.RefreshTokenTTL == 0 {
+		opts.RefreshTokenTTL = DefaultRefreshTokenTTL
+	}
+	if opts.PasswdIterations == 0 {
+		opts.PasswdIterations = DefaultPasswdIterations
+	}
+	if opts.CaptchaAfterFailures == 0 {
+		opts.CaptchaAfterFailures = DefaultCaptchaAfterFailures
+	}
+	if opts.FailureWindow == 0 {
+		opts.FailureWindow = DefaultFailureWindow
+	}
+	if opts.LockAfterFailures == 0 {
+		opts.LockAfterFailures = DefaultLockAfterFailures
+	}
+	if opts.LockIPAfterFailures == 0 {
+		opts.LockIPAfterFailures = DefaultLockIPAfterFailures
+	}
+	if opts.LockDuration == 0 {
+		opts.LockDuration = DefaultLockDuration
+	}
+	if opts.MaxLockDuration == 0 {
+		opts.MaxLockDuration = DefaultMaxLockDuration
+	}
+	if opts.TotpIssuer == "" {
+		opts.TotpIssuer = DefaultTotpIssuer
+	}
+	if opts.Publisher == nil {
+		opts.Publisher = event.NoopPublisher{}
+	}
 	ret := &Auth{
 		AuthOptions: opts,
+		refresh:     &refreshTokens{cache: opts.Cache, ttl: opts.RefreshTokenTTL},
+		passwd:      &passwdHasher{iterations: opts.PasswdIterations},
+		revoked:     &revocations{cache: opts.Cache},
+		failures: &loginFailures{
+			cache:        opts.Cache,
+			window:       opts.FailureWindow,
+			captchaAfter: int64(opts.CaptchaAfterFailures),
+			lockAfter:    int64(opts.LockAfterFailures),
+			lockIPAfter:  int64(opts.LockIPAfterFailures),
+			lockFor:      opts.LockDuration,
+			maxLockFor:   opts.MaxLockDuration,
+		},
 	}
 
 	// 自动创建表
-	opts.DB.AutoMigrate(models.Users{})
+	opts.DB.AutoMigrate(models.Users{}, models.GuestDevices{}, models.UserTotps{}, models.UserRecoveryCodes{}, models.UserSessions{}, models.UserIdentities{})
 	return ret
 }
 
 type Auth struct {
 	AuthOptions
+	refresh  *refreshTokens
+	passwd   *passwdHasher
+	revoked  *revocations
+	failures *loginFailures
 }
 
-func (h *Auth) AnonymousLogin(ctx core.Context, in *msg.AnonymousLoginRequest) {
-	out := &msg.AnonymousLoginResponse{}
-	var err error
-
-	defer func() {
-		ctx.Response(out, err)
-	}()
-
-	if !RegUname.MatchString(in.Uname) {
-		err = errors.New(int32(msg.ResponseFlag_PasswdWrong), "error")
-		return
-	}
-
-	if len(in.Passwd) < 6 {
-		return
-	}
-
-	user := &models.Users{
-		Uname: in.Uname,
-	}
-
-	res := h.DB.Limit(1).Find(user, user)
-	if err := res.Error; err != nil {
-		return
-	}
-
-	if res.RowsAffected == 0 {
-		user = &models.Users{
-			Uname:    in.Uname,
-			Passwd:   in.Passwd,
-			Nickname: "游客",
-			Gender:   0,
-		}
-
-		res := h.DB.Create(user)
-		if res.Error != nil {
-			err = errors.New(int32(msg.ResponseFlag_DataBaseErr), "create failed")
-		}
-		if res.RowsAffected == 0 {
-			err = errors.New(int32(msg.ResponseFlag_DataBaseErr), "create failed")
-			return
-		}
-	}
-
-	if user.Passwd != in.Passwd {
-		return
-	}
+type loginTokens struct {
+	access    string
+	refresh   string
+	expiresIn int64
+}
 
-	if user.Stat != 0 {
-		return
+// login issues the access token and a new refresh token family of the user, records the session
+// of the login and caches the user. secondFactor tells the login passed the second factor,
+// which the admin role requires.
+func (h *Auth) login(user *models.Users, client *loginClient, secondFactor bool) (*loginTokens, error) {
+	role := grantedRole(user, secondFactor)
+	if role != user.Role {
+		log.Warnf("uid %d logins without the second factor, the role %d is not granted", user.UID, user.Role)
+	}
+	token := &coreauth.UserInfo{
+		UId:     uint32(user.UID),
+		UName:   user.Uname,
+		URole:   role,
+		TokenID: uuid.NewString(),
+	}
+	access, err := h.Keys.Sign(token, h.AccessTokenTTL)
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_GenTokenErr), "generate token failed")
 	}
+	now := time.Now()
 
-	assess, err := coreauth.GenerateToken(h.PK, &coreauth.UserInfo{
-		UId:   uint32(user.UID),
-		UName: user.Uname,
-	}, 0)
-
+	refresh, family, err := h.refresh.issue(context.Background(), user.UID, client.deviceID, role)
 	if err != nil {
-		err = errors.New(int32(msg.ResponseFlag_GenTokenErr), err.Error())
-		return
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_GenTokenErr), "generate refresh token failed")
+	}
+
+	err = h.createSession(&models.UserSessions{
+		ID:          family.ID,
+		UID:         user.UID,
+		DeviceID:    client.deviceID,
+		IP:          client.ip,
+		UserAgent:   client.userAgent,
+		TokenID:     token.TokenID,
+		TokenExpire: now.Add(h.AccessTokenTTL).Unix(),
+		CreateAt:    now,
+		LastSeenAt:  now,
+		ExpireAt:    family.ExpireAt,
+	})
+	if err != nil {
+		h.Cache.DeleteRefreshFamily(context.Background(), family.ID)
+		return nil, err
 	}
 
 	cacheInfo := &cache.AuthCacheInfo{
 		User:         user,
-		AssessToken:  assess,
-		RefreshToken: uuid.NewString(),
+		AssessToken:  access,
+		RefreshToken: family.ID,
+		LoginAt:      now.Format(time.RFC3339),
+		LoginIP:      client.ip,
 	}
-
-	if err = h.Cache.StoreUser(context.Background(), cacheInfo, time.Hour); err != nil {
-		return
+	if err := h.Cache.StoreUser(context.Background(), cacheInfo, time.Hour); err != nil {
+		log.Error(err)
 	}
 
-	out.AssessToken = assess
+	return &loginTokens{
+		access:    access,
+		refresh:   refresh,
+		expiresIn: int64(h.AccessTokenTTL / time.Second),
+	}, nil
+}
 
-	out.UserInfo = &msg.UserInfo{
-		Uid:     user.UID,
-		Uname:   user.Uname,
-		Stat:    int32(user.Stat),
-		Created: user.CreateAt.Unix(),
+func userInfoOf(user *models.Users) *msg.UserInfo {
+	return &msg.UserInfo{
+		Uid:      user.UID,
+		Uname:    user.Uname,
+		Stat:     int32(user.Stat),
+		Created:  user.CreateAt.Unix(),
+		Nickname: user.Nickname,
+		Avatar:   user.Avatar,
+		Guest:    user.Guest != 0,
 	}
 }
 
 func (h *Auth) CTByName() *calltable.CallTable[string] {
-
-	NewMethod := func(f interface{}) *calltable.Method {
-		refv := reflect.ValueOf(f)
-		if refv.Kind() != reflect.Func {
-			return nil
-		}
-		ret := &calltable.Method{
-			Func:        refv,
-			Style:       calltable.StyleAsync,
-			RequestType: refv.Type().In(1).Elem(),
-		}
-		return ret
-	}
-
 	ct := calltable.NewCallTable[string]()
-	ct.Add("Login", NewMethod(h.Login))
-	ct.Add("AnonymousLogin", NewMethod(h.AnonymousLogin))
+	msg.RegisterUAuthServer(ct, h)
 	return ct
 }
 
-func (h *Auth) Login(ctx core.Context, in *msg.LoginRequest) {
-	out := &msg.LoginResponse{}
-	// var err = &err.Error{}
-	var err error
+func (h *Auth) Login(ctx context.Context, in *msg.LoginRequest) (out *msg.LoginResponse, err error) {
+	out = &msg.LoginResponse{}
 
-	defer func() {
-		ctx.Response(out, err)
-	}()
-
-	if !RegUname.MatchString(in.Uname) {
-		err = errors.New(int32(msg.ResponseFlag_PasswdWrong), "error")
+	ip := remoteIPOf(ctx)
+	if until := h.failures.lockedUntil(context.Background(), in.Uname, ip); !until.IsZero() {
+		err = retryAfter("locked", until)
 		return
 	}
-
-	if len(in.Passwd) < 6 {
+	if until := h.failures.throttledUntil(context.Background(), in.Uname, ip); !until.IsZero() {
+		err = retryAfter("too many failures", until)
 		return
 	}
 
+	if h.failures.captchaRequired(context.Background(), in.Uname, ip) {
+		if in.CaptchaVerify == nil {
+			err = errors.New(int32(msg.ResponseFlag_CaptchaRequired), "captcha required")
+			return
+		}
+		if !h.verifyCaptcha(in.CaptchaVerify) {
+			err = errors.New(int32(msg.ResponseFlag_CaptchaWrong), "captcha wrong")
+			return
+		}
+	}
+
 	user := &models.Users{
 		Uname: in.Uname,
 	}
 
 	res := h.DB.Limit(1).Find(user, user)
-	if err := res.Error; err != nil {
+	if res.Error != nil {
+		err = errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
 		return
 	}
 
+	// an unknown uname is the same as a wrong password, and its check takes as long, so the unames are not probed.
 	if res.RowsAffected == 0 {
-		return
+		user = &models.Users{}
 	}
-
-	if user.Passwd != in.Passwd {
+	if !h.checkPasswd(user, in.Passwd) {
+		h.failures.fail(context.Background(), in.Uname, ip)
+		err = errors.New(int32(msg.ResponseFlag_PasswdWrong), "wrong uname or passwd")
 		return
 	}
+	h.failures.succeed(context.Background(), in.Uname)
 
 	if user.Stat != 0 {
+		err = errors.New(int32(msg.ResponseFlag_StatErr), "user stat is not ok")
 		return
 	}
 
-	assess, err := coreauth.GenerateToken(h.PK, &coreauth.UserInfo{
-		UId:   uint32(user.UID),
-		UName: user.Uname,
-	}, 0)
-
+	// the tokens of the user with the second factor are issued by LoginSecondFactor.
+	totp, err := h.enabledTotp(user.UID)
 	if err != nil {
 		return
 	}
-
-	cacheInfo := &cache.AuthCacheInfo{
-		User:         user,
-		AssessToken:  assess,
-		RefreshToken: uuid.NewString(),
+	if totp != nil {
+		out.Challenge, err = h.storeChallenge(user, in.DeviceId)
+		out.ChallengeExpiresIn = int64(challengeTTL / time.Second)
+		return
 	}
 
-	if err = h.Cache.StoreUser(context.Background(), cacheInfo, time.Hour); err != nil {
-		log.Error(err)
+	tokens, err := h.login(user, clientOf(core.ContextOf(ctx), in.DeviceId), false)
+	if err != nil {
+		return
 	}
 
-	out.AssessToken = assess
-	out.UserInfo = &msg.UserInfo{
-		Uid:     user.UID,
-		Uname:   user.Uname,
-		Stat:    int32(user.Stat),
-		Created: user.CreateAt.Unix(),
-	}
+	out.AssessToken = tokens.access
+	out.RefreshToken = tokens.refresh
+	out.ExpiresIn = tokens.expiresIn
+	out.UserInfo = userInfoOf(user)
+	return out, nil
 }
 
-func (h *Auth) UserInfo(ctx core.Context, in *msg.UserInfoRequest) {
+func (h *Auth) UserInfo(ctx context.Context, in *msg.UserInfoRequest) (*msg.UserInfoResponse, error) {
 	user := &models.Users{
 		UID: in.Uid,
 	}
@@ -227,65 +324,144 @@ func (h *Auth) UserInfo(ctx core.Context, in *msg.UserInfoRequest) {
 	} else {
 		res := h.DB.Limit(1).Find(user, user)
 		if res.Error != nil {
-			return
+			return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
 		}
 		if res.RowsAffected == 0 {
-			return
+			return nil, errors.NotFound("user %d not found", in.Uid)
 		}
 		h.Cache.StoreUser(context.Background(), &cache.AuthCacheInfo{User: user}, time.Hour)
 	}
 
-	out := &msg.UserInfoResponse{
-		Info: &msg.UserInfo{
-			Uid:     user.UID,
-			Uname:   user.Uname,
-			Stat:    int32(user.Stat),
-			Created: user.CreateAt.Unix(),
-		},
-	}
-
-	ctx.Response(out, nil)
+	return &msg.UserInfoResponse{Info: userInfoOf(user)}, nil
 }
 
-func (h *Auth) Register(ctx core.Context, in *msg.RegisterRequest) (*msg.RegisterResponse, error) {
-	if !RegUname.MatchString(in.Uname) {
-		return nil, nil
-	}
-
-	if len(in.Passwd) < 6 {
-		return nil, fmt.Errorf("passwd is required")
+func (h *Auth) Register(ctx context.Context, in *msg.RegisterRequest) (*msg.RegisterResponse, error) {
+	hashed, err := h.passwd.hash(in.Passwd)
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "hash passwd failed")
 	}
-
 	user := &models.Users{
 		Uname:    in.Uname,
-		Passwd:   in.Passwd,
+		Passwd:   hashed,
 		Nickname: in.Nickname,
 		Gender:   'X',
+		Role:     uint32(msgcore.Role_RoleUser),
 	}
 
 	res := h.DB.Create(user)
 
 	if res.Error != nil {
 		log.Error(res.Error)
-		return nil, fmt.Errorf("server internal error")
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "create user failed")
 	}
 
 	if res.RowsAffected == 0 {
-		return nil, fmt.Errorf("create user error")
+		return nil, errors.AlreadyExists("user %s already exists", in.Uname)
 	}
 
 	return &msg.RegisterResponse{Msg: "ok"}, nil
 }
 
+// RefreshToken exchanges the refresh token for a new access token and a new refresh token,
+// the used refresh token is invalid after the exchange.
+func (h *Auth) RefreshToken(ctx context.Context, in *msg.RefreshTokenRequest) (*msg.RefreshTokenResponse, error) {
+	refresh, family, err := h.refresh.rotate(context.Background(), in.RefreshToken, in.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.Users{UID: family.UID}
+	res := h.DB.Limit(1).Find(user, user)
+	if res.Error != nil {
+		return nil, errors.Wrap(res.Error, int32(msg.ResponseFlag_DataBaseErr), "find user failed")
+	}
+	if res.RowsAffected == 0 || user.Stat != 0 {
+		h.Cache.DeleteRefreshFamily(context.Background(), family.ID)
+		return nil, errors.New(int32(msg.ResponseFlag_StatErr), "user stat is not ok")
+	}
+	if family.CreateAt.Before(h.Cache.UserValidAfter(context.Background(), user.UID)) {
+		h.Cache.DeleteRefreshFamily(context.Background(), family.ID)
+		return nil, errors.New(int32(msg.ResponseFlag_RefreshTokenInvalid), "refresh token revoked")
+	}
+
+	// the role of the login, unless the user is demoted since.
+	role := family.Role
+	if user.Role < role {
+		role = user.Role
+	}
+	token := &coreauth.UserInfo{
+		UId:     uint32(user.UID),
+		UName:   user.Uname,
+		URole:   role,
+		TokenID: uuid.NewString(),
+	}
+	access, err := h.Keys.Sign(token, h.AccessTokenTTL)
+	if err != nil {
+		return nil, errors.Wrap(err, int32(msg.ResponseFlag_GenTokenErr), "generate token failed")
+	}
+
+	now := time.Now()
+	client := clientOf(core.ContextOf(ctx), in.DeviceId)
+	err = h.touchSession(&models.UserSessions{
+		ID:          family.ID,
+		UID:         user.UID,
+		DeviceID:    family.DeviceID,
+		IP:          client.ip,
+		UserAgent:   client.userAgent,
+		TokenID:     token.TokenID,
+		TokenExpire: now.Add(h.AccessTokenTTL).Unix(),
+		CreateAt:    family.CreateAt,
+		LastSeenAt:  now,
+		ExpireAt:    family.ExpireAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &msg.RefreshTokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		ExpiresIn:    int64(h.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// PublicKeys returns the JWKS document of the keys which verify the tokens,
+// including the retired keys which are in the overlap.
+func (h *Auth) PublicKeys(ctx context.Context, in *msg.PublicKeysRequest) (*msg.PublicKeysResponse, error) {
+	raw, err := h.Keys.PublicKeys().MarshalJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, errors.CodeInternal, "marshal public keys failed")
+	}
+	return &msg.PublicKeysResponse{Keys: raw}, nil
+}
+
+// Introspect tells the token is active, for the services which verify the tokens
+// by coreauth.AuthClient instead of the keys. An invalid token is not an error but inactive.
+func (h *Auth) Introspect(ctx context.Context, in *msg.IntrospectRequest) (*msg.IntrospectResponse, error) {
+	uinfo, err := h.Keys.Verify([]byte(in.Token))
+	if err != nil || h.revoked.IsRevoked(uinfo) {
+		return &msg.IntrospectResponse{}, nil
+	}
+	return &msg.IntrospectResponse{
+		Active:   true,
+		Uid:      int64(uinfo.UId),
+		Uname:    uinfo.UName,
+		Role:     uinfo.URole,
+		TokenId:  uinfo.TokenID,
+		IssuedAt: uinfo.IssuedAt,
+		ExpireAt: uinfo.ExpireAt,
+	}, nil
+}
+
 func (h *Auth) AuthWrapper(f http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authorstr := r.Header.Get("Authorization")
-		authorstr = strings.TrimPrefix(authorstr, "Bearer ")
-		if authorstr == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			return
+		uinfo, err := coreauth.Authenticate(h.Keys, r)
+		if err == nil && uinfo == nil {
+			err = coreauth.ErrTokenRequired
+		}
+		if err == nil && h.revoked.IsRevoked(uinfo) {
+			err = coreauth.ErrTokenRevoked
 		}
-		_, err := coreauth.VerifyToken(&h.PK.PublicKey, []byte(authorstr))
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
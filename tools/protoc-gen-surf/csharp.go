@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// csharpNamespace is the csharp_namespace option, or the proto package in pascal case as protoc does.
+func csharpNamespace(f *protogen.File) string {
+	if ns := f.Proto.GetOptions().GetCsharpNamespace(); len(ns) > 0 {
+		return ns
+	}
+	parts := strings.Split(string(f.Desc.Package()), ".")
+	for i, p := range parts {
+		parts[i] = pascalCase(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func pascalCase(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, c := range s {
+		if c == '_' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// csharpType is the full name of the message class, the nested messages are in the Types of their parents.
+func csharpType(gen *protogen.Plugin, md protoreflect.MessageDescriptor) string {
+	name := string(md.Name())
+	if parent, ok := md.Parent().(protoreflect.MessageDescriptor); ok {
+		return csharpType(gen, parent) + ".Types." + name
+	}
+	ns := ""
+	if f, ok := gen.FilesByPath[md.ParentFile().Path()]; ok {
+		ns = csharpNamespace(f)
+	}
+	if len(ns) == 0 {
+		return "global::" + name
+	}
+	return "global::" + ns + "." + name
+}
+
+// generateCSharpFile generates the clients and the msgid tables for the messages generated by protoc --csharp_out.
+func generateCSharpFile(gen *protogen.Plugin, f *protogen.File, opts *Options) error {
+	msgs := msgIDMessages(f, "")
+	if len(f.Services) == 0 && len(msgs) == 0 {
+		return nil
+	}
+
+	ident := fileIdent(f)
+	g := gen.NewGeneratedFile(strings.TrimSuffix(f.Desc.Path(), ".proto")+"Surf.cs", "")
+	g.P("// Code generated by protoc-gen-surf. DO NOT EDIT.")
+	g.P("// source: ", f.Desc.Path())
+	g.P()
+	g.P("using System;")
+	g.P("using System.Collections.Generic;")
+	g.P("using System.Threading;")
+	g.P("using System.Threading.Tasks;")
+	g.P("using Google.Protobuf;")
+	g.P()
+
+	indent := ""
+	ns := csharpNamespace(f)
+	if len(ns) > 0 {
+		g.P("namespace ", ns, " {")
+		indent = "  "
+	}
+
+	for _, service := range f.Services {
+		name := service.GoName + "Client"
+		g.P(indent, "// ", name, " calls the ", service.GoName, " service,")
+		g.P(indent, "// call sends the request to the method and returns the response body, it throws the err of the response envelope.")
+		g.P(indent, "public class ", name, " {")
+		g.P(indent, "  private readonly Func<string, byte[], CancellationToken, Task<byte[]>> call;")
+		g.P(indent, "  private readonly string prefix;")
+		g.P()
+		g.P(indent, "  public ", name, "(Func<string, byte[], CancellationToken, Task<byte[]>> call, string prefix = \"\") {")
+		g.P(indent, "    this.call = call;")
+		g.P(indent, "    this.prefix = prefix;")
+		g.P(indent, "  }")
+		for _, method := range unaryOrServerStream(service.Methods) {
+			if method.Desc.IsStreamingServer() {
+				// the server streams are served by the conn clients only.
+				continue
+			}
+			in, out := csharpType(gen, method.Input.Desc), csharpType(gen, method.Output.Desc)
+			g.P()
+			g.P(indent, "  public async Task<", out, "> ", method.GoName, "Async(", in, " request, CancellationToken cancellationToken = default) {")
+			g.P(indent, "    var raw = await call(prefix + ", fmt.Sprintf("%q", method.GoName), ", request.ToByteArray(), cancellationToken);")
+			g.P(indent, "    return ", out, ".Parser.ParseFrom(raw);")
+			g.P(indent, "  }")
+		}
+		g.P(indent, "}")
+		g.P()
+	}
+
+	if len(msgs) > 0 {
+		g.P(indent, "public static class ", ident, "MsgID {")
+		for _, msg := range msgs {
+			g.P(indent, "  public const uint ", msg.Desc.Name(), " = ", msgID(msg.Desc), ";")
+		}
+		g.P()
+		g.P(indent, "  public static readonly Dictionary<uint, MessageParser> Parsers = new Dictionary<uint, MessageParser> {")
+		for _, msg := range msgs {
+			g.P(indent, "    { ", msg.Desc.Name(), ", ", csharpType(gen, msg.Desc), ".Parser },")
+		}
+		g.P(indent, "  };")
+		g.P(indent, "}")
+	}
+
+	if len(ns) > 0 {
+		g.P("}")
+	}
+	return nil
+}
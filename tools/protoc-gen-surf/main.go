@@ -0,0 +1,48 @@
+// protoc-gen-surf generates typed calltable registrations for the services
+// and the MSGID messages of proto files, so that handlers are dispatched
+// without reflect, and a missing handler method is a compile error.
+// the typed clients of the services are generated too, for go, typescript and c#.
+//
+//	protoc --go_out=. --surf_out=. [--surf_opt=style=micro] foo.proto
+//
+// options:
+//
+//	style:      grpc(default) func(context.Context, *Req) (*Resp, error)
+//	            micro         func(context.Context, *Req, *Resp) error
+//	            a server streaming method is func(context.Context, *Req, Service_MethodStream) error in both,
+//	            the client streaming methods are skipped
+//	msg_suffix: only the MSGID messages with the suffix get a handler, default "Request"
+//	lang:       go(default) the calltable registrations and the typed clients on client.Transport
+//	            ts          the clients and the msgid tables for the messages of ts-proto
+//	            csharp      the clients and the msgid tables for the messages of protoc --csharp_out
+//	no_client:  true skips the go clients, for the packages which core/client depends on, like uauth
+package main
+
+import (
+	"flag"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+func main() {
+	var flags flag.FlagSet
+	opts := &Options{}
+	flags.StringVar(&opts.Style, "style", StyleGRpc, "the style of service handlers, grpc or micro")
+	flags.StringVar(&opts.MsgSuffix, "msg_suffix", "Request", "the suffix of messages to generate handlers")
+	flags.StringVar(&opts.Lang, "lang", LangGo, "the language to generate, go, ts or csharp")
+	flags.BoolVar(&opts.NoClient, "no_client", false, "skip the go clients")
+
+	protogen.Options{
+		ParamFunc: flags.Set,
+	}.Run(func(gen *protogen.Plugin) error {
+		for _, f := range gen.Files {
+			if !f.Generate {
+				continue
+			}
+			if err := generateFile(gen, f, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	msgcore "github.com/ajenpan/surf/msg/core"
+)
+
+const (
+	StyleGRpc  = "grpc"
+	StyleMicro = "micro"
+)
+
+const (
+	LangGo     = "go"
+	LangTS     = "ts"
+	LangCSharp = "csharp"
+)
+
+type Options struct {
+	Style     string
+	MsgSuffix string
+	Lang      string
+	// NoClient skips the go clients, for the packages which core/client depends on.
+	NoClient bool
+}
+
+const (
+	contextPackage   = protogen.GoImportPath("context")
+	reflectPackage   = protogen.GoImportPath("reflect")
+	calltablePackage = protogen.GoImportPath("github.com/ajenpan/surf/core/utils/calltable")
+	clientPackage    = protogen.GoImportPath("github.com/ajenpan/surf/core/client")
+)
+
+// msgIDMessages returns the messages with the suffix which declare a nested MSGID enum with an ID value.
+func msgIDMessages(f *protogen.File, suffix string) []*protogen.Message {
+	var ret []*protogen.Message
+	for _, msg := range f.Messages {
+		if !strings.HasSuffix(msg.GoIdent.GoName, suffix) {
+			continue
+		}
+		if msgID(msg.Desc) == 0 {
+			continue
+		}
+		ret = append(ret, msg)
+	}
+	return ret
+}
+
+func msgID(md protoreflect.MessageDescriptor) uint32 {
+	enum := md.Enums().ByName("MSGID")
+	if enum == nil {
+		return 0
+	}
+	id := enum.Values().ByName("ID")
+	if id == nil {
+		return 0
+	}
+	return uint32(id.Number())
+}
+
+// fileIdent turns "service/battle/battle_inner.proto" into "BattleInner".
+func fileIdent(f *protogen.File) string {
+	base := strings.TrimSuffix(filepath.Base(f.Desc.Path()), ".proto")
+	var b strings.Builder
+	upper := true
+	for _, c := range base {
+		if c == '_' || c == '-' || c == '.' {
+			upper = true
+			continue
+		}
+		if upper && c >= 'a' && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		upper = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func generateFile(gen *protogen.Plugin, f *protogen.File, opts *Options) error {
+	switch opts.Lang {
+	case LangGo, "":
+	case LangTS:
+		return generateTSFile(gen, f, opts)
+	case LangCSharp:
+		return generateCSharpFile(gen, f, opts)
+	default:
+		return fmt.Errorf("unknown lang: %s", opts.Lang)
+	}
+
+	if opts.Style != StyleGRpc && opts.Style != StyleMicro {
+		return fmt.Errorf("unknown style: %s", opts.Style)
+	}
+
+	msgs := msgIDMessages(f, opts.MsgSuffix)
+	if len(f.Services) == 0 && len(msgs) == 0 {
+		return nil
+	}
+
+	filename := f.GeneratedFilenamePrefix + "_surf.pb.go"
+	g := gen.NewGeneratedFile(filename, f.GoImportPath)
+	g.P("// Code generated by protoc-gen-surf. DO NOT EDIT.")
+	g.P("// source: ", f.Desc.Path())
+	g.P()
+	g.P("package ", f.GoPackageName)
+	g.P()
+
+	for _, service := range f.Services {
+		generateService(g, f, service, opts)
+		if !opts.NoClient {
+			generateClient(g, service)
+		}
+	}
+
+	if len(msgs) > 0 {
+		generateMessages(g, fileIdent(f), msgs)
+	}
+	return nil
+}
+
+func generateService(g *protogen.GeneratedFile, f *protogen.File, service *protogen.Service, opts *Options) {
+	serverName := service.GoName + "Server"
+	ctxIdent := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+
+	methods := unaryOrServerStream(service.Methods)
+
+	g.P("// ", serverName, " is the handler of the ", service.GoName, " service.")
+	g.P("type ", serverName, " interface {")
+	for _, method := range methods {
+		if method.Desc.IsStreamingServer() {
+			g.P(method.GoName, "(", ctxIdent, ", *", method.Input.GoIdent, ", ", streamName(service, method), ") error")
+		} else if opts.Style == StyleMicro {
+			g.P(method.GoName, "(", ctxIdent, ", *", method.Input.GoIdent, ", *", method.Output.GoIdent, ") error")
+		} else {
+			g.P(method.GoName, "(", ctxIdent, ", *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", error)")
+		}
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Register", serverName, " adds the methods of ", service.GoName, " into the call table, keyed by method name.")
+	g.P("func Register", serverName, "(ct *", calltablePackage.Ident("CallTable"), "[string], srv ", serverName, ") {")
+	for _, method := range methods {
+		style := "StyleGRpc"
+		if method.Desc.IsStreamingServer() {
+			style = "StyleServerStream"
+		} else if opts.Style == StyleMicro {
+			style = "StyleMicro"
+		}
+		g.P("ct.Add(", fmt.Sprintf("%q", method.GoName), ", &", calltablePackage.Ident("Method"), "{")
+		g.P("FuncName: ", fmt.Sprintf("%q", method.GoName), ",")
+		g.P("Style: ", calltablePackage.Ident(style), ",")
+		g.P("RequestType: ", reflectPackage.Ident("TypeOf"), "((*", method.Input.GoIdent, ")(nil)).Elem(),")
+		g.P("ResponseType: ", reflectPackage.Ident("TypeOf"), "((*", method.Output.GoIdent, ")(nil)).Elem(),")
+		if proto.HasExtension(method.Desc.Options(), msgcore.E_Method) {
+			g.P("Meta: ", calltablePackage.Ident("MethodMetaOf"), "(", f.GoDescriptorIdent, ".Services().ByName(",
+				fmt.Sprintf("%q", service.Desc.Name()), ").Methods().ByName(", fmt.Sprintf("%q", method.Desc.Name()), ")),")
+		}
+		g.P("Invoker: _", service.GoName, "_", method.GoName, "_Invoker(srv),")
+		g.P("})")
+	}
+	g.P("}")
+	g.P()
+
+	for _, method := range methods {
+		if method.Desc.IsStreamingServer() {
+			generateStream(g, service, method)
+			continue
+		}
+		g.P("func _", service.GoName, "_", method.GoName, "_Invoker(srv ", serverName, ") ", calltablePackage.Ident("Invoker"), " {")
+		g.P("return func(ctx interface{}, req interface{}) (interface{}, error) {")
+		g.P("c, ok := ctx.(", ctxIdent, ")")
+		g.P("if !ok {")
+		g.P("return nil, ", calltablePackage.Ident("ErrInvalidContext"))
+		g.P("}")
+		g.P("in, ok := req.(*", method.Input.GoIdent, ")")
+		g.P("if !ok {")
+		g.P("return nil, ", calltablePackage.Ident("ErrInvalidRequest"))
+		g.P("}")
+		if opts.Style == StyleMicro {
+			g.P("out := &", method.Output.GoIdent, "{}")
+			g.P("if err := srv.", method.GoName, "(c, in, out); err != nil {")
+			g.P("return nil, err")
+			g.P("}")
+			g.P("return out, nil")
+		} else {
+			g.P("out, err := srv.", method.GoName, "(c, in)")
+			g.P("if out == nil {")
+			g.P("return nil, err")
+			g.P("}")
+			g.P("return out, err")
+		}
+		g.P("}")
+		g.P("}")
+		g.P()
+	}
+}
+
+// unaryOrServerStream drops the client streaming methods, which are not supported.
+func unaryOrServerStream(methods []*protogen.Method) []*protogen.Method {
+	var ret []*protogen.Method
+	for _, method := range methods {
+		if method.Desc.IsStreamingClient() {
+			continue
+		}
+		ret = append(ret, method)
+	}
+	return ret
+}
+
+func streamName(service *protogen.Service, method *protogen.Method) string {
+	return service.GoName + "_" + method.GoName + "Stream"
+}
+
+// generateStream generates the typed stream of a server streaming method, and its invoker,
+// which takes the stream from the calltable.Streamer context.
+func generateStream(g *protogen.GeneratedFile, service *protogen.Service, method *protogen.Method) {
+	name := streamName(service, method)
+	impl := strings.ToLower(name[:1]) + name[1:]
+	ctxIdent := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+
+	g.P("// ", name, " sends the responses of ", service.GoName, ".", method.GoName, ".")
+	g.P("type ", name, " interface {")
+	g.P("Context() ", ctxIdent)
+	g.P("Send(*", method.Output.GoIdent, ") error")
+	g.P("}")
+	g.P()
+	g.P("type ", impl, " struct {")
+	g.P(calltablePackage.Ident("Stream"))
+	g.P("}")
+	g.P()
+	g.P("func (s *", impl, ") Send(m *", method.Output.GoIdent, ") error {")
+	g.P("return s.Stream.Send(m)")
+	g.P("}")
+	g.P()
+	g.P("func _", service.GoName, "_", method.GoName, "_Invoker(srv ", service.GoName, "Server) ", calltablePackage.Ident("Invoker"), " {")
+	g.P("return func(ctx interface{}, req interface{}) (interface{}, error) {")
+	g.P("s, ok := ctx.(", calltablePackage.Ident("Streamer"), ")")
+	g.P("if !ok || s.Stream() == nil {")
+	g.P("return nil, ", calltablePackage.Ident("ErrInvalidContext"))
+	g.P("}")
+	g.P("in, ok := req.(*", method.Input.GoIdent, ")")
+	g.P("if !ok {")
+	g.P("return nil, ", calltablePackage.Ident("ErrInvalidRequest"))
+	g.P("}")
+	g.P("stream := s.Stream()")
+	g.P("return nil, srv.", method.GoName, "(stream.Context(), in, &", impl, "{stream})")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+// httpRoute returns the http path and verb declared by the method options.
+func httpRoute(method *protogen.Method) (string, string) {
+	opts, ok := proto.GetExtension(method.Desc.Options(), msgcore.E_Method).(*msgcore.MethodOptions)
+	if !ok || opts == nil {
+		return "", ""
+	}
+	return opts.HttpPath, opts.HttpVerb
+}
+
+// generateClient generates the typed client of the service on a client.Transport.
+func generateClient(g *protogen.GeneratedFile, service *protogen.Service) {
+	methods := unaryOrServerStream(service.Methods)
+	clientName := service.GoName + "Client"
+	impl := strings.ToLower(clientName[:1]) + clientName[1:]
+	ctxIdent := g.QualifiedGoIdent(contextPackage.Ident("Context"))
+
+	g.P("// ", clientName, " calls the ", service.GoName, " service over a client.Transport.")
+	g.P("type ", clientName, " interface {")
+	for _, method := range methods {
+		if method.Desc.IsStreamingServer() {
+			g.P(method.GoName, "(ctx ", ctxIdent, ", in *", method.Input.GoIdent, ") (", service.GoName, "_", method.GoName, "Client, error)")
+		} else {
+			g.P(method.GoName, "(ctx ", ctxIdent, ", in *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", error)")
+		}
+	}
+	g.P("}")
+	g.P()
+
+	g.P("type ", impl, " struct {")
+	g.P("t ", clientPackage.Ident("Transport"))
+	g.P("}")
+	g.P()
+	g.P("func New", clientName, "(t ", clientPackage.Ident("Transport"), ") ", clientName, " {")
+	g.P("return &", impl, "{t: t}")
+	g.P("}")
+	g.P()
+
+	g.P("var (")
+	for _, method := range methods {
+		fields := fmt.Sprintf("Name: %q", method.GoName)
+		if path, verb := httpRoute(method); len(path) > 0 || len(verb) > 0 {
+			fields += fmt.Sprintf(", HttpPath: %q, HttpVerb: %q", path, verb)
+		}
+		g.P("_", service.GoName, "_", method.GoName, "_Method = &", clientPackage.Ident("Method"), "{", fields, "}")
+	}
+	g.P(")")
+	g.P()
+
+	for _, method := range methods {
+		methodVar := "_" + service.GoName + "_" + method.GoName + "_Method"
+		if !method.Desc.IsStreamingServer() {
+			g.P("func (c *", impl, ") ", method.GoName, "(ctx ", ctxIdent, ", in *", method.Input.GoIdent, ") (*", method.Output.GoIdent, ", error) {")
+			g.P("out := &", method.Output.GoIdent, "{}")
+			g.P("if err := c.t.Call(ctx, ", methodVar, ", in, out); err != nil {")
+			g.P("return nil, err")
+			g.P("}")
+			g.P("return out, nil")
+			g.P("}")
+			g.P()
+			continue
+		}
+
+		streamName := service.GoName + "_" + method.GoName + "Client"
+		streamImpl := strings.ToLower(streamName[:1]) + streamName[1:]
+		g.P("func (c *", impl, ") ", method.GoName, "(ctx ", ctxIdent, ", in *", method.Input.GoIdent, ") (", streamName, ", error) {")
+		g.P("s, err := c.t.Stream(ctx, ", methodVar, ", in)")
+		g.P("if err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return &", streamImpl, "{s}, nil")
+		g.P("}")
+		g.P()
+		g.P("// ", streamName, " receives the responses of ", service.GoName, ".", method.GoName, ", Recv returns io.EOF at the end.")
+		g.P("type ", streamName, " interface {")
+		g.P("Recv() (*", method.Output.GoIdent, ", error)")
+		g.P("Close() error")
+		g.P("}")
+		g.P()
+		g.P("type ", streamImpl, " struct {")
+		g.P(clientPackage.Ident("Stream"))
+		g.P("}")
+		g.P()
+		g.P("func (s *", streamImpl, ") Recv() (*", method.Output.GoIdent, ", error) {")
+		g.P("m := &", method.Output.GoIdent, "{}")
+		g.P("if err := s.Stream.Recv(m); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return m, nil")
+		g.P("}")
+		g.P()
+	}
+}
+
+func generateMessages(g *protogen.GeneratedFile, ident string, msgs []*protogen.Message) {
+	handlerName := ident + "MsgHandler"
+
+	g.P("// ", handlerName, " is the handler of the messages with a MSGID.")
+	g.P("type ", handlerName, " interface {")
+	for _, msg := range msgs {
+		g.P("On", msg.GoIdent.GoName, "(*", msg.GoIdent, ")")
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Register", handlerName, " adds the message handlers into the call table, keyed by MSGID.")
+	g.P("func Register", handlerName, "(ct *", calltablePackage.Ident("CallTable"), "[uint32], h ", handlerName, ") {")
+	for _, msg := range msgs {
+		g.P("ct.Add(uint32(", msg.GoIdent.GoName, "_ID), &", calltablePackage.Ident("Method"), "{")
+		g.P("FuncName: ", fmt.Sprintf("%q", "On"+msg.GoIdent.GoName), ",")
+		g.P("Style: ", calltablePackage.Ident("StyleAsync"), ",")
+		g.P("RequestType: ", reflectPackage.Ident("TypeOf"), "((*", msg.GoIdent, ")(nil)).Elem(),")
+		if proto.HasExtension(msg.Desc.Options(), msgcore.E_Handler) {
+			g.P("Meta: ", calltablePackage.Ident("MessageMetaOf"), "((*", msg.GoIdent, ")(nil).ProtoReflect().Descriptor()),")
+		}
+		g.P("Invoker: func(ctx interface{}, req interface{}) (interface{}, error) {")
+		g.P("in, ok := req.(*", msg.GoIdent, ")")
+		g.P("if !ok {")
+		g.P("return nil, ", calltablePackage.Ident("ErrInvalidRequest"))
+		g.P("}")
+		g.P("h.On", msg.GoIdent.GoName, "(in)")
+		g.P("return nil, nil")
+		g.P("},")
+		g.P("})")
+	}
+	g.P("}")
+	g.P()
+}
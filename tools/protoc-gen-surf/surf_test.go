@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/ajenpan/surf/msg/mailbox"
+	battle "github.com/ajenpan/surf/server/battle/proto"
+)
+
+// withImports lists the file after its imports, as protoc does.
+func withImports(fd protoreflect.FileDescriptor, seen map[string]bool) []*descriptorpb.FileDescriptorProto {
+	if seen[fd.Path()] {
+		return nil
+	}
+	seen[fd.Path()] = true
+	var ret []*descriptorpb.FileDescriptorProto
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		ret = append(ret, withImports(imports.Get(i).FileDescriptor, seen)...)
+	}
+	return append(ret, protodesc.ToFileDescriptorProto(fd))
+}
+
+func generate(t *testing.T, fd protoreflect.FileDescriptor, opts *Options) string {
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{fd.Path()},
+		Parameter:      proto.String("Mcore/options.proto=github.com/ajenpan/surf/msg/core"),
+		ProtoFile:      withImports(fd, map[string]bool{}),
+	}
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range gen.Files {
+		if err := generateFile(gen, f, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatal(resp.GetError())
+	}
+	if len(resp.File) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(resp.File))
+	}
+	return resp.File[0].GetContent()
+}
+
+func TestGenerateService(t *testing.T) {
+	content := generate(t, mailbox.File_mailbox_proto, &Options{Style: StyleMicro, MsgSuffix: "Request"})
+	for _, expect := range []string{
+		"type MailBoxServer interface",
+		"RecvMail(context.Context, *RecvMailRequest, *RecvMailResponse) error",
+		"func RegisterMailBoxServer(ct *calltable.CallTable[string], srv MailBoxServer)",
+		"Style:        calltable.StyleMicro,",
+		`Meta:         calltable.MethodMetaOf(File_mailbox_proto.Services().ByName("MailBox").Methods().ByName("SendMail")),`,
+		"RecvMail(ctx context.Context, in *RecvMailRequest) (*RecvMailResponse, error)",
+		"func NewMailBoxClient(t client.Transport) MailBoxClient",
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+
+	content = generate(t, mailbox.File_mailbox_proto, &Options{Style: StyleGRpc, MsgSuffix: "Request"})
+	if !strings.Contains(content, "RecvMail(context.Context, *RecvMailRequest) (*RecvMailResponse, error)") {
+		t.Fatalf("unexpected grpc style:\n%s", content)
+	}
+
+	content = generate(t, mailbox.File_mailbox_proto, &Options{Style: StyleGRpc, MsgSuffix: "Request", NoClient: true})
+	if !strings.Contains(content, "func RegisterMailBoxServer") || strings.Contains(content, "MailBoxClient") {
+		t.Fatalf("unexpected no_client:\n%s", content)
+	}
+}
+
+func TestGenerateMessages(t *testing.T) {
+	content := generate(t, battle.File_service_battle_proto_battle_proto, &Options{Style: StyleGRpc, MsgSuffix: "Request"})
+	for _, expect := range []string{
+		"type BattleMsgHandler interface",
+		"OnJoinBattleRequest(*JoinBattleRequest)",
+		"ct.Add(uint32(PlayerReadyRequest_ID)",
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+	if strings.Contains(content, "OnJoinBattleResponse") {
+		t.Fatal("responses should not have a handler")
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("replay.proto"),
+		Package: proto.String("replay"),
+		Syntax:  proto.String("proto3"),
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("./replay;replay")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("ReplayRequest")},
+			{Name: proto.String("ReplayFrame")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Replay"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Watch"), InputType: proto.String(".replay.ReplayRequest"), OutputType: proto.String(".replay.ReplayFrame"), ServerStreaming: proto.Bool(true)},
+				{Name: proto.String("Upload"), InputType: proto.String(".replay.ReplayFrame"), OutputType: proto.String(".replay.ReplayRequest"), ClientStreaming: proto.Bool(true)},
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := generate(t, fd, &Options{Style: StyleMicro, MsgSuffix: "Request"})
+	for _, expect := range []string{
+		"Watch(context.Context, *ReplayRequest, Replay_WatchStream) error",
+		"Send(*ReplayFrame) error",
+		"Style:        calltable.StyleServerStream,",
+		"return nil, srv.Watch(stream.Context(), in, &replay_WatchStream{stream})",
+		"Watch(ctx context.Context, in *ReplayRequest) (Replay_WatchClient, error)",
+		"Recv() (*ReplayFrame, error)",
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+	if strings.Contains(content, "Upload") {
+		t.Fatal("client streaming methods should be skipped")
+	}
+}
+
+func TestGenerateClients(t *testing.T) {
+	content := generate(t, mailbox.File_mailbox_proto, &Options{Lang: LangTS})
+	for _, expect := range []string{
+		`RecvMailRequest, RecvMailResponse, SendMailRequest`,
+		`} from "./mailbox";`,
+		`export class MailBoxClient {`,
+		`const raw = await this.call(this.prefix + "RecvMail", RecvMailRequest.encode(request).finish());`,
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+
+	content = generate(t, mailbox.File_mailbox_proto, &Options{Lang: LangCSharp})
+	for _, expect := range []string{
+		"public class MailBoxClient {",
+		"public async Task<global::RecvMailResponse> RecvMailAsync(global::RecvMailRequest request, CancellationToken cancellationToken = default) {",
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+
+	content = generate(t, battle.File_service_battle_proto_battle_proto, &Options{Lang: LangTS})
+	for _, expect := range []string{
+		`from "./battle";`,
+		"JoinBattleRequest: 2000,",
+		"2001: (raw) => JoinBattleResponse.decode(raw),",
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+
+	content = generate(t, battle.File_service_battle_proto_battle_proto, &Options{Lang: LangCSharp})
+	for _, expect := range []string{
+		"namespace Battle {",
+		"public static class BattleMsgID {",
+		"public const uint JoinBattleRequest = 2000;",
+		"{ JoinBattleRequest, global::Battle.JoinBattleRequest.Parser },",
+	} {
+		if !strings.Contains(content, expect) {
+			t.Fatalf("missing %q in:\n%s", expect, content)
+		}
+	}
+}
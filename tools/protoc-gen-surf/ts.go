@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+)
+
+// tsModule is the module of the messages generated from the proto file, like "battle/battle".
+func tsModule(f *protogen.File) string {
+	return strings.TrimSuffix(f.Desc.Path(), ".proto")
+}
+
+// tsImportPath returns the relative import path of module "to" from module "from".
+func tsImportPath(from, to string) string {
+	fromDir := strings.Split(path.Dir(from), "/")
+	if path.Dir(from) == "." {
+		fromDir = nil
+	}
+	toParts := strings.Split(to, "/")
+	i := 0
+	for i < len(fromDir) && i < len(toParts)-1 && fromDir[i] == toParts[i] {
+		i++
+	}
+	rel := strings.Repeat("../", len(fromDir)-i) + strings.Join(toParts[i:], "/")
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// generateTSFile generates the clients and the msgid tables for the messages generated by ts-proto,
+// which have the static encode and decode of each message.
+func generateTSFile(gen *protogen.Plugin, f *protogen.File, opts *Options) error {
+	msgs := msgIDMessages(f, "")
+	if len(f.Services) == 0 && len(msgs) == 0 {
+		return nil
+	}
+
+	module := tsModule(f)
+	g := gen.NewGeneratedFile(module+"_surf.ts", "")
+	g.P("// Code generated by protoc-gen-surf. DO NOT EDIT.")
+	g.P("// source: ", f.Desc.Path())
+	g.P()
+
+	// the messages are imported from the modules of their proto files.
+	imports := map[string]map[string]bool{}
+	use := func(msg *protogen.Message) string {
+		mod := tsImportPath(module, strings.TrimSuffix(msg.Desc.ParentFile().Path(), ".proto"))
+		if imports[mod] == nil {
+			imports[mod] = map[string]bool{}
+		}
+		imports[mod][msg.GoIdent.GoName] = true
+		return msg.GoIdent.GoName
+	}
+	for _, service := range f.Services {
+		for _, method := range unaryOrServerStream(service.Methods) {
+			if !method.Desc.IsStreamingServer() {
+				use(method.Input)
+				use(method.Output)
+			}
+		}
+	}
+	for _, msg := range msgs {
+		use(msg)
+	}
+	var mods []string
+	for mod := range imports {
+		mods = append(mods, mod)
+	}
+	sort.Strings(mods)
+	for _, mod := range mods {
+		var names []string
+		for name := range imports[mod] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		g.P("import { ", strings.Join(names, ", "), " } from ", fmt.Sprintf("%q", mod), ";")
+	}
+	g.P()
+
+	if len(f.Services) > 0 {
+		g.P("// SurfCall sends the request to the method and resolves the response body,")
+		g.P("// it rejects with the err of the response envelope.")
+		g.P("export type SurfCall = (method: string, request: Uint8Array) => Promise<Uint8Array>;")
+		g.P()
+	}
+
+	for _, service := range f.Services {
+		g.P("export class ", service.GoName, "Client {")
+		g.P("  constructor(private readonly call: SurfCall, private readonly prefix: string = \"\") {}")
+		for _, method := range unaryOrServerStream(service.Methods) {
+			if method.Desc.IsStreamingServer() {
+				// the server streams are served by the conn clients only.
+				continue
+			}
+			in, out := method.Input.GoIdent.GoName, method.Output.GoIdent.GoName
+			g.P()
+			g.P("  async ", method.GoName, "(request: ", in, "): Promise<", out, "> {")
+			g.P("    const raw = await this.call(this.prefix + ", fmt.Sprintf("%q", method.GoName), ", ", in, ".encode(request).finish());")
+			g.P("    return ", out, ".decode(raw);")
+			g.P("  }")
+		}
+		g.P("}")
+		g.P()
+	}
+
+	if len(msgs) > 0 {
+		ident := fileIdent(f)
+		g.P("export const ", ident, "MsgID = {")
+		for _, msg := range msgs {
+			g.P("  ", msg.GoIdent.GoName, ": ", msgID(msg.Desc), ",")
+		}
+		g.P("} as const;")
+		g.P()
+		g.P("export const ", ident, "MsgDecoders: { [msgid: number]: (raw: Uint8Array) => unknown } = {")
+		for _, msg := range msgs {
+			g.P("  ", msgID(msg.Desc), ": (raw) => ", msg.GoIdent.GoName, ".decode(raw),")
+		}
+		g.P("};")
+	}
+	return nil
+}